@@ -23,6 +23,8 @@ func main() {
 	}
 
 	root.AddCommand(workspaceCmd())
+	root.AddCommand(serveCmd())
+	root.AddCommand(multilineCmd())
 
 	err := root.Execute()
 	otelShutdown()
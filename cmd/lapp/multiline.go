@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/go-errors/errors"
+	"github.com/spf13/cobra"
+	"github.com/strrl/lapp/pkg/logsource"
+	"github.com/strrl/lapp/pkg/multiline"
+)
+
+var (
+	multilineLearnSample int
+	multilineExplainMax  int
+)
+
+func multilineCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "multiline",
+		Short: "Inspect how log lines are merged into multiline entries",
+	}
+	cmd.AddCommand(multilineLearnCmd())
+	cmd.AddCommand(multilineExplainCmd())
+	return cmd
+}
+
+func multilineLearnCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "learn <logfile>",
+		Short: "Print the start-of-entry rule learned from the head of a log file",
+		Long: `Samples the first lines of a log file and prints the signature most
+entries start with, as ingestion learns it for each file. Without a learned
+rule, entries are split at timestamped lines only.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runMultilineLearn,
+	}
+	cmd.Flags().IntVar(&multilineLearnSample, "sample", 500, "number of leading lines to sample")
+	return cmd
+}
+
+func runMultilineLearn(cmd *cobra.Command, args []string) error {
+	// Stop reading once the sample is full.
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	detector, err := multiline.NewDetector(multiline.DetectorConfig{
+		Learn:            multiline.LearnExtend,
+		LearnSampleLines: multilineLearnSample,
+	})
+	if err != nil {
+		return errors.Errorf("multiline detector: %w", err)
+	}
+
+	lines, err := logsource.Ingest(ctx, args[0])
+	if err != nil {
+		return errors.Errorf("ingest: %w", err)
+	}
+	var sample []string
+	for rr := range logsource.DecodeContainer(ctx, lines) {
+		if rr.Err != nil {
+			return errors.Errorf("read log file: %w", rr.Err)
+		}
+		sample = append(sample, rr.Value.Content)
+		if len(sample) >= detector.LearnSampleLines() {
+			break
+		}
+	}
+
+	rule := detector.Learn(sample)
+	if rule == nil {
+		fmt.Printf("No start-of-entry signature learned from %d lines; entries are split at timestamps.\n", len(sample))
+		return nil
+	}
+	fmt.Println(rule)
+	return nil
+}
+
+func multilineExplainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain <logfile>",
+		Short: "Show why each line of a log file does or does not start an entry",
+		Long: `Prints, for each line, its tokenized prefix, the part of it that best
+matches a known timestamp format with its probability, and whether the
+timestamp detector treats it as a new entry. The MERGE column shows what
+ingestion finally did with the line, after learned signatures, stack trace
+and payload rules: start a new entry, continue the previous one, or continue
+an entry split at the size limit. A summary of entry sizes follows.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runMultilineExplain,
+	}
+	cmd.Flags().IntVar(&multilineExplainMax, "lines", 1000, "number of leading lines to explain, 0 for all")
+	return cmd
+}
+
+func runMultilineExplain(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	// The same rules ingestion merges with.
+	detector, err := multiline.NewDetector(multiline.DetectorConfig{
+		StackTraces: true,
+		Payloads:    true,
+		Learn:       multiline.LearnExtend,
+	})
+	if err != nil {
+		return errors.Errorf("multiline detector: %w", err)
+	}
+
+	lines, err := logsource.Ingest(ctx, args[0])
+	if err != nil {
+		return errors.Errorf("ingest: %w", err)
+	}
+	var contents []string
+	for rr := range logsource.DecodeContainer(ctx, lines) {
+		if rr.Err != nil {
+			return errors.Errorf("read log file: %w", rr.Err)
+		}
+		contents = append(contents, rr.Value.Content)
+		if multilineExplainMax > 0 && len(contents) >= multilineExplainMax {
+			break
+		}
+	}
+
+	merged := multiline.MergeSlice(ctx, contents, detector)
+	starts := make(map[int]multiline.MergedLine, len(merged))
+	for _, m := range merged {
+		starts[m.StartLine] = m
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "LINE\tNEW\tPROB\tMATCH\tMERGE\tTOKENS")
+	for i, line := range contents {
+		e := detector.Explain(line)
+		decision := "continue"
+		if m, ok := starts[i+1]; ok {
+			decision = "start"
+			if m.Continuation {
+				decision = "split"
+			}
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%t\t%.2f\t%q\t%s\t%q\n", i+1, e.IsNewEntry, e.Probability, e.Match, decision, e.Tokens)
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Errorf("write explanation: %w", err)
+	}
+
+	printEntrySizes(merged, len(contents))
+	return nil
+}
+
+// printEntrySizes summarizes how many lines and bytes the merged entries
+// span.
+func printEntrySizes(merged []multiline.MergedLine, lineCount int) {
+	if len(merged) == 0 {
+		fmt.Println("\nNo entries.")
+		return
+	}
+	lineSizes := make([]int, 0, len(merged))
+	byteSizes := make([]int, 0, len(merged))
+	multi, truncated := 0, 0
+	for _, m := range merged {
+		n := m.EndLine - m.StartLine + 1
+		if n > 1 {
+			multi++
+		}
+		if m.Truncated {
+			truncated++
+		}
+		lineSizes = append(lineSizes, n)
+		byteSizes = append(byteSizes, len(m.Content))
+	}
+
+	fmt.Printf("\n%d entries from %d lines: %d multiline, %d split at the size limit\n", len(merged), lineCount, multi, truncated)
+	fmt.Printf("Lines per entry: %s\n", sizeSummary(lineSizes))
+	fmt.Printf("Bytes per entry: %s\n", sizeSummary(byteSizes))
+}
+
+func sizeSummary(sizes []int) string {
+	slices.Sort(sizes)
+	at := func(q float64) int { return sizes[int(q*float64(len(sizes)-1))] }
+	return fmt.Sprintf("min %d, median %d, p95 %d, max %d", sizes[0], at(0.5), at(0.95), sizes[len(sizes)-1])
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/go-errors/errors"
+	"github.com/spf13/cobra"
+	"github.com/strrl/lapp/pkg/ingest"
+	"github.com/strrl/lapp/pkg/logsource"
+	"github.com/strrl/lapp/pkg/workspace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var serveTopic string
+var serveModel string
+var serveRebuild bool
+
+func serveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Receive logs over the network into a workspace",
+		Long: `Run a log receiver that appends every received message to the workspace's
+logs/ directory. On shutdown (Ctrl-C) the workspace is rebuilt, exactly as
+after add-log.
+
+Rebuilding requires OPENROUTER_API_KEY unless --rebuild=false is given.`,
+	}
+	cmd.PersistentFlags().StringVar(&serveTopic, "topic", "", "workspace topic (required)")
+	cmd.PersistentFlags().StringVar(&serveModel, "model", "", "override LLM model used for the rebuild")
+	cmd.PersistentFlags().BoolVar(&serveRebuild, "rebuild", true, "rebuild patterns and notes on shutdown")
+	_ = cmd.MarkPersistentFlagRequired("topic")
+	cmd.AddCommand(serveSyslogCmd())
+	cmd.AddCommand(serveOTLPCmd())
+	cmd.AddCommand(serveHTTPCmd())
+	cmd.AddCommand(serveForwardCmd())
+	return cmd
+}
+
+var syslogUDPAddr string
+var syslogTCPAddr string
+
+func serveSyslogCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "syslog",
+		Short: "Receive RFC 3164 / RFC 5424 syslog over UDP and TCP",
+		Long: `Listen for syslog messages over UDP and TCP (octet-counted or newline-framed)
+and append them to logs/syslog.log in the workspace. Header fields (host,
+app_name, procid, msgid, structured data) are kept as attributes.`,
+		Args: cobra.NoArgs,
+		RunE: runServeSyslog,
+	}
+	cmd.Flags().StringVar(&syslogUDPAddr, "udp", ":5514", "UDP listen address (empty to disable)")
+	cmd.Flags().StringVar(&syslogTCPAddr, "tcp", ":5514", "TCP listen address (empty to disable)")
+	return cmd
+}
+
+func runServeSyslog(cmd *cobra.Command, _ []string) error {
+	server, err := logsource.NewSyslogServer(logsource.SyslogConfig{
+		UDPAddr: syslogUDPAddr,
+		TCPAddr: syslogTCPAddr,
+	})
+	if err != nil {
+		return errors.Errorf("syslog server: %w", err)
+	}
+	slog.Info("Syslog receiver listening", "udp", server.UDPAddr(), "tcp", server.TCPAddr())
+	return serveIntoWorkspace(cmd.Context(), "syslog", server, nil)
+}
+
+var otlpAddr string
+
+func serveOTLPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "otlp",
+		Short: "Receive OpenTelemetry logs over OTLP/HTTP",
+		Long: `Accept OTLP/HTTP ExportLogsServiceRequest payloads (protobuf or JSON) on
+POST /v1/logs and append every LogRecord to logs/otlp.log in the workspace.
+Resource and record attributes, severity and trace/span IDs are kept.`,
+		Args: cobra.NoArgs,
+		RunE: runServeOTLP,
+	}
+	cmd.Flags().StringVar(&otlpAddr, "addr", ":4318", "HTTP listen address")
+	return cmd
+}
+
+func runServeOTLP(cmd *cobra.Command, _ []string) error {
+	server, err := logsource.NewOTLPServer(logsource.OTLPConfig{Addr: otlpAddr})
+	if err != nil {
+		return errors.Errorf("otlp server: %w", err)
+	}
+	slog.Info("OTLP receiver listening", "addr", server.Addr())
+	return serveIntoWorkspace(cmd.Context(), "otlp", server, nil)
+}
+
+var httpIngestAddr string
+
+func serveHTTPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "http",
+		Short: "Receive Loki push and Elasticsearch _bulk requests",
+		Long: `Accept Loki pushes on POST /loki/api/v1/push (JSON or snappy protobuf) and
+Elasticsearch bulk requests on POST /_bulk or /<index>/_bulk, and append every
+entry to logs/http.log in the workspace. Stream labels, structured metadata and
+document fields are kept as attributes.`,
+		Args: cobra.NoArgs,
+		RunE: runServeHTTP,
+	}
+	cmd.Flags().StringVar(&httpIngestAddr, "addr", ":3100", "HTTP listen address")
+	return cmd
+}
+
+func runServeHTTP(cmd *cobra.Command, _ []string) error {
+	server, err := logsource.NewHTTPIngestServer(logsource.HTTPIngestConfig{Addr: httpIngestAddr})
+	if err != nil {
+		return errors.Errorf("http ingest server: %w", err)
+	}
+	slog.Info("HTTP ingest receiver listening", "addr", server.Addr())
+	return serveIntoWorkspace(cmd.Context(), "http", server, nil)
+}
+
+var forwardAddr string
+
+func serveForwardCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "forward",
+		Short: "Receive Fluentd / Fluent Bit records over the Forward protocol",
+		Long: `Accept Fluent Forward connections (Message, Forward, PackedForward and
+CompressedPackedForward modes, with chunk acks) and append every record to
+logs/<tag>.log in the workspace, one file per Fluent tag (e.g. app.web goes to
+logs/app-web.log). The tag is also kept as the "source" attribute and record
+fields as attributes. Shared-key auth is not supported.`,
+		Args: cobra.NoArgs,
+		RunE: runServeForward,
+	}
+	cmd.Flags().StringVar(&forwardAddr, "addr", ":24224", "TCP listen address")
+	return cmd
+}
+
+func runServeForward(cmd *cobra.Command, _ []string) error {
+	server, err := logsource.NewForwardServer(logsource.ForwardConfig{Addr: forwardAddr})
+	if err != nil {
+		return errors.Errorf("forward server: %w", err)
+	}
+	slog.Info("Forward receiver listening", "addr", server.Addr())
+	return serveIntoWorkspace(cmd.Context(), "forward", server, forwardSinkName)
+}
+
+// logReceiver is a network log source that streams lines until its context
+// is cancelled.
+type logReceiver interface {
+	Ingest(ctx context.Context) (<-chan logsource.Result[*logsource.LogLine], error)
+}
+
+// forwardSinkName routes a Forward record to the file of its Fluent tag.
+func forwardSinkName(line *logsource.LogLine) string {
+	if tag := line.Attrs["source"]; tag != "" {
+		return workspace.SinkName(tag)
+	}
+	return "forward.log"
+}
+
+// serveIntoWorkspace appends everything received to logs/<source>.log in the
+// --topic workspace, or to the file sinkName picks per line if it is not
+// nil, until interrupted, then optionally rebuilds it.
+func serveIntoWorkspace(ctx context.Context, source string, receiver logReceiver, sinkName func(*logsource.LogLine) string) error {
+	dir, err := topicToDir(serveTopic)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "logs")); os.IsNotExist(err) {
+		hint := availableWorkspacesHint()
+		return errors.Errorf("not a workspace: %s (no logs/ directory)%s", dir, hint)
+	}
+
+	apiKey := os.Getenv("OPENROUTER_API_KEY")
+	if serveRebuild && apiKey == "" {
+		return errors.New("OPENROUTER_API_KEY environment variable is required (or pass --rebuild=false)")
+	}
+
+	ctx, span := otel.Tracer("lapp/cmd").Start(ctx, "cmd.Serve")
+	defer span.End()
+
+	recvCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if sinkName == nil {
+		sinkName = func(*logsource.LogLine) string { return source + ".log" }
+	}
+	sinks := workspace.OpenSinks(dir)
+
+	lines, err := receiver.Ingest(recvCtx)
+	if err != nil {
+		return errors.Errorf("start %s receiver: %w", source, err)
+	}
+
+	received := 0
+	for rr := range lines {
+		if rr.Err != nil {
+			slog.Warn("Receive error", "source", source, "err", rr.Err)
+			continue
+		}
+		if err := sinks.Write(sinkName(rr.Value), rr.Value); err != nil {
+			_ = sinks.Close()
+			return err
+		}
+		received++
+	}
+	if err := sinks.Close(); err != nil {
+		return err
+	}
+	slog.Info("Receiver stopped", "source", source, "messages", received)
+
+	if serveRebuild && received > 0 {
+		if err := rebuildWorkspace(ctx, dir, apiKey, serveModel, ingest.FileOptions{}, nil); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+	}
+
+	span.SetStatus(codes.Ok, "")
+	return nil
+}
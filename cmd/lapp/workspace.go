@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -15,9 +16,12 @@ import (
 	"github.com/go-errors/errors"
 	"github.com/spf13/cobra"
 	"github.com/strrl/lapp/pkg/analyzer"
-	"github.com/strrl/lapp/pkg/multiline"
+	"github.com/strrl/lapp/pkg/event"
+	"github.com/strrl/lapp/pkg/ingest"
+	"github.com/strrl/lapp/pkg/logsource"
 	"github.com/strrl/lapp/pkg/pattern"
 	"github.com/strrl/lapp/pkg/semantic"
+	"github.com/strrl/lapp/pkg/store"
 	"github.com/strrl/lapp/pkg/workspace"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -154,7 +158,20 @@ Use ` + "`lapp workspace add-log --topic " + topic + " <logfile>`" + ` to add lo
 
 var addLogModel string
 var addLogStdin bool
+var addLogCharset string
 var addLogTopic string
+var addLogParsers []string
+var addLogGrok []string
+var addLogGrokPatterns []string
+var addLogNginxFormat string
+var addLogKlogYear int
+var addLogCSV bool
+var addLogCSVColumns []string
+var addLogMessageKeys []string
+var addLogJSONKeepNested bool
+var addLogJSONArrays string
+var addLogJSONMaxDepth int
+var addLogTimezone string
 
 func workspaceAddLogCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -163,6 +180,9 @@ func workspaceAddLogCmd() *cobra.Command {
 		Long: `Copy a log file into the workspace's logs/ directory, then run the full
 pipeline (Drain clustering + semantic labeling) to regenerate patterns/ and notes/.
 
+UTF-8 and UTF-16 logs are recognized by their BOM or content; anything else
+is transcoded from --charset. Invalid byte sequences become U+FFFD.
+
 Requires OPENROUTER_API_KEY environment variable.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: runWorkspaceAddLog,
@@ -170,6 +190,19 @@ Requires OPENROUTER_API_KEY environment variable.`,
 	cmd.Flags().StringVar(&addLogTopic, "topic", "", "workspace topic (required)")
 	cmd.Flags().StringVar(&addLogModel, "model", "", "override LLM model")
 	cmd.Flags().BoolVar(&addLogStdin, "stdin", false, "read log from stdin")
+	cmd.Flags().StringVar(&addLogCharset, "charset", logsource.DefaultFallbackCharset, "charset assumed for logs without a BOM that are not UTF-8")
+	cmd.Flags().StringSliceVar(&addLogParsers, "parsers", nil, "line parsers to try, in order (default "+strings.Join(event.DefaultRegistry.Chain(), ",")+")")
+	cmd.Flags().StringArrayVar(&addLogGrok, "grok", nil, "Grok expression for the "+customGrokParser+" parser, tried ahead of plain text (repeatable)")
+	cmd.Flags().StringArrayVar(&addLogGrokPatterns, "grok-patterns", nil, "file of custom Grok pattern definitions for --grok (repeatable)")
+	cmd.Flags().IntVar(&addLogKlogYear, "klog-year", 0, "year of the year-less klog timestamps (default: inferred from the current date)")
+	cmd.Flags().BoolVar(&addLogCSV, "csv", false, "read the added log as CSV/TSV with a header row; only the message column is clustered")
+	cmd.Flags().StringArrayVar(&addLogCSVColumns, "csv-column", nil, "role of a CSV column as <column>=timestamp|level|message|attr|ignore (repeatable)")
+	cmd.Flags().StringSliceVar(&addLogMessageKeys, "message-key", nil, "fields JSON/logfmt lines are clustered by, in order of preference (default "+strings.Join(event.DefaultMessageKeys, ",")+")")
+	cmd.Flags().BoolVar(&addLogJSONKeepNested, "json-keep-nested", false, "keep nested JSON objects as one attr instead of flattening them into dotted keys")
+	cmd.Flags().StringVar(&addLogJSONArrays, "json-arrays", "", "how JSON arrays become attrs: json|index|join|drop (default json)")
+	cmd.Flags().IntVar(&addLogJSONMaxDepth, "json-max-depth", 0, "levels of nested JSON objects to flatten; deeper ones are kept as JSON (default: no limit)")
+	cmd.Flags().StringVar(&addLogTimezone, "timezone", "", "IANA time zone of timestamps written without one, e.g. Europe/Berlin or Local (default UTC)")
+	cmd.Flags().StringVar(&addLogNginxFormat, "nginx-log-format", "", "Nginx log_format of the access logs, for the "+nginxParser+" parser")
 	_ = cmd.MarkFlagRequired("topic")
 	return cmd
 }
@@ -191,26 +224,190 @@ func runWorkspaceAddLog(cmd *cobra.Command, args []string) error {
 		return errors.New("OPENROUTER_API_KEY environment variable is required")
 	}
 
+	parserOpts := parserOptions{
+		chain:        addLogParsers,
+		grok:         addLogGrok,
+		grokPatterns: addLogGrokPatterns,
+		nginxFormat:  addLogNginxFormat,
+		klogYear:     addLogKlogYear,
+		csv:          addLogCSV,
+		csvColumns:   addLogCSVColumns,
+		messageKeys:  addLogMessageKeys,
+		json: event.JSONOptions{
+			KeepNested: addLogJSONKeepNested,
+			Arrays:     event.ArrayMode(addLogJSONArrays),
+			MaxDepth:   addLogJSONMaxDepth,
+		},
+		timezone: addLogTimezone,
+	}
+	parsers, err := parserRegistry(parserOpts)
+	if err != nil {
+		return err
+	}
+	opts := ingest.FileOptions{FallbackCharset: addLogCharset, Parsers: parsers}
+	// --csv describes the added file only: the CSV parser would take the
+	// first line of any other file as its header.
+	addedOpts := opts
+	if addLogCSV {
+		addedOpts.QuotedRecords = true
+		parserOpts.csv, parserOpts.csvColumns = false, nil
+		parserOpts.chain = slices.DeleteFunc(slices.Clone(parserOpts.chain), func(name string) bool { return name == csvParser })
+		if opts.Parsers, err = parserRegistry(parserOpts); err != nil {
+			return err
+		}
+	}
+
 	ctx, span := otel.Tracer("lapp/cmd").Start(cmd.Context(), "cmd.WorkspaceAddLog")
 	defer span.End()
 
-	if err := copyLogToWorkspace(dir, args, span); err != nil {
+	added, err := copyLogToWorkspace(dir, args, span)
+	if err != nil {
 		return err
 	}
 
-	allTagged, allContent, fileCount, err := mergeAllLogs(ctx, dir)
+	if err := rebuildWorkspace(ctx, dir, apiKey, addLogModel, opts, map[string]ingest.FileOptions{added: addedOpts}); err != nil {
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+	return nil
+}
+
+// Names of the parsers built from add-log flags.
+const (
+	customGrokParser = "custom_grok"
+	nginxParser      = "nginx"
+	csvParser        = "csv"
+)
+
+// parserOptions are the add-log flags that customize the parser chain.
+type parserOptions struct {
+	chain        []string
+	grok         []string
+	grokPatterns []string
+	nginxFormat  string
+	klogYear     int
+	csv          bool
+	csvColumns   []string
+	messageKeys  []string
+	json         event.JSONOptions
+	timezone     string
+}
+
+// parserRegistry returns the parser chain opts describe, or nil for the
+// default chain. Parsers built from flags are tried first, ahead of the
+// built-in ones they are more specific than, unless the chain is given
+// explicitly.
+func parserRegistry(opts parserOptions) (*event.Registry, error) {
+	if len(opts.grokPatterns) > 0 && len(opts.grok) == 0 {
+		return nil, errors.New("--grok-patterns requires --grok")
+	}
+	if len(opts.csvColumns) > 0 && !opts.csv {
+		return nil, errors.New("--csv-column requires --csv")
+	}
+	if len(opts.chain) == 0 && len(opts.grok) == 0 && opts.nginxFormat == "" && opts.klogYear == 0 && !opts.csv && len(opts.messageKeys) == 0 && opts.json == (event.JSONOptions{}) && opts.timezone == "" {
+		return nil, nil
+	}
+
+	registry := event.DefaultRegistry.Clone()
+	if len(opts.messageKeys) > 0 {
+		if err := registry.SetMessageKeys(opts.messageKeys...); err != nil {
+			return nil, err
+		}
+	}
+	if opts.timezone != "" {
+		loc, err := time.LoadLocation(opts.timezone)
+		if err != nil {
+			return nil, errors.Errorf("--timezone: %w", err)
+		}
+		registry.SetLocation(loc)
+	}
+	if opts.json != (event.JSONOptions{}) {
+		p, err := event.NewJSONParser(opts.json)
+		if err != nil {
+			return nil, err
+		}
+		if err := registry.Replace(event.ParserJSON, p); err != nil {
+			return nil, err
+		}
+	}
+	if opts.klogYear != 0 {
+		if err := registry.Replace(event.ParserKlog, &event.KlogParser{Year: opts.klogYear}); err != nil {
+			return nil, err
+		}
+	}
+	var custom []string
+	if opts.csv {
+		roles := make(map[string]event.ColumnRole, len(opts.csvColumns))
+		for _, spec := range opts.csvColumns {
+			i := strings.LastIndex(spec, "=")
+			if i <= 0 {
+				return nil, errors.Errorf("--csv-column %q: want <column>=<role>", spec)
+			}
+			roles[spec[:i]] = event.ColumnRole(spec[i+1:])
+		}
+		p, err := event.NewCSVParser(event.CSVConfig{Roles: roles})
+		if err != nil {
+			return nil, err
+		}
+		if err := registry.Register(csvParser, p); err != nil {
+			return nil, err
+		}
+		custom = append(custom, csvParser)
+	}
+	if opts.nginxFormat != "" {
+		p, err := event.NewNginxParser(opts.nginxFormat)
+		if err != nil {
+			return nil, err
+		}
+		if err := registry.Register(nginxParser, p); err != nil {
+			return nil, err
+		}
+		custom = append(custom, nginxParser)
+	}
+	if len(opts.grok) > 0 {
+		g := event.NewGrok()
+		for _, path := range opts.grokPatterns {
+			if err := g.AddPatternsFile(path); err != nil {
+				return nil, err
+			}
+		}
+		p, err := event.NewGrokParser(g, opts.grok...)
+		if err != nil {
+			return nil, err
+		}
+		if err := registry.Register(customGrokParser, p); err != nil {
+			return nil, err
+		}
+		custom = append(custom, customGrokParser)
+	}
+	chain := opts.chain
+	if len(chain) == 0 {
+		chain = append(custom, event.DefaultRegistry.Chain()...)
+	}
+	if err := registry.SetChain(chain...); err != nil {
+		return nil, errors.Errorf("parsers: %w", err)
+	}
+	return registry, nil
+}
+
+// rebuildWorkspace runs the full pipeline over every file in <dir>/logs/
+// and regenerates patterns/ and notes/. Files are ingested with opts, or
+// with their entry in fileOpts if they have one.
+func rebuildWorkspace(ctx context.Context, dir, apiKey, model string, opts ingest.FileOptions, fileOpts map[string]ingest.FileOptions) error {
+	logs, err := ingestAllLogs(ctx, dir, opts, fileOpts)
 	if err != nil {
 		return err
 	}
 
-	slog.Info("Processing logs", "files", fileCount, "lines", len(allTagged))
+	slog.Info("Processing logs", "files", logs.fileCount, "lines", len(logs.tagged))
 
-	filtered, err := runDrain(ctx, allContent)
+	filtered, err := runDrain(ctx, logs.content)
 	if err != nil {
 		return err
 	}
 
-	labels, err := labelPatterns(ctx, filtered, allContent, apiKey)
+	labels, err := labelPatterns(ctx, filtered, logs.content, apiKey, model)
 	if err != nil {
 		return err
 	}
@@ -219,80 +416,123 @@ func runWorkspaceAddLog(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	builder := workspace.NewBuilder(dir, allTagged, filtered, labels)
+	builder := workspace.NewBuilder(dir, logs.tagged, filtered, labels)
+	builder.SetTimeline(logs.timeline)
+	builder.SetRequests(logs.requests, logs.requestTotal)
 	if err := builder.BuildAll(); err != nil {
 		return errors.Errorf("build workspace: %w", err)
 	}
 
 	slog.Info("Workspace rebuilt", "patterns", len(filtered))
-	span.SetStatus(codes.Ok, "")
 	return nil
 }
 
-func copyLogToWorkspace(dir string, args []string, span trace.Span) error {
+// copyLogToWorkspace copies the log to <dir>/logs/ and returns its name
+// there.
+func copyLogToWorkspace(dir string, args []string, span trace.Span) (string, error) {
 	if addLogStdin {
 		name := fmt.Sprintf("stdin-%d.log", time.Now().UnixNano())
 		data, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			return errors.Errorf("read stdin: %w", err)
+			return "", errors.Errorf("read stdin: %w", err)
 		}
 		if err := os.WriteFile(filepath.Join(dir, "logs", name), data, 0o644); err != nil {
-			return errors.Errorf("write stdin log: %w", err)
+			return "", errors.Errorf("write stdin log: %w", err)
 		}
 		slog.Info("Added stdin log", "name", name)
-		return nil
+		return name, nil
 	}
 
 	if len(args) < 1 {
-		return errors.New("logfile argument required (or use --stdin)")
+		return "", errors.New("logfile argument required (or use --stdin)")
 	}
 	logFile := args[0]
 	span.SetAttributes(attribute.String("log.file", logFile))
 
 	data, err := os.ReadFile(logFile)
 	if err != nil {
-		return errors.Errorf("read log file: %w", err)
+		return "", errors.Errorf("read log file: %w", err)
 	}
-	dest := filepath.Join(dir, "logs", filepath.Base(logFile))
-	if err := os.WriteFile(dest, data, 0o644); err != nil {
-		return errors.Errorf("copy log file: %w", err)
+	name := filepath.Base(logFile)
+	if err := os.WriteFile(filepath.Join(dir, "logs", name), data, 0o644); err != nil {
+		return "", errors.Errorf("copy log file: %w", err)
 	}
-	slog.Info("Added log file", "file", filepath.Base(logFile))
-	return nil
+	slog.Info("Added log file", "file", name)
+	return name, nil
 }
 
-func mergeAllLogs(ctx context.Context, dir string) (tagged []workspace.TaggedLine, content []string, fileCount int, err error) {
-	allLogs, err := workspace.ReadAllLogs(dir)
+// workspaceLogs is everything ingestAllLogs loaded from the store.
+type workspaceLogs struct {
+	// tagged and content hold the entries in file order; content is the
+	// text patterns are mined from.
+	tagged  []workspace.TaggedLine
+	content []string
+	// timeline holds the same entries merged across files by timestamp.
+	timeline []workspace.TaggedLine
+	// requests are the request sessions notes/requests.md lists, of
+	// requestTotal.
+	requests     []workspace.Request
+	requestTotal int
+	fileCount    int
+}
+
+// ingestAllLogs brings the workspace store up to date with every file in
+// <dir>/logs/, resuming each from its checkpoint, rebuilds the merged
+// timeline and returns the stored entries. Files are ingested with opts, or
+// with their entry in fileOpts if they have one.
+func ingestAllLogs(ctx context.Context, dir string, opts ingest.FileOptions, fileOpts map[string]ingest.FileOptions) (*workspaceLogs, error) {
+	fileNames, err := workspace.ListLogFiles(dir)
 	if err != nil {
-		return nil, nil, 0, errors.Errorf("read all logs: %w", err)
+		return nil, errors.Errorf("list log files: %w", err)
 	}
 
 	// Sort filenames for deterministic output across rebuilds
-	fileNames := make([]string, 0, len(allLogs))
-	for name := range allLogs {
-		fileNames = append(fileNames, name)
-	}
 	sort.Strings(fileNames)
 
-	var allTagged []workspace.TaggedLine
-	var allContent []string
+	st, err := workspace.OpenStore(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = st.Close() }()
+
+	logs := &workspaceLogs{fileCount: len(fileNames)}
 	for _, fileName := range fileNames {
-		lines := allLogs[fileName]
-		detector, err := multiline.NewDetector(multiline.DetectorConfig{})
+		o, ok := fileOpts[fileName]
+		if !ok {
+			o = opts
+		}
+		inserted, err := ingest.IngestFile(ctx, st, fileName, filepath.Join(dir, "logs", fileName), o)
+		if err != nil {
+			return nil, errors.Errorf("ingest %s: %w", fileName, err)
+		}
+		if inserted > 0 {
+			slog.Info("Ingested log file", "file", fileName, "entries", inserted)
+		}
+
+		entries, err := st.QueryLogs(ctx, store.QueryOpts{Source: fileName})
 		if err != nil {
-			return nil, nil, 0, errors.Errorf("multiline detector: %w", err)
+			return nil, errors.Errorf("load %s: %w", fileName, err)
 		}
-		merged := multiline.MergeSlice(ctx, lines, detector)
-		for _, m := range merged {
-			allTagged = append(allTagged, workspace.TaggedLine{
-				Content:  m.Content,
-				FileName: fileName,
-				LineNum:  m.StartLine,
-			})
-			allContent = append(allContent, m.Content)
+		for _, e := range entries {
+			tagged := workspace.TaggedEntry(e)
+			logs.tagged = append(logs.tagged, tagged)
+			logs.content = append(logs.content, tagged.PatternText())
 		}
 	}
-	return allTagged, allContent, len(allLogs), nil
+
+	timeline, err := ingest.BuildTimeline(ctx, st, fileNames, logsource.TimelineConfig{})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range timeline {
+		logs.timeline = append(logs.timeline, workspace.TaggedEntry(e))
+	}
+
+	logs.requests, logs.requestTotal, err = workspace.LoadRequests(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
 }
 
 func runDrain(ctx context.Context, content []string) ([]pattern.DrainCluster, error) {
@@ -317,7 +557,7 @@ func runDrain(ctx context.Context, content []string) ([]pattern.DrainCluster, er
 	return filtered, nil
 }
 
-func labelPatterns(ctx context.Context, filtered []pattern.DrainCluster, content []string, apiKey string) ([]semantic.SemanticLabel, error) {
+func labelPatterns(ctx context.Context, filtered []pattern.DrainCluster, content []string, apiKey, model string) ([]semantic.SemanticLabel, error) {
 	if len(filtered) == 0 {
 		return nil, nil
 	}
@@ -325,7 +565,7 @@ func labelPatterns(ctx context.Context, filtered []pattern.DrainCluster, content
 	slog.Info("Labeling patterns", "count", len(inputs))
 	labels, err := semantic.Label(ctx, semantic.Config{
 		APIKey: apiKey,
-		Model:  addLogModel,
+		Model:  model,
 	}, inputs)
 	if err != nil {
 		return nil, errors.Errorf("label: %w", err)
@@ -0,0 +1,468 @@
+package event
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goerrors "github.com/go-errors/errors"
+)
+
+// Attribute keys the access log parsers normalize request fields to.
+const (
+	AttrClientIP  = "client_ip"
+	AttrUser      = "user"
+	AttrMethod    = "method"
+	AttrPath      = "path"
+	AttrQuery     = "query"
+	AttrProtocol  = "protocol"
+	AttrStatus    = "status"
+	AttrBytes     = "bytes"
+	AttrLatencyMS = "latency_ms"
+	AttrUserAgent = "user_agent"
+	AttrReferrer  = "referrer"
+)
+
+// httpDateLayout is the Common Log Format time, e.g. 28/Mar/2024:13:45:30 +0000.
+const httpDateLayout = "02/Jan/2006:15:04:05 -0700"
+
+// combinedLogPattern matches the Apache/Nginx common log format with the
+// optional referrer and user agent of the combined format. Nginx setups
+// often append fields such as $request_time; those are ignored.
+var combinedLogPattern = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\d+|-)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?(?: |$)`)
+
+// accessLogParser parses Apache httpd and Nginx common and combined access
+// logs.
+type accessLogParser struct{}
+
+func (accessLogParser) Parse(line string) (*ParsedLine, bool) {
+	m := combinedLogPattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, false
+	}
+	ts, err := time.Parse(httpDateLayout, m[4])
+	if err != nil {
+		return nil, false
+	}
+
+	event := newBaseEvent(line)
+	event.Timestamp = &ts
+	setAttr(&event, AttrClientIP, m[1])
+	setAttr(&event, AttrUser, m[3])
+	setRequest(&event, m[5])
+	setInt(&event, AttrStatus, m[6])
+	setInt(&event, AttrBytes, m[7])
+	setAttr(&event, AttrReferrer, m[8])
+	setAttr(&event, AttrUserAgent, m[9])
+	return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event}, true
+}
+
+// albTypes are the request types that start an ALB access log entry.
+var albTypes = map[string]bool{"http": true, "https": true, "h2": true, "grpcs": true, "ws": true, "wss": true}
+
+// elbParser parses AWS Classic Load Balancer and Application Load
+// Balancer access logs. Both are space-separated with quoted request and
+// user agent fields; ALB entries start with the request type.
+type elbParser struct{}
+
+func (elbParser) Parse(line string) (*ParsedLine, bool) {
+	fields, ok := splitQuotedFields(line)
+	if !ok || len(fields) < 15 {
+		return nil, false
+	}
+	requestType := fields[0]
+	alb := albTypes[requestType]
+	if alb {
+		if len(fields) < 16 {
+			return nil, false
+		}
+		fields = fields[1:]
+	}
+	ts, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return nil, false
+	}
+	client, _, err := net.SplitHostPort(fields[2])
+	if err != nil {
+		return nil, false
+	}
+
+	event := newBaseEvent(line)
+	event.Timestamp = &ts
+	setAttr(&event, "load_balancer", fields[1])
+	setAttr(&event, AttrClientIP, client)
+	setAttr(&event, "target", fields[3])
+	// Each processing time is -1 if the request never reached that stage.
+	var latency float64
+	complete := true
+	for _, f := range fields[4:7] {
+		secs, err := strconv.ParseFloat(f, 64)
+		if err != nil || secs < 0 {
+			complete = false
+			break
+		}
+		latency += secs
+	}
+	if complete {
+		event.Attrs[AttrLatencyMS] = formatMillis(latency)
+	}
+	setInt(&event, AttrStatus, fields[7])
+	setInt(&event, "target_status", fields[8])
+	setInt(&event, "received_bytes", fields[9])
+	setInt(&event, AttrBytes, fields[10])
+	setRequest(&event, fields[11])
+	setAttr(&event, AttrUserAgent, fields[12])
+	setAttr(&event, "ssl_cipher", fields[13])
+	setAttr(&event, "ssl_protocol", fields[14])
+	if alb {
+		event.Attrs["request_type"] = requestType
+		if len(fields) > 17 {
+			setAttr(&event, "target_group_arn", fields[15])
+			setAttr(&event, "trace_id", fields[16])
+			setAttr(&event, "domain", fields[17])
+		}
+	}
+	return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event}, true
+}
+
+// w3cFieldAttrs maps W3C extended log fields to normalized attrs.
+var w3cFieldAttrs = map[string]string{
+	"c-ip":            AttrClientIP,
+	"cs-username":     AttrUser,
+	"cs-method":       AttrMethod,
+	"cs-uri-stem":     AttrPath,
+	"cs-uri-query":    AttrQuery,
+	"cs-version":      AttrProtocol,
+	"sc-status":       AttrStatus,
+	"sc-bytes":        AttrBytes,
+	"cs(user-agent)":  AttrUserAgent,
+	"cs(referer)":     AttrReferrer,
+	"s-ip":            "server_ip",
+	"s-port":          "server_port",
+	"cs-host":         "host",
+	"sc-substatus":    "substatus",
+	"sc-win32-status": "win32_status",
+	"cs-bytes":        "received_bytes",
+}
+
+// w3cDirectives are the header lines of the W3C extended log format.
+var w3cDirectives = []string{"#Version:", "#Fields:", "#Software:", "#Start-Date:", "#End-Date:", "#Date:", "#Remark:"}
+
+// w3cParser parses W3C extended logs as written by IIS. Data lines are
+// read by the most recent #Fields header of the same source, so each
+// source needs its own instance (see SourceParser). The header is its
+// state, so a source resumed past it keeps being recognized (see
+// StatefulParser).
+type w3cParser struct {
+	mu     sync.Mutex
+	fields []string
+}
+
+var _ StatefulParser = (*w3cParser)(nil)
+
+func (p *w3cParser) NewSource() Parser {
+	return &w3cParser{}
+}
+
+// State returns the fields of the last #Fields header.
+func (p *w3cParser) State() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return strings.Join(p.fields, " ")
+}
+
+func (p *w3cParser) WithState(state string) (Parser, error) {
+	return &w3cParser{fields: strings.Fields(state)}, nil
+}
+
+func (p *w3cParser) Parse(line string) (*ParsedLine, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A header can trail a data line within one multiline entry when a
+	// server restarts mid-file; it applies to the lines after it.
+	first, rest, _ := strings.Cut(line, "\n")
+	defer func() {
+		for _, l := range strings.Split(rest, "\n") {
+			p.readDirective(l)
+		}
+	}()
+
+	if strings.HasPrefix(first, "#") {
+		if !p.readDirective(first) {
+			return nil, false
+		}
+		return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: newBaseEvent(line)}, true
+	}
+	if len(p.fields) == 0 {
+		return nil, false
+	}
+	values := strings.Fields(first)
+	if len(values) != len(p.fields) {
+		return nil, false
+	}
+
+	event := newBaseEvent(line)
+	var date, clock string
+	for i, field := range p.fields {
+		value := values[i]
+		switch field {
+		case "date":
+			date = value
+			continue
+		case "time":
+			clock = value
+			continue
+		case "time-taken":
+			// IIS writes milliseconds.
+			setInt(&event, AttrLatencyMS, value)
+			continue
+		case "cs(user-agent)", "cs(referer)", "cs(cookie)":
+			// IIS replaces spaces with '+' in these fields.
+			value = strings.ReplaceAll(value, "+", " ")
+		}
+		key, ok := w3cFieldAttrs[field]
+		if !ok {
+			key = field
+		}
+		if key == AttrStatus || key == AttrBytes {
+			setInt(&event, key, value)
+			continue
+		}
+		setAttr(&event, key, value)
+	}
+	if date != "" && clock != "" {
+		// W3C extended logs are always in UTC.
+		if ts, err := time.Parse("2006-01-02 15:04:05", date+" "+clock); err == nil {
+			event.Timestamp = &ts
+		}
+	}
+	return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event}, true
+}
+
+// readDirective records a #Fields header and reports whether l is a W3C
+// directive at all.
+func (p *w3cParser) readDirective(l string) bool {
+	for _, d := range w3cDirectives {
+		if !strings.HasPrefix(l, d) {
+			continue
+		}
+		if d == "#Fields:" {
+			p.fields = strings.Fields(strings.ToLower(strings.TrimPrefix(l, d)))
+		}
+		return true
+	}
+	return false
+}
+
+// nginxVariablePattern matches $name and ${name} in a log_format.
+var nginxVariablePattern = regexp.MustCompile(`\$(?:\{(\w+)\}|(\w+))`)
+
+// nginxVariables normalizes the Nginx variables an access log commonly
+// contains; others become attrs named after the variable.
+var nginxVariables = map[string]func(e *Event, value string){
+	"remote_addr":          func(e *Event, v string) { setAttr(e, AttrClientIP, v) },
+	"remote_user":          func(e *Event, v string) { setAttr(e, AttrUser, v) },
+	"request":              setRequest,
+	"request_method":       func(e *Event, v string) { setAttr(e, AttrMethod, v) },
+	"uri":                  func(e *Event, v string) { setAttr(e, AttrPath, v) },
+	"args":                 func(e *Event, v string) { setAttr(e, AttrQuery, v) },
+	"server_protocol":      func(e *Event, v string) { setAttr(e, AttrProtocol, v) },
+	"status":               func(e *Event, v string) { setInt(e, AttrStatus, v) },
+	"body_bytes_sent":      func(e *Event, v string) { setInt(e, AttrBytes, v) },
+	"bytes_sent":           func(e *Event, v string) { setInt(e, "bytes_sent", v) },
+	"http_referer":         func(e *Event, v string) { setAttr(e, AttrReferrer, v) },
+	"http_user_agent":      func(e *Event, v string) { setAttr(e, AttrUserAgent, v) },
+	"http_x_forwarded_for": func(e *Event, v string) { setAttr(e, "forwarded_for", v) },
+	"request_time":         func(e *Event, v string) { setSeconds(e, AttrLatencyMS, v) },
+	"upstream_response_time": func(e *Event, v string) {
+		setSeconds(e, "upstream_latency_ms", v)
+	},
+	"request_uri": func(e *Event, v string) {
+		path, query, _ := strings.Cut(v, "?")
+		setAttr(e, AttrPath, path)
+		setAttr(e, AttrQuery, query)
+	},
+	"time_local": func(e *Event, v string) {
+		if ts, err := time.Parse(httpDateLayout, v); err == nil {
+			e.Timestamp = &ts
+		}
+	},
+	"time_iso8601": func(e *Event, v string) {
+		if ts, err := time.Parse(time.RFC3339, v); err == nil {
+			e.Timestamp = &ts
+		}
+	},
+	"msec": func(e *Event, v string) {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			ts := time.UnixMilli(int64(secs * 1000)).UTC()
+			e.Timestamp = &ts
+		}
+	},
+}
+
+// NginxParser parses access logs written with a custom Nginx log_format.
+type NginxParser struct {
+	re   *regexp.Regexp
+	vars []string
+}
+
+var _ Parser = (*NginxParser)(nil)
+
+// NewNginxParser compiles an Nginx log_format string, e.g.
+//
+//	$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent $request_time
+//
+// Each variable captures up to the literal text that follows it.
+func NewNginxParser(format string) (*NginxParser, error) {
+	locs := nginxVariablePattern.FindAllStringSubmatchIndex(format, -1)
+	if len(locs) == 0 {
+		return nil, goerrors.Errorf("nginx log_format %q has no variables", format)
+	}
+
+	var (
+		b    strings.Builder
+		vars []string
+		prev int
+	)
+	b.WriteString("^")
+	for i, loc := range locs {
+		b.WriteString(regexp.QuoteMeta(format[prev:loc[0]]))
+		var name string
+		if loc[2] >= 0 {
+			name = format[loc[2]:loc[3]]
+		} else {
+			name = format[loc[4]:loc[5]]
+		}
+		vars = append(vars, name)
+
+		next := len(format)
+		if i+1 < len(locs) {
+			next = locs[i+1][0]
+		}
+		literal := format[loc[1]:next]
+		switch {
+		case loc[1] == len(format):
+			b.WriteString("(.*)")
+		case literal == "":
+			return nil, goerrors.Errorf("nginx log_format %q: $%s and the next variable have no separator", format, name)
+		case literal[0] == '"':
+			b.WriteString(`([^"]*)`)
+		case literal[0] == ']':
+			b.WriteString(`([^\]]*)`)
+		case literal[0] == ' ':
+			b.WriteString(`(\S*)`)
+		default:
+			b.WriteString("(.*?)")
+		}
+		prev = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(format[prev:]))
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, goerrors.Errorf("compile nginx log_format %q: %w", format, err)
+	}
+	return &NginxParser{re: re, vars: vars}, nil
+}
+
+// Parse implements Parser.
+func (p *NginxParser) Parse(line string) (*ParsedLine, bool) {
+	m := p.re.FindStringSubmatch(line)
+	if m == nil {
+		return nil, false
+	}
+	event := newBaseEvent(line)
+	for i, name := range p.vars {
+		value := m[i+1]
+		if set, ok := nginxVariables[name]; ok {
+			set(&event, value)
+			continue
+		}
+		setAttr(&event, name, value)
+	}
+	return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event}, true
+}
+
+// setAttr sets a non-empty value; "-" is how access logs write an empty
+// field.
+func setAttr(e *Event, key, value string) {
+	if value == "" || value == "-" {
+		return
+	}
+	e.Attrs[key] = value
+}
+
+// setInt sets a value that must be an integer.
+func setInt(e *Event, key, value string) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		e.Attrs[key] = strconv.FormatInt(n, 10)
+	}
+}
+
+// setSeconds sets a duration given in seconds as milliseconds.
+func setSeconds(e *Event, key, value string) {
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs >= 0 {
+		e.Attrs[key] = formatMillis(secs)
+	}
+}
+
+func formatMillis(secs float64) string {
+	return strconv.FormatFloat(secs*1000, 'f', -1, 64)
+}
+
+// setRequest splits an HTTP request line such as "GET /a?b=1 HTTP/1.1"
+// into method, path, query and protocol. Load balancers log absolute URLs,
+// whose host is kept as well. Anything else is kept as "request".
+func setRequest(e *Event, request string) {
+	parts := strings.Fields(request)
+	if len(parts) < 2 || len(parts) > 3 {
+		setAttr(e, "request", request)
+		return
+	}
+	setAttr(e, AttrMethod, parts[0])
+	target := parts[1]
+	if u, err := url.Parse(target); err == nil && u.IsAbs() {
+		setAttr(e, "host", u.Host)
+		target = u.RequestURI()
+	}
+	path, query, _ := strings.Cut(target, "?")
+	setAttr(e, AttrPath, path)
+	setAttr(e, AttrQuery, query)
+	if len(parts) == 3 {
+		setAttr(e, AttrProtocol, parts[2])
+	}
+}
+
+// splitQuotedFields splits line on spaces, keeping double-quoted fields
+// (with backslash escapes) together and unquoted.
+func splitQuotedFields(line string) ([]string, bool) {
+	var fields []string
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+		if line[i] != '"' {
+			end := strings.IndexByte(line[i:], ' ')
+			if end < 0 {
+				end = len(line) - i
+			}
+			fields = append(fields, line[i:i+end])
+			i += end
+			continue
+		}
+		result := scanQuotedValue(line, i+1)
+		if !result.ok {
+			return nil, false
+		}
+		fields = append(fields, result.value)
+		i = result.next
+	}
+	return fields, true
+}
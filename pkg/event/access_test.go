@@ -0,0 +1,166 @@
+package event
+
+import "testing"
+
+func TestParseLine_CombinedAccessLog(t *testing.T) {
+	line := `203.0.113.9 - frank [28/Mar/2024:13:45:30 +0000] "GET /api/users?id=7 HTTP/1.1" 404 512 "https://example.com/" "curl/8.4.0"`
+
+	parsed := ParseLine(line)
+
+	if parsed.Parser != ParserAccessLog {
+		t.Fatalf("expected %s, got %q", ParserAccessLog, parsed.Parser)
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-28T13:45:30Z")
+	assertAttr(t, parsed.Event.Attrs, AttrClientIP, "203.0.113.9")
+	assertAttr(t, parsed.Event.Attrs, AttrUser, "frank")
+	assertAttr(t, parsed.Event.Attrs, AttrMethod, "GET")
+	assertAttr(t, parsed.Event.Attrs, AttrPath, "/api/users")
+	assertAttr(t, parsed.Event.Attrs, AttrQuery, "id=7")
+	assertAttr(t, parsed.Event.Attrs, AttrProtocol, "HTTP/1.1")
+	assertAttr(t, parsed.Event.Attrs, AttrStatus, "404")
+	assertAttr(t, parsed.Event.Attrs, AttrBytes, "512")
+	assertAttr(t, parsed.Event.Attrs, AttrReferrer, "https://example.com/")
+	assertAttr(t, parsed.Event.Attrs, AttrUserAgent, "curl/8.4.0")
+}
+
+func TestParseLine_CommonAccessLog(t *testing.T) {
+	parsed := ParseLine(`10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "POST /login HTTP/1.0" 302 -`)
+
+	if parsed.Parser != ParserAccessLog {
+		t.Fatalf("expected %s, got %q", ParserAccessLog, parsed.Parser)
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2000-10-10T20:55:36Z")
+	assertAttr(t, parsed.Event.Attrs, AttrStatus, "302")
+	for _, key := range []string{AttrUser, AttrBytes, AttrUserAgent} {
+		if _, ok := parsed.Event.Attrs[key]; ok {
+			t.Errorf("expected no %s for an empty field", key)
+		}
+	}
+}
+
+func TestParseLine_ClassicELB(t *testing.T) {
+	line := `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000086 0.001048 0.001337 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.38.0" DHE-RSA-AES128-SHA TLSv1.2`
+
+	parsed := ParseLine(line)
+
+	if parsed.Parser != ParserELB {
+		t.Fatalf("expected %s, got %q", ParserELB, parsed.Parser)
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2015-05-13T23:39:43Z")
+	assertAttr(t, parsed.Event.Attrs, AttrClientIP, "192.168.131.39")
+	assertAttr(t, parsed.Event.Attrs, "host", "www.example.com:443")
+	assertAttr(t, parsed.Event.Attrs, AttrPath, "/")
+	assertAttr(t, parsed.Event.Attrs, AttrStatus, "200")
+	assertAttr(t, parsed.Event.Attrs, AttrBytes, "57")
+	assertAttr(t, parsed.Event.Attrs, AttrLatencyMS, "2.471")
+	assertAttr(t, parsed.Event.Attrs, AttrUserAgent, "curl/7.38.0")
+}
+
+func TestParseLine_ALB(t *testing.T) {
+	line := `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/api/v1?x=1 HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2018-07-02T22:22:48.364000Z "authenticate,forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+
+	parsed := ParseLine(line)
+
+	if parsed.Parser != ParserELB {
+		t.Fatalf("expected %s, got %q", ParserELB, parsed.Parser)
+	}
+	assertAttr(t, parsed.Event.Attrs, "request_type", "https")
+	assertAttr(t, parsed.Event.Attrs, AttrMethod, "GET")
+	assertAttr(t, parsed.Event.Attrs, AttrPath, "/api/v1")
+	assertAttr(t, parsed.Event.Attrs, AttrQuery, "x=1")
+	assertAttr(t, parsed.Event.Attrs, AttrLatencyMS, "171")
+	assertAttr(t, parsed.Event.Attrs, AttrTraceID, "583372811d84f3d73c47ec4e58577259")
+	assertAttr(t, parsed.Event.Attrs, AttrXRayTraceID, "1-58337281-1d84f3d73c47ec4e58577259")
+	assertAttr(t, parsed.Event.Attrs, "domain", "www.example.com")
+}
+
+func TestW3CParserFollowsFieldsHeader(t *testing.T) {
+	r := NewRegistry().ForSource()
+
+	for _, header := range []string{
+		"#Software: Microsoft Internet Information Services 10.0",
+		"#Fields: date time s-ip cs-method cs-uri-stem cs-uri-query s-port cs-username c-ip cs(User-Agent) sc-status sc-substatus sc-win32-status time-taken",
+	} {
+		if got := r.Parse(header).Parser; got != ParserW3C {
+			t.Fatalf("header %q: expected %s, got %q", header, ParserW3C, got)
+		}
+	}
+
+	parsed := r.Parse("2024-03-28 13:45:30 10.0.0.2 GET /index.html q=1 443 - 203.0.113.9 Mozilla/5.0+(Windows+NT+10.0) 500 0 0 15")
+	if parsed.Parser != ParserW3C {
+		t.Fatalf("expected %s, got %q", ParserW3C, parsed.Parser)
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-28T13:45:30Z")
+	assertAttr(t, parsed.Event.Attrs, AttrMethod, "GET")
+	assertAttr(t, parsed.Event.Attrs, AttrPath, "/index.html")
+	assertAttr(t, parsed.Event.Attrs, AttrQuery, "q=1")
+	assertAttr(t, parsed.Event.Attrs, AttrClientIP, "203.0.113.9")
+	assertAttr(t, parsed.Event.Attrs, AttrUserAgent, "Mozilla/5.0 (Windows NT 10.0)")
+	assertAttr(t, parsed.Event.Attrs, AttrStatus, "500")
+	assertAttr(t, parsed.Event.Attrs, AttrLatencyMS, "15")
+	assertAttr(t, parsed.Event.Attrs, "server_port", "443")
+	if _, ok := parsed.Event.Attrs[AttrUser]; ok {
+		t.Error("expected no user for '-'")
+	}
+
+	// Another source has not seen the header.
+	other := NewRegistry().ForSource()
+	if got := other.Parse("2024-03-28 13:45:30 10.0.0.2 GET /index.html q=1 443 - 203.0.113.9 Mozilla/5.0 500 0 0 15").Parser; got == ParserW3C {
+		t.Error("expected the #Fields header not to leak into another source")
+	}
+}
+
+func TestW3CParserStateIsPerSource(t *testing.T) {
+	header := "#Fields: date time cs-method cs-uri-stem sc-status time-taken"
+	line := "2024-03-28 13:45:30 GET /index.html 200 15"
+
+	// A shared registry parses each line on its own.
+	ParseLine(header)
+	if got := ParseLine(line).Parser; got == ParserW3C {
+		t.Error("expected DefaultRegistry not to keep the #Fields header")
+	}
+
+	r := NewRegistry().ForSource()
+	r.Parse(header)
+	state := r.SourceState()
+	if state[ParserW3C] == "" {
+		t.Fatalf("expected the #Fields header in the source state, got %v", state)
+	}
+	resumed := NewRegistry().ForSource()
+	if err := resumed.RestoreSourceState(state); err != nil {
+		t.Fatalf("RestoreSourceState: %v", err)
+	}
+	parsed := resumed.Parse(line)
+	if parsed.Parser != ParserW3C {
+		t.Fatalf("expected %s after restoring the state, got %q", ParserW3C, parsed.Parser)
+	}
+	assertAttr(t, parsed.Event.Attrs, AttrStatus, "200")
+}
+
+func TestNginxParser(t *testing.T) {
+	p, err := NewNginxParser(`$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent" rt=$request_time uct=${upstream_connect_time}`)
+	if err != nil {
+		t.Fatalf("NewNginxParser: %v", err)
+	}
+
+	parsed, ok := p.Parse(`198.51.100.4 - - [28/Mar/2024:13:45:30 +0100] "PUT /v1/items/9 HTTP/2.0" 201 17 "-" "okhttp/4.12" rt=0.250 uct=0.001`)
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-28T12:45:30Z")
+	assertAttr(t, parsed.Event.Attrs, AttrClientIP, "198.51.100.4")
+	assertAttr(t, parsed.Event.Attrs, AttrMethod, "PUT")
+	assertAttr(t, parsed.Event.Attrs, AttrPath, "/v1/items/9")
+	assertAttr(t, parsed.Event.Attrs, AttrStatus, "201")
+	assertAttr(t, parsed.Event.Attrs, AttrBytes, "17")
+	assertAttr(t, parsed.Event.Attrs, AttrUserAgent, "okhttp/4.12")
+	assertAttr(t, parsed.Event.Attrs, AttrLatencyMS, "250")
+	assertAttr(t, parsed.Event.Attrs, "upstream_connect_time", "0.001")
+
+	if _, ok := p.Parse("not an access log"); ok {
+		t.Error("expected no match")
+	}
+	if _, err := NewNginxParser("$remote_addr$status"); err == nil {
+		t.Error("expected adjacent variables to be rejected")
+	}
+}
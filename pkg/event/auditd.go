@@ -0,0 +1,221 @@
+package event
+
+import (
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Attribute keys the auditd parser adds to every record.
+const (
+	// AttrAuditType is the record type, e.g. SYSCALL or PATH.
+	AttrAuditType = "audit_type"
+	// AttrAuditID identifies the audit event a record belongs to as
+	// "<seconds>.<millis>:<serial>"; all records of one event share it.
+	AttrAuditID = "audit_id"
+	// AttrAuditSerial is the serial part of AttrAuditID.
+	AttrAuditSerial = "audit_serial"
+	// AttrAuditEvent is the type of the first record of the event, e.g.
+	// SYSCALL for the PATH and CWD records that follow it.
+	AttrAuditEvent = "audit_event"
+	// AttrAuditRecord is the 0-based position of the record in its event.
+	AttrAuditRecord = "audit_record"
+)
+
+// auditHeaderPattern matches "type=SYSCALL msg=audit(1364481363.243:24287): ",
+// optionally preceded by the node name.
+var auditHeaderPattern = regexp.MustCompile(`^(?:node=(\S+) )?type=(\S+) msg=audit\((\d+)\.(\d{3}):(\d+)\): ?`)
+
+// auditHexFields may be logged hex-encoded when their value contains
+// spaces, quotes or control characters.
+var auditHexFields = map[string]bool{
+	"proctitle": true, "comm": true, "exe": true, "cwd": true, "name": true,
+	"cmd": true, "acct": true, "data": true, "path": true,
+}
+
+// auditContextFields are copied from the first record of an event to the
+// records that follow it, so e.g. a PATH record carries the exe and key of
+// the syscall that touched the path.
+var auditContextFields = []string{"syscall", "success", "exit", "pid", "ppid", "uid", "auid", "ses", "tty", "comm", "exe", "key", "subj"}
+
+// maxOpenAuditEvents bounds how many events an auditdParser remembers.
+// Records of one event are written together, so a few suffice.
+const maxOpenAuditEvents = 64
+
+// auditdParser parses Linux audit records as written to audit.log:
+//
+//	type=SYSCALL msg=audit(1364481363.243:24287): arch=c000003e syscall=2 success=no exit=-13 comm="cat" exe="/bin/cat" key="sshd_config"
+//	type=PATH msg=audit(1364481363.243:24287): item=0 name="/etc/ssh/sshd_config" inode=409248 mode=0100600
+//	type=EOE msg=audit(1364481363.243:24287):
+//
+// Fields become attrs, hex-encoded values are decoded, and the quoted msg
+// of user-space records is flattened into its fields. Records are
+// correlated by their serial: each gets AttrAuditID and the context of the
+// event's first record (see auditContextFields), which needs state per
+// source (see SourceParser).
+type auditdParser struct {
+	mu     sync.Mutex
+	events map[string]*auditEvent
+	order  []string
+}
+
+type auditEvent struct {
+	typ     string
+	records int
+	context map[string]string
+}
+
+func (p *auditdParser) NewSource() Parser {
+	return &auditdParser{}
+}
+
+func (p *auditdParser) Parse(line string) (*ParsedLine, bool) {
+	first, _, _ := strings.Cut(line, "\n")
+	m := auditHeaderPattern.FindStringSubmatchIndex(first)
+	if m == nil {
+		return nil, false
+	}
+	group := func(i int) string {
+		if m[2*i] < 0 {
+			return ""
+		}
+		return first[m[2*i]:m[2*i+1]]
+	}
+	secs, err := strconv.ParseInt(group(3), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	millis, _ := strconv.ParseInt(group(4), 10, 64)
+	ts := time.Unix(secs, millis*int64(time.Millisecond)).UTC()
+
+	event := newBaseEvent(line)
+	event.Timestamp = &ts
+	typ := group(2)
+	id := group(3) + "." + group(4) + ":" + group(5)
+	setAttr(&event, "node", group(1))
+	event.Attrs[AttrAuditType] = typ
+	event.Attrs[AttrAuditID] = id
+	event.Attrs[AttrAuditSerial] = group(5)
+
+	for _, f := range scanAuditFields(first[m[1]:]) {
+		if f.key == "msg" && strings.Contains(f.value, "=") {
+			// User-space records quote their own fields:
+			// msg='op=PAM:authentication acct="root" res=failed'.
+			for _, inner := range scanAuditFields(f.value) {
+				setAttr(&event, inner.key, decodeAuditValue(inner))
+			}
+			continue
+		}
+		setAttr(&event, f.key, decodeAuditValue(f))
+	}
+
+	p.correlate(&event, typ, id)
+	return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event}, true
+}
+
+// correlate links a record to the earlier records of its event.
+func (p *auditdParser) correlate(event *Event, typ, id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ev, ok := p.events[id]
+	if !ok {
+		if p.events == nil {
+			p.events = make(map[string]*auditEvent)
+		}
+		if len(p.order) >= maxOpenAuditEvents {
+			delete(p.events, p.order[0])
+			p.order = p.order[1:]
+		}
+		ev = &auditEvent{typ: typ, context: make(map[string]string)}
+		for _, key := range auditContextFields {
+			if v, ok := event.Attrs[key]; ok {
+				ev.context[key] = v
+			}
+		}
+		p.events[id] = ev
+		p.order = append(p.order, id)
+	}
+
+	event.Attrs[AttrAuditEvent] = ev.typ
+	event.Attrs[AttrAuditRecord] = strconv.Itoa(ev.records)
+	ev.records++
+	for key, v := range ev.context {
+		if _, ok := event.Attrs[key]; !ok {
+			event.Attrs[key] = v
+		}
+	}
+
+	if typ == "EOE" {
+		delete(p.events, id)
+		for i, open := range p.order {
+			if open == id {
+				p.order = append(p.order[:i], p.order[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// auditField is one key=value field of an audit record.
+type auditField struct {
+	assignment
+	quoted bool
+}
+
+// scanAuditFields splits audit record fields. Values are bare, or in
+// double or single quotes without escapes. The enriched format separates
+// the translated fields (UID="root") with a 0x1d byte.
+func scanAuditFields(s string) []auditField {
+	var fields []auditField
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\x1d' {
+			i++
+			continue
+		}
+		eq := strings.IndexAny(s[i:], "= \x1d")
+		if eq < 0 || s[i+eq] != '=' {
+			// A bare word; skip it.
+			end := strings.IndexAny(s[i:], " \x1d")
+			if end < 0 {
+				break
+			}
+			i += end
+			continue
+		}
+		key := s[i : i+eq]
+		i += eq + 1
+
+		if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end >= 0 {
+				fields = append(fields, auditField{assignment{key, s[i+1 : i+1+end]}, true})
+				i += end + 2
+				continue
+			}
+		}
+		end := strings.IndexAny(s[i:], " \x1d")
+		if end < 0 {
+			end = len(s) - i
+		}
+		fields = append(fields, auditField{assignment{key, s[i : i+end]}, false})
+		i += end
+	}
+	return fields
+}
+
+// decodeAuditValue decodes a hex-encoded value of one of auditHexFields.
+// proctitle separates the arguments with NUL bytes; they become spaces.
+func decodeAuditValue(f auditField) string {
+	if f.quoted || !auditHexFields[f.key] || f.value == "(null)" || len(f.value)%2 != 0 {
+		return f.value
+	}
+	decoded, err := hex.DecodeString(f.value)
+	if err != nil {
+		return f.value
+	}
+	return strings.TrimRight(strings.ReplaceAll(string(decoded), "\x00", " "), " ")
+}
@@ -0,0 +1,60 @@
+package event
+
+import "testing"
+
+func TestParseLine_Auditd(t *testing.T) {
+	parsed := ParseLine(`type=USER_AUTH msg=audit(1710590400.123:4242): pid=811 uid=0 auid=4294967295 ses=4294967295 msg='op=PAM:authentication grantors=? acct="root" exe="/usr/sbin/sshd" hostname=203.0.113.9 addr=203.0.113.9 terminal=ssh res=failed'`)
+
+	if parsed.Parser != ParserAuditd {
+		t.Fatalf("expected %s, got %q", ParserAuditd, parsed.Parser)
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-16T12:00:00Z")
+	if parsed.Event.Timestamp.Nanosecond() != 123000000 {
+		t.Errorf("expected milliseconds, got %v", parsed.Event.Timestamp)
+	}
+	assertAttr(t, parsed.Event.Attrs, AttrAuditType, "USER_AUTH")
+	assertAttr(t, parsed.Event.Attrs, AttrAuditID, "1710590400.123:4242")
+	assertAttr(t, parsed.Event.Attrs, AttrAuditSerial, "4242")
+	assertAttr(t, parsed.Event.Attrs, "op", "PAM:authentication")
+	assertAttr(t, parsed.Event.Attrs, "acct", "root")
+	assertAttr(t, parsed.Event.Attrs, "res", "failed")
+}
+
+func TestAuditdCorrelation(t *testing.T) {
+	p := (&auditdParser{}).NewSource()
+
+	records := []string{
+		`node=web1 type=SYSCALL msg=audit(1364481363.243:24287): arch=c000003e syscall=2 success=no exit=-13 pid=3538 uid=1000 comm="cat" exe="/bin/cat" key="sshd_config"`,
+		`node=web1 type=CWD msg=audit(1364481363.243:24287): cwd="/home/shadowman"`,
+		`node=web1 type=PATH msg=audit(1364481363.243:24287): item=0 name="/etc/ssh/sshd_config" inode=409248`,
+		`node=web1 type=PROCTITLE msg=audit(1364481363.243:24287): proctitle=636174002F6574632F7373682F737368645F636F6E666967`,
+		`node=web1 type=EOE msg=audit(1364481363.243:24287):`,
+	}
+	var parsed []*ParsedLine
+	for _, r := range records {
+		pl, ok := p.Parse(r)
+		if !ok {
+			t.Fatalf("expected match for %q", r)
+		}
+		parsed = append(parsed, pl)
+	}
+
+	path := parsed[2].Event.Attrs
+	assertAttr(t, path, AttrAuditEvent, "SYSCALL")
+	assertAttr(t, path, AttrAuditRecord, "2")
+	assertAttr(t, path, "node", "web1")
+	assertAttr(t, path, "name", "/etc/ssh/sshd_config")
+	assertAttr(t, path, "exe", "/bin/cat")
+	assertAttr(t, path, "key", "sshd_config")
+	assertAttr(t, path, "success", "no")
+	assertAttr(t, parsed[3].Event.Attrs, "proctitle", "cat /etc/ssh/sshd_config")
+	assertAttr(t, parsed[4].Event.Attrs, AttrAuditRecord, "4")
+
+	// The event is closed by EOE; a reused serial starts a new one.
+	next, _ := p.Parse(`type=PATH msg=audit(1364481363.243:24287): item=0 name="/tmp"`)
+	assertAttr(t, next.Event.Attrs, AttrAuditEvent, "PATH")
+	assertAttr(t, next.Event.Attrs, AttrAuditRecord, "0")
+	if _, ok := next.Event.Attrs["exe"]; ok {
+		t.Error("expected no context from the closed event")
+	}
+}
@@ -0,0 +1,442 @@
+package event
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strrl/lapp/pkg/timestamp"
+)
+
+// cefHeaderFields names the CEF header fields between "CEF:Version|" and
+// the extension.
+var cefHeaderFields = []string{"device_vendor", "device_product", "device_version", "signature_id", "name", "severity"}
+
+// cefTimestampKeys are the extension keys that carry the event time, in
+// order of preference.
+var cefTimestampKeys = []string{"rt", "start", "end"}
+
+// cefLabelPattern matches the custom extension label keys such as
+// cs1Label or flexString2Label.
+var cefLabelPattern = regexp.MustCompile(`^((?:cs|cn|cfp|c6a|flexString|flexNumber|flexDate|deviceCustomDate)\d+)Label$`)
+
+// cefParser parses ArcSight Common Event Format records, e.g.
+//
+//	CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232
+//
+// optionally behind a syslog header. Header fields become attrs named by
+// cefHeaderFields, extensions keep their CEF keys, and custom fields are
+// renamed after their labels (cs1Label=policy cs1=deny gives policy=deny).
+type cefParser struct {
+	now func() time.Time
+}
+
+func (p cefParser) Parse(line string) (*ParsedLine, bool) {
+	first, _, _ := strings.Cut(line, "\n")
+	start := securityHeaderStart(first, "CEF:")
+	if start < 0 {
+		return nil, false
+	}
+	parts := splitCEFHeader(first[start+len("CEF:"):], len(cefHeaderFields)+1)
+	if len(parts) != len(cefHeaderFields)+2 {
+		return nil, false
+	}
+
+	event := newBaseEvent(line)
+	applySyslogPrefix(&event, first[:start], p.now)
+	setAttr(&event, "cef_version", parts[0])
+	for i, key := range cefHeaderFields {
+		setAttr(&event, key, parts[i+1])
+	}
+	if level, ok := securitySeverityLevel(parts[len(cefHeaderFields)]); ok {
+		event.Attrs["level"] = level
+	}
+
+	ext := scanSpacedExtension(parts[len(parts)-1])
+	setExtension(&event, ext, cefTimestampKeys, p.now)
+	if _, ok := event.Attrs["msg"]; !ok {
+		setAttr(&event, "msg", parts[len(cefHeaderFields)-1])
+	}
+	return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event}, true
+}
+
+// leefParser parses IBM QRadar Log Event Extended Format records:
+//
+//	LEEF:1.0|Vendor|Product|Version|EventID|key=value<TAB>key=value
+//	LEEF:2.0|Vendor|Product|Version|EventID|^|key=value^key=value
+//
+// LEEF 2.0 names the extension delimiter, as a character or in hex such as
+// x09; otherwise it is a tab. Extensions written with spaces instead are
+// split as in CEF. devTime is read with devTimeFormat if one is given.
+type leefParser struct {
+	now func() time.Time
+}
+
+func (p leefParser) Parse(line string) (*ParsedLine, bool) {
+	first, _, _ := strings.Cut(line, "\n")
+	start := securityHeaderStart(first, "LEEF:")
+	if start < 0 {
+		return nil, false
+	}
+	body := first[start+len("LEEF:"):]
+	version, _, _ := strings.Cut(body, "|")
+	headers := 5
+	if strings.HasPrefix(version, "2") {
+		headers = 6
+	}
+	parts := strings.SplitN(body, "|", headers+1)
+	if len(parts) < headers {
+		return nil, false
+	}
+	ext := ""
+	if len(parts) > headers {
+		ext = parts[headers]
+	}
+
+	delimiter := "\t"
+	if headers == 6 {
+		if d, ok := leefDelimiter(parts[5]); ok {
+			delimiter = d
+		}
+	}
+
+	event := newBaseEvent(line)
+	applySyslogPrefix(&event, first[:start], p.now)
+	setAttr(&event, "leef_version", parts[0])
+	setAttr(&event, "device_vendor", parts[1])
+	setAttr(&event, "device_product", parts[2])
+	setAttr(&event, "device_version", parts[3])
+	setAttr(&event, "event_id", parts[4])
+
+	var fields []assignment
+	if strings.Contains(ext, delimiter) || !strings.Contains(ext, " ") {
+		fields = splitDelimitedExtension(ext, delimiter)
+	} else {
+		fields = scanSpacedExtension(ext)
+	}
+
+	devTime, devTimeFormat := "", ""
+	var rest []assignment
+	for _, f := range fields {
+		switch f.key {
+		case "devTime":
+			devTime = f.value
+		case "devTimeFormat":
+			devTimeFormat = f.value
+		default:
+			rest = append(rest, f)
+		}
+	}
+	if devTime != "" {
+		if ts, ok := parseLEEFTime(devTime, devTimeFormat, p.now); ok {
+			event.Timestamp = &ts
+		} else {
+			setAttr(&event, "devTime", devTime)
+		}
+	}
+	setExtension(&event, rest, nil, p.now)
+	if sev, ok := event.Attrs["sev"]; ok {
+		if level, ok := securitySeverityLevel(sev); ok {
+			event.Attrs["level"] = level
+		}
+	}
+	return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event}, true
+}
+
+// securityHeaderStart returns where marker starts line: at its beginning
+// or after a syslog header ending in a space. It returns -1 otherwise.
+func securityHeaderStart(line, marker string) int {
+	if strings.HasPrefix(line, marker) {
+		return 0
+	}
+	i := strings.Index(line, " "+marker)
+	if i < 0 {
+		return -1
+	}
+	return i + 1
+}
+
+// applySyslogPrefix takes the timestamp and host from the syslog header a
+// relay put in front of a record, e.g. "<134>Mar 16 08:12:04 fw01 " or
+// "2024-03-16T08:12:04Z fw01 ". Headers it does not recognize are ignored.
+func applySyslogPrefix(e *Event, prefix string, now func() time.Time) {
+	fields := strings.Fields(prefix)
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "<") {
+		pri, rest, _ := strings.Cut(fields[0], ">")
+		if _, err := strconv.Atoi(pri[1:]); err != nil {
+			return
+		}
+		fields[0] = rest
+		if fields[0] == "" || fields[0] == "1" {
+			// RFC 5424 version.
+			fields = fields[1:]
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	if ts, ok := parseTimestamp(fields[0]); ok {
+		e.Timestamp = &ts
+		fields = fields[1:]
+	} else if len(fields) >= 3 {
+		ts, ok := timestamp.ParseLayout("Jan _2 15:04:05", strings.Join(fields[:3], " "), nowOrDefault(now))
+		if !ok {
+			return
+		}
+		e.Timestamp = &ts
+		fields = fields[3:]
+	} else {
+		return
+	}
+	if len(fields) > 0 {
+		setAttr(e, "host", fields[0])
+	}
+}
+
+func nowOrDefault(now func() time.Time) time.Time {
+	if now != nil {
+		return now()
+	}
+	return time.Now()
+}
+
+// splitCEFHeader splits s on the first n unescaped pipes, unescaping "\|"
+// and "\\" in the header fields. The extension is returned as is.
+func splitCEFHeader(s string, n int) []string {
+	var (
+		parts []string
+		field strings.Builder
+	)
+	i := 0
+	for ; i < len(s) && len(parts) < n; i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			field.WriteByte(s[i+1])
+			i++
+		case c == '|':
+			parts = append(parts, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	if len(parts) < n {
+		return parts
+	}
+	return append(parts, s[i:])
+}
+
+// scanSpacedExtension splits a CEF extension into key=value pairs. Values
+// may contain spaces: a value ends where the next " key=" begins. "\=",
+// "\\", "\n" and "\r" are unescaped.
+func scanSpacedExtension(ext string) []assignment {
+	type key struct{ start, eq int }
+	var keys []key
+	for i := 0; i < len(ext); i++ {
+		if ext[i] != '=' || (i > 0 && ext[i-1] == '\\') {
+			continue
+		}
+		k := i
+		for k > 0 && isExtensionKeyChar(ext[k-1]) {
+			k--
+		}
+		if k < i && (k == 0 || ext[k-1] == ' ') {
+			keys = append(keys, key{k, i})
+		}
+	}
+
+	fields := make([]assignment, 0, len(keys))
+	for j, k := range keys {
+		end := len(ext)
+		if j+1 < len(keys) {
+			end = keys[j+1].start
+		}
+		fields = append(fields, assignment{
+			key:   ext[k.start:k.eq],
+			value: unescapeCEFValue(strings.TrimSpace(ext[k.eq+1 : end])),
+		})
+	}
+	return fields
+}
+
+func isExtensionKeyChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '[' || c == ']' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+var cefValueReplacer = strings.NewReplacer(`\=`, `=`, `\\`, `\`, `\n`, "\n", `\r`, "\r")
+
+func unescapeCEFValue(v string) string {
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+	return cefValueReplacer.Replace(v)
+}
+
+// splitDelimitedExtension splits a LEEF extension of key=value pairs
+// separated by delimiter.
+func splitDelimitedExtension(ext, delimiter string) []assignment {
+	var fields []assignment
+	for _, pair := range strings.Split(ext, delimiter) {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		fields = append(fields, assignment{key: strings.TrimSpace(key), value: value})
+	}
+	return fields
+}
+
+// leefDelimiter decodes the delimiter header of LEEF 2.0: a single
+// character, or its code in hex as x09 or 0x09.
+func leefDelimiter(raw string) (string, bool) {
+	switch len(raw) {
+	case 0:
+		return "", false
+	case 1:
+		return raw, true
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(raw), "0"), "x")
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return "", false
+	}
+	return string(rune(code)), true
+}
+
+// setExtension sets the extension fields as attrs. The first of
+// timestampKeys that parses becomes the event time; custom fields are
+// renamed after their labels.
+func setExtension(e *Event, fields []assignment, timestampKeys []string, now func() time.Time) {
+	labels := make(map[string]string)
+	for _, f := range fields {
+		if m := cefLabelPattern.FindStringSubmatch(f.key); m != nil && f.value != "" {
+			labels[m[1]] = f.value
+		}
+	}
+
+	values := make(map[string]string, len(fields))
+	for _, f := range fields {
+		if cefLabelPattern.MatchString(f.key) {
+			continue
+		}
+		key := f.key
+		if label, ok := labels[key]; ok {
+			key = label
+		}
+		values[f.key] = f.value
+		setAttr(e, key, f.value)
+	}
+
+	for _, key := range timestampKeys {
+		if ts, ok := parseCEFTime(values[key], now); ok {
+			e.Timestamp = &ts
+			return
+		}
+	}
+}
+
+// parseCEFTime parses milliseconds since the epoch or one of the date
+// formats the CEF specification allows, such as "Mar 16 2024 08:12:04.123
+// UTC", which timestamp.Parse knows.
+func parseCEFTime(value string, now func() time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), true
+	}
+	return timestamp.ParseAt(value, nowOrDefault(now))
+}
+
+// parseLEEFTime parses devTime with devTimeFormat, a Java SimpleDateFormat
+// pattern, falling back to the CEF formats.
+func parseLEEFTime(value, format string, now func() time.Time) (time.Time, bool) {
+	if layout, ok := javaTimeLayout(format); ok {
+		if ts, ok := timestamp.ParseLayout(layout, value, nowOrDefault(now)); ok {
+			return ts, true
+		}
+	}
+	return parseCEFTime(value, now)
+}
+
+// javaTimeLayouts maps SimpleDateFormat letter runs to Go layout elements.
+var javaTimeLayouts = map[string]string{
+	"yyyy": "2006", "yy": "06",
+	"MMMM": "January", "MMM": "Jan", "MM": "01", "M": "1",
+	"dd": "02", "d": "2",
+	"EEEE": "Monday", "EEE": "Mon",
+	"HH": "15", "H": "15", "hh": "03", "h": "3",
+	"mm": "04", "m": "4", "ss": "05", "s": "5",
+	"SSS": "000", "SS": "00", "S": "0",
+	"a": "PM", "z": "MST", "zzz": "MST", "Z": "-0700", "X": "Z07", "XX": "Z0700", "XXX": "Z07:00",
+}
+
+// javaTimeLayout converts a SimpleDateFormat pattern such as
+// "MMM dd yyyy HH:mm:ss.SSS zzz" to a Go layout. It reports false for
+// patterns using letters it does not know.
+func javaTimeLayout(format string) (string, bool) {
+	if format == "" {
+		return "", false
+	}
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		c := format[i]
+		switch {
+		case c == '\'':
+			end := strings.IndexByte(format[i+1:], '\'')
+			if end < 0 {
+				return "", false
+			}
+			b.WriteString(format[i+1 : i+1+end])
+			i += end + 2
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			j := i
+			for j < len(format) && format[j] == c {
+				j++
+			}
+			elem, ok := javaTimeLayouts[format[i:j]]
+			if !ok {
+				return "", false
+			}
+			b.WriteString(elem)
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), true
+}
+
+// securitySeverityLevel maps a CEF or LEEF severity, 0-10 or a CEF name
+// such as "High", to a canonical level.
+func securitySeverityLevel(raw string) (string, bool) {
+	if n, err := strconv.Atoi(raw); err == nil {
+		switch {
+		case n < 0 || n > 10:
+			return "", false
+		case n <= 3:
+			return "info", true
+		case n <= 6:
+			return "warn", true
+		case n <= 8:
+			return "error", true
+		default:
+			return "fatal", true
+		}
+	}
+	switch strings.ToLower(raw) {
+	case "low":
+		return "info", true
+	case "medium":
+		return "warn", true
+	case "high":
+		return "error", true
+	case "very-high", "very high":
+		return "fatal", true
+	}
+	return "", false
+}
@@ -0,0 +1,102 @@
+package event
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLine_CEF(t *testing.T) {
+	parsed := ParseLine(`CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 rt=1710590400000 msg=Detected a threat. No action needed cs1Label=policy cs1=block all`)
+
+	if parsed.Parser != ParserCEF {
+		t.Fatalf("expected %s, got %q", ParserCEF, parsed.Parser)
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-16T12:00:00Z")
+	assertAttr(t, parsed.Event.Attrs, "device_vendor", "Security")
+	assertAttr(t, parsed.Event.Attrs, "device_product", "threatmanager")
+	assertAttr(t, parsed.Event.Attrs, "signature_id", "100")
+	assertAttr(t, parsed.Event.Attrs, "name", "worm successfully stopped")
+	assertAttr(t, parsed.Event.Attrs, "severity", "10")
+	assertAttr(t, parsed.Event.Attrs, "level", "fatal")
+	assertAttr(t, parsed.Event.Attrs, "src", "10.0.0.1")
+	assertAttr(t, parsed.Event.Attrs, "spt", "1232")
+	assertAttr(t, parsed.Event.Attrs, "msg", "Detected a threat. No action needed")
+	assertAttr(t, parsed.Event.Attrs, "policy", "block all")
+	if _, ok := parsed.Event.Attrs["cs1"]; ok {
+		t.Error("expected cs1 to be renamed after its label")
+	}
+}
+
+func TestCEFEscapesAndSyslogHeader(t *testing.T) {
+	p := cefParser{now: func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) }}
+
+	parsed, ok := p.Parse(`<134>Mar 16 08:12:04 fw01 CEF:0|Acme|Fire\|Wall|2.0|deny|Blocked|High|act=deny request=/a?b\=1 rt=Mar 16 2024 08:12:03`)
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-16T08:12:03Z")
+	assertAttr(t, parsed.Event.Attrs, "host", "fw01")
+	assertAttr(t, parsed.Event.Attrs, "device_product", "Fire|Wall")
+	assertAttr(t, parsed.Event.Attrs, "level", "error")
+	assertAttr(t, parsed.Event.Attrs, "request", "/a?b=1")
+	assertAttr(t, parsed.Event.Attrs, "msg", "Blocked")
+
+	if _, ok := p.Parse("CEF:0|too|few|fields"); ok {
+		t.Error("expected a truncated header to be rejected")
+	}
+}
+
+func TestParseLine_LEEF(t *testing.T) {
+	parsed := ParseLine("LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdst=172.50.123.1\tsev=5\tcat=anomaly\tdevTime=Mar 16 2024 08:12:04.000 UTC\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS zzz")
+
+	if parsed.Parser != ParserLEEF {
+		t.Fatalf("expected %s, got %q", ParserLEEF, parsed.Parser)
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-16T08:12:04Z")
+	assertAttr(t, parsed.Event.Attrs, "device_vendor", "Microsoft")
+	assertAttr(t, parsed.Event.Attrs, "device_version", "4.0 SP1")
+	assertAttr(t, parsed.Event.Attrs, "event_id", "15345")
+	assertAttr(t, parsed.Event.Attrs, "dst", "172.50.123.1")
+	assertAttr(t, parsed.Event.Attrs, "cat", "anomaly")
+	assertAttr(t, parsed.Event.Attrs, "level", "warn")
+}
+
+func TestLEEFDelimiters(t *testing.T) {
+	p := leefParser{}
+
+	parsed, ok := p.Parse("LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^msg=port scan")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertAttr(t, parsed.Event.Attrs, "src", "10.0.1.8")
+	assertAttr(t, parsed.Event.Attrs, "msg", "port scan")
+
+	parsed, ok = p.Parse("LEEF:2.0|Vendor|Product|1.0|7|x7c|usrName=bob|devTime=1710590400000")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertAttr(t, parsed.Event.Attrs, "usrName", "bob")
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-16T12:00:00Z")
+
+	parsed, ok = p.Parse("LEEF:1.0|Vendor|Product|1.0|login|usrName=alice identSrc=10.1.1.1")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertAttr(t, parsed.Event.Attrs, "usrName", "alice")
+	assertAttr(t, parsed.Event.Attrs, "identSrc", "10.1.1.1")
+}
+
+func TestJavaTimeLayout(t *testing.T) {
+	for format, want := range map[string]string{
+		"MMM dd yyyy HH:mm:ss.SSS zzz": "Jan 02 2006 15:04:05.000 MST",
+		"yyyy-MM-dd'T'HH:mm:ssXXX":     "2006-01-02T15:04:05Z07:00",
+	} {
+		got, ok := javaTimeLayout(format)
+		if !ok || got != want {
+			t.Errorf("javaTimeLayout(%q): got %q, %v, want %q", format, got, ok, want)
+		}
+	}
+	if _, ok := javaTimeLayout("yyyy QQ"); ok {
+		t.Error("expected unknown letters to be rejected")
+	}
+}
@@ -0,0 +1,236 @@
+package event
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Well-known attrs holding trace and request correlation identifiers.
+const (
+	// AttrTraceID is the trace ID, lowercase hex in the W3C form where the
+	// source's form converts: X-Ray's 1-5759e988-bd862e3fe1be46a994272793
+	// becomes 5759e988bd862e3fe1be46a994272793.
+	AttrTraceID = "trace_id"
+	// AttrSpanID is the span ID, lowercase hex.
+	AttrSpanID = "span_id"
+	// AttrRequestID is a request ID such as X-Request-ID.
+	AttrRequestID = "request_id"
+	// AttrXRayTraceID keeps an AWS X-Ray trace ID as written, for lookups
+	// in the X-Ray console.
+	AttrXRayTraceID = "xray_trace_id"
+)
+
+var (
+	traceparentPattern = regexp.MustCompile(`\b00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}\b`)
+	xrayHeaderPattern  = regexp.MustCompile(`\bRoot=(1-[0-9a-f]{8}-[0-9a-f]{24})(?:;\s*Parent=([0-9a-f]{16}))?`)
+	xrayTraceIDPattern = regexp.MustCompile(`^1-([0-9a-f]{8})-([0-9a-f]{24})$`)
+	uuidPattern        = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	// requestIDTextPattern finds "request_id: abc-123" and the like in
+	// text no parser split into attrs.
+	requestIDTextPattern = regexp.MustCompile(`(?i)\b(x-request-id|request[-_ ]?id|req[-_]?id)["']?\s*[:=]\s*["']?([0-9a-z][0-9a-z._:-]*[0-9a-z])`)
+)
+
+// correlationKind says what an attr key holds.
+type correlationKind int
+
+const (
+	correlationNone correlationKind = iota
+	correlationTraceparent
+	correlationXRayHeader
+	correlationTraceID
+	correlationSpanID
+	correlationRequestID
+	// correlationShortRequestID keys such as req_id are request IDs only
+	// when the value is a UUID.
+	correlationShortRequestID
+)
+
+// correlationKeys maps attr keys, lowercased and with "-", "_", "." and
+// "/" removed, to what they hold.
+var correlationKeys = map[string]correlationKind{
+	"traceparent": correlationTraceparent,
+
+	"xamzntraceid":   correlationXRayHeader,
+	"awsxraytraceid": correlationXRayHeader,
+	"xraytraceid":    correlationXRayHeader,
+
+	"traceid":                   correlationTraceID,
+	"ddtraceid":                 correlationTraceID,
+	"oteltraceid":               correlationTraceID,
+	"xb3traceid":                correlationTraceID,
+	"loggingtraceid":            correlationTraceID,
+	"logginggoogleapiscomtrace": correlationTraceID,
+
+	"spanid":                     correlationSpanID,
+	"ddspanid":                   correlationSpanID,
+	"otelspanid":                 correlationSpanID,
+	"xb3spanid":                  correlationSpanID,
+	"loggingspanid":              correlationSpanID,
+	"logginggoogleapiscomspanid": correlationSpanID,
+
+	"requestid":      correlationRequestID,
+	"xrequestid":     correlationRequestID,
+	"httprequestid":  correlationRequestID,
+	"xamznrequestid": correlationRequestID,
+	"awsrequestid":   correlationRequestID,
+
+	"reqid": correlationShortRequestID,
+	"rid":   correlationShortRequestID,
+}
+
+func correlationKeyKind(key string) correlationKind {
+	key = strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', '.', '/':
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+	return correlationKeys[key]
+}
+
+// extractCorrelation normalizes the trace and request identifiers in the
+// attrs of e into AttrTraceID, AttrSpanID and AttrRequestID, e.g. from a
+// W3C traceparent, an X-Amzn-Trace-Id header, traceId or x-request-id.
+// Aliases such as traceId are renamed; headers holding more than an ID are
+// kept. Lines without such attrs are searched for a traceparent, an X-Ray
+// header or a "request_id: ..." in their text.
+func extractCorrelation(e *Event) {
+	keys := make([]string, 0, len(e.Attrs))
+	for key := range e.Attrs {
+		keys = append(keys, key)
+	}
+	// Sorted, so which alias wins is always the same.
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		value := strings.TrimSpace(e.Attrs[key])
+		switch correlationKeyKind(key) {
+		case correlationTraceparent:
+			if m := traceparentPattern.FindStringSubmatch(strings.ToLower(value)); m != nil {
+				setCorrelation(e, "", AttrTraceID, m[1])
+				setCorrelation(e, "", AttrSpanID, m[2])
+			}
+		case correlationXRayHeader:
+			extractXRayHeader(e, value)
+		case correlationTraceID:
+			if xrayHeaderPattern.MatchString(value) {
+				// E.g. the trace_id field of ALB access logs.
+				if key == AttrTraceID {
+					delete(e.Attrs, key)
+				}
+				extractXRayHeader(e, value)
+				continue
+			}
+			if id, xray, ok := normalizeTraceID(value); ok {
+				setCorrelation(e, key, AttrTraceID, id)
+				if xray != "" {
+					setCorrelation(e, "", AttrXRayTraceID, xray)
+				}
+			}
+		case correlationSpanID:
+			if id := strings.ToLower(value); isHexID(id) {
+				setCorrelation(e, key, AttrSpanID, id)
+			}
+		case correlationRequestID:
+			setCorrelation(e, key, AttrRequestID, value)
+		case correlationShortRequestID:
+			if id := strings.ToLower(value); uuidPattern.MatchString(id) {
+				setCorrelation(e, key, AttrRequestID, value)
+			}
+		}
+	}
+
+	if _, ok := e.Attrs[AttrTraceID]; !ok {
+		text := strings.ToLower(e.Text)
+		if m := traceparentPattern.FindStringSubmatch(text); m != nil {
+			setCorrelation(e, "", AttrTraceID, m[1])
+			setCorrelation(e, "", AttrSpanID, m[2])
+		} else if strings.Contains(e.Text, "Root=1-") {
+			extractXRayHeader(e, e.Text)
+		}
+	}
+	if _, ok := e.Attrs[AttrRequestID]; !ok {
+		if m := requestIDTextPattern.FindStringSubmatch(e.Text); m != nil {
+			short := correlationKeyKind(m[1]) == correlationShortRequestID
+			if id := strings.ToLower(m[2]); uuidPattern.MatchString(id) || (!short && isTextID(id)) {
+				setCorrelation(e, "", AttrRequestID, m[2])
+			}
+		}
+	}
+}
+
+// isTextID reports whether a lowercased value found after "request id:" in
+// free text looks like an ID rather than a word, as in "failed to parse
+// request id: invalid header": a hex ID, or one with a digit or separator.
+func isTextID(id string) bool {
+	return isHexID(id) || strings.ContainsAny(id, "0123456789-_.:")
+}
+
+// extractXRayHeader reads "Root=1-...;Parent=...;Sampled=1".
+func extractXRayHeader(e *Event, value string) {
+	m := xrayHeaderPattern.FindStringSubmatch(value)
+	if m == nil {
+		return
+	}
+	id, _, _ := normalizeTraceID(m[1])
+	setCorrelation(e, "", AttrTraceID, id)
+	setCorrelation(e, "", AttrXRayTraceID, m[1])
+	if m[2] != "" {
+		setCorrelation(e, "", AttrSpanID, m[2])
+	}
+}
+
+// normalizeTraceID returns a trace ID in lowercase hex, converting X-Ray
+// IDs, whose form it also returns. Decimal IDs, as Datadog logs them, are
+// kept. The all-zero ID is invalid.
+func normalizeTraceID(value string) (id, xray string, ok bool) {
+	id = strings.ToLower(strings.TrimSpace(value))
+	if m := xrayTraceIDPattern.FindStringSubmatch(id); m != nil {
+		return m[1] + m[2], id, true
+	}
+	// Cloud Logging: projects/<project>/traces/<id>.
+	if _, after, found := strings.Cut(id, "/traces/"); found {
+		id = after
+	}
+	if !isHexID(id) {
+		return "", "", false
+	}
+	return id, "", true
+}
+
+// isHexID reports whether id is a non-zero hex or decimal ID of 16 to 32
+// digits, or shorter if decimal.
+func isHexID(id string) bool {
+	if len(id) == 0 || len(id) > 32 || strings.Trim(id, "0") == "" {
+		return false
+	}
+	decimal := true
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case c >= '0' && c <= '9':
+		case c >= 'a' && c <= 'f':
+			decimal = false
+		default:
+			return false
+		}
+	}
+	return decimal || len(id) >= 16
+}
+
+// setCorrelation sets the well-known attr to value, renaming the alias it
+// came from, if any. An existing different value is not overwritten, and
+// then the alias is kept.
+func setCorrelation(e *Event, alias, attr, value string) {
+	if value == "" {
+		return
+	}
+	if existing, ok := e.Attrs[attr]; ok && existing != value && alias != attr {
+		return
+	}
+	e.Attrs[attr] = value
+	if alias != "" && alias != attr {
+		delete(e.Attrs, alias)
+	}
+}
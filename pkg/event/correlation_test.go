@@ -0,0 +1,97 @@
+package event
+
+import "testing"
+
+func TestExtractCorrelation(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		attrs  map[string]string
+		absent []string
+	}{
+		{
+			name:  "traceparent",
+			line:  `{"msg":"charge","traceparent":"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"}`,
+			attrs: map[string]string{AttrTraceID: "4bf92f3577b34da6a3ce929d0e0e4736", AttrSpanID: "00f067aa0ba902b7"},
+		},
+		{
+			name:   "otel aliases",
+			line:   `{"msg":"charge","traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"00F067AA0BA902B7"}`,
+			attrs:  map[string]string{AttrTraceID: "4bf92f3577b34da6a3ce929d0e0e4736", AttrSpanID: "00f067aa0ba902b7"},
+			absent: []string{"traceId", "spanId"},
+		},
+		{
+			name:   "datadog",
+			line:   `level=info dd.trace_id=1234567890123456789 dd.span_id=987654321 msg=ok`,
+			attrs:  map[string]string{AttrTraceID: "1234567890123456789", AttrSpanID: "987654321"},
+			absent: []string{"dd.trace_id"},
+		},
+		{
+			name:  "x-ray header",
+			line:  `{"msg":"ok","X-Amzn-Trace-Id":"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"}`,
+			attrs: map[string]string{AttrTraceID: "5759e988bd862e3fe1be46a994272793", AttrXRayTraceID: "1-5759e988-bd862e3fe1be46a994272793", AttrSpanID: "53995c3f42cd8ad8", "X-Amzn-Trace-Id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"},
+		},
+		{
+			name:  "x-ray trace id",
+			line:  `trace_id=1-5759e988-bd862e3fe1be46a994272793 msg=ok`,
+			attrs: map[string]string{AttrTraceID: "5759e988bd862e3fe1be46a994272793", AttrXRayTraceID: "1-5759e988-bd862e3fe1be46a994272793"},
+		},
+		{
+			name:   "x-request-id",
+			line:   `{"msg":"ok","x-request-id":"req-42"}`,
+			attrs:  map[string]string{AttrRequestID: "req-42"},
+			absent: []string{"x-request-id"},
+		},
+		{
+			name:   "uuid req_id",
+			line:   `level=info req_id=0F8FAD5B-D9CB-469F-A165-70867728950E msg=ok`,
+			attrs:  map[string]string{AttrRequestID: "0F8FAD5B-D9CB-469F-A165-70867728950E"},
+			absent: []string{"req_id"},
+		},
+		{
+			name:   "non-uuid req_id",
+			line:   `level=info req_id=7 msg=ok`,
+			attrs:  map[string]string{"req_id": "7"},
+			absent: []string{AttrRequestID},
+		},
+		{
+			name:   "invalid trace id",
+			line:   `{"msg":"ok","traceId":"00000000000000000000000000000000"}`,
+			attrs:  map[string]string{"traceId": "00000000000000000000000000000000"},
+			absent: []string{AttrTraceID},
+		},
+		{
+			name:  "conflicting alias",
+			line:  `{"msg":"ok","request_id":"a1","requestId":"b2"}`,
+			attrs: map[string]string{AttrRequestID: "a1", "requestId": "b2"},
+		},
+		{
+			name:  "plain text",
+			line:  `worker stalled, request_id: 7c9e6679-7425-40de-944b-e07fc1f90ae7 traceparent=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01`,
+			attrs: map[string]string{AttrRequestID: "7c9e6679-7425-40de-944b-e07fc1f90ae7", AttrTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		},
+		{
+			name:   "plain text word",
+			line:   `ERROR failed to parse request id: invalid header`,
+			absent: []string{AttrRequestID},
+		},
+		{
+			name:  "plain text short id",
+			line:  `served request id=req-42 in 3ms`,
+			attrs: map[string]string{AttrRequestID: "req-42"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := ParseLine(tt.line)
+			for key, value := range tt.attrs {
+				assertAttr(t, parsed.Event.Attrs, key, value)
+			}
+			for _, key := range tt.absent {
+				if _, ok := parsed.Event.Attrs[key]; ok {
+					t.Errorf("expected no %s attr, got %v", key, parsed.Event.Attrs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package event
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+
+	goerrors "github.com/go-errors/errors"
+)
+
+// ColumnRole says what a CSV column holds.
+type ColumnRole string
+
+const (
+	// RoleTimestamp columns hold the event time. Several timestamp
+	// columns, such as separate date and time, are joined with a space.
+	RoleTimestamp ColumnRole = "timestamp"
+	// RoleLevel holds the log level.
+	RoleLevel ColumnRole = "level"
+	// RoleMessage holds the message patterns are mined from.
+	RoleMessage ColumnRole = "message"
+	// RoleAttr columns become attrs named after the column.
+	RoleAttr ColumnRole = "attr"
+	// RoleIgnore columns are dropped.
+	RoleIgnore ColumnRole = "ignore"
+)
+
+// defaultColumnRoles infers roles from common header names; other columns
+// are attrs.
+var defaultColumnRoles = map[string]ColumnRole{
+	"ts": RoleTimestamp, "time": RoleTimestamp, "timestamp": RoleTimestamp, "@timestamp": RoleTimestamp,
+	"date": RoleTimestamp, "datetime": RoleTimestamp,
+	"level": RoleLevel, "severity": RoleLevel, "lvl": RoleLevel, "loglevel": RoleLevel, "log_level": RoleLevel,
+	"message": RoleMessage, "msg": RoleMessage, "content": RoleMessage, "text": RoleMessage, "log": RoleMessage,
+}
+
+// CSVConfig configures a CSVParser.
+type CSVConfig struct {
+	// Comma is the field delimiter. 0 picks a tab if the header line
+	// contains one and a comma otherwise.
+	Comma rune
+	// Header names the columns of sources without a header row. If empty,
+	// the first record of each source is its header.
+	Header []string
+	// Roles assigns roles to columns by header name, matched
+	// case-insensitively. Columns not listed get a role by their name:
+	// ts, time, timestamp, date → timestamp; level, severity → level;
+	// message, msg, content, text, log → message; anything else → attr.
+	Roles map[string]ColumnRole
+}
+
+// CSVParser parses CSV and TSV logs with a header row, e.g. the Loghub
+// structured logs:
+//
+//	LineId,Date,Time,Level,Component,Content
+//	1,2015-10-18,18:01:47.978,INFO,mapreduce.v2.app.MRAppMaster,Created MRAppMaster
+//
+// Records are mapped to the event by column role, and only the message
+// column is reported as ParsedLine.Message. Quoted fields may span lines
+// when the source is merged with multiline.DetectorConfig.QuotedRecords.
+// The header is read once per source and kept as its state (see
+// StatefulParser). CSVParser is not in the default chain, since it would
+// take any line as a header.
+type CSVParser struct {
+	cfg CSVConfig
+
+	mu     sync.Mutex
+	comma  rune
+	header []string
+	roles  []ColumnRole
+}
+
+var _ StatefulParser = (*CSVParser)(nil)
+
+// NewCSVParser returns a CSV parser for cfg.
+func NewCSVParser(cfg CSVConfig) (*CSVParser, error) {
+	for column, role := range cfg.Roles {
+		switch role {
+		case RoleTimestamp, RoleLevel, RoleMessage, RoleAttr, RoleIgnore:
+		default:
+			return nil, goerrors.Errorf("column %s: unknown role %q", column, role)
+		}
+	}
+	p := &CSVParser{cfg: cfg}
+	if len(cfg.Header) > 0 {
+		p.setHeader(cfg.Header, cfg.Comma)
+	}
+	return p, nil
+}
+
+// NewSource implements SourceParser.
+func (p *CSVParser) NewSource() Parser {
+	q := &CSVParser{cfg: p.cfg}
+	if len(p.cfg.Header) > 0 {
+		q.setHeader(p.cfg.Header, p.cfg.Comma)
+	}
+	return q
+}
+
+// csvState is the state of a CSVParser: the header row it read.
+type csvState struct {
+	Comma  rune     `json:"comma"`
+	Header []string `json:"header"`
+}
+
+// State implements StatefulParser. Headers given by CSVConfig are not
+// state.
+func (p *CSVParser) State() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.cfg.Header) > 0 || p.header == nil {
+		return ""
+	}
+	b, err := json.Marshal(csvState{Comma: p.comma, Header: p.header})
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// WithState implements StatefulParser.
+func (p *CSVParser) WithState(state string) (Parser, error) {
+	q := p.NewSource().(*CSVParser)
+	if len(p.cfg.Header) > 0 {
+		return q, nil
+	}
+	var st csvState
+	if err := json.Unmarshal([]byte(state), &st); err != nil {
+		return nil, goerrors.Errorf("csv state: %w", err)
+	}
+	if len(st.Header) > 0 {
+		q.setHeader(st.Header, st.Comma)
+	}
+	return q, nil
+}
+
+// Parse implements Parser. The header row is recognized with an event of
+// its own; records whose field count differs from the header's are not.
+// Like W3C directives, the header is kept as an entry rather than dropped,
+// so that every line of the file is stored and line references and
+// checkpoints stay in step with it.
+func (p *CSVParser) Parse(line string) (*ParsedLine, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.header == nil {
+		comma := p.cfg.Comma
+		if comma == 0 {
+			comma = ','
+			if first, _, _ := strings.Cut(line, "\n"); strings.Contains(first, "\t") {
+				comma = '\t'
+			}
+		}
+		record, ok := readCSVRecord(line, comma)
+		if !ok {
+			return nil, false
+		}
+		p.setHeader(record, comma)
+		return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: newBaseEvent(line)}, true
+	}
+
+	record, ok := readCSVRecord(line, p.comma)
+	if !ok || len(record) != len(p.header) {
+		return nil, false
+	}
+	if len(p.cfg.Header) == 0 && slices.Equal(record, p.header) {
+		// A repeated header, e.g. of concatenated exports.
+		return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: newBaseEvent(line)}, true
+	}
+
+	event := newBaseEvent(line)
+	var (
+		message  string
+		tsParts  []string
+		tsFields []int
+	)
+	for i, value := range record {
+		switch p.roles[i] {
+		case RoleTimestamp:
+			if value != "" {
+				tsParts = append(tsParts, value)
+				tsFields = append(tsFields, i)
+			}
+		case RoleLevel:
+			if level, ok := canonicalizeLevel(value); ok {
+				event.Attrs["level"] = level
+			} else {
+				setAttr(&event, p.header[i], value)
+			}
+		case RoleMessage:
+			if message == "" {
+				message = value
+			} else {
+				setAttr(&event, p.header[i], value)
+			}
+		case RoleAttr:
+			setAttr(&event, p.header[i], value)
+		}
+	}
+	if len(tsParts) > 0 {
+		if ts, ok := parseTimestamp(strings.Join(tsParts, " ")); ok {
+			event.Timestamp = &ts
+		} else {
+			// Keep what could not be parsed.
+			for _, i := range tsFields {
+				setAttr(&event, p.header[i], record[i])
+			}
+		}
+	}
+	return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event, Message: message}, true
+}
+
+func (p *CSVParser) setHeader(header []string, comma rune) {
+	if comma == 0 {
+		comma = ','
+	}
+	p.comma = comma
+	p.header = header
+	p.roles = make([]ColumnRole, len(header))
+	for i, name := range header {
+		p.roles[i] = p.roleOf(name)
+	}
+}
+
+func (p *CSVParser) roleOf(column string) ColumnRole {
+	for name, role := range p.cfg.Roles {
+		if strings.EqualFold(name, column) {
+			return role
+		}
+	}
+	if role, ok := defaultColumnRoles[strings.ToLower(strings.TrimSpace(column))]; ok {
+		return role
+	}
+	return RoleAttr
+}
+
+// readCSVRecord reads the single record of an entry, which may span lines
+// inside quoted fields.
+func readCSVRecord(entry string, comma rune) ([]string, bool) {
+	r := csv.NewReader(strings.NewReader(entry))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	record, err := r.Read()
+	if err != nil {
+		return nil, false
+	}
+	return record, true
+}
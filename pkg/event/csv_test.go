@@ -0,0 +1,73 @@
+package event
+
+import "testing"
+
+func TestCSVParserHeaderRoles(t *testing.T) {
+	p, err := NewCSVParser(CSVConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := p.NewSource()
+
+	if _, ok := src.Parse("LineId,Date,Time,Level,Component,Content"); !ok {
+		t.Fatal("expected the header to be recognized")
+	}
+	parsed, ok := src.Parse("1,2015-10-18,18:01:47,INFO,mapreduce.v2.app.MRAppMaster,\"Created MRAppMaster, attempt 1\"")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2015-10-18T18:01:47Z")
+	assertAttr(t, parsed.Event.Attrs, "level", "info")
+	assertAttr(t, parsed.Event.Attrs, "LineId", "1")
+	assertAttr(t, parsed.Event.Attrs, "Component", "mapreduce.v2.app.MRAppMaster")
+	if parsed.Message != "Created MRAppMaster, attempt 1" {
+		t.Errorf("unexpected message %q", parsed.Message)
+	}
+	if _, ok := parsed.Event.Attrs["Content"]; ok {
+		t.Error("expected the message column to stay out of attrs")
+	}
+
+	if _, ok := src.Parse("1,2,3"); ok {
+		t.Error("expected a record with the wrong field count to be rejected")
+	}
+
+	// Another source reads its own header.
+	other := p.NewSource()
+	if _, ok := other.Parse("ts\tmsg"); !ok {
+		t.Fatal("expected the TSV header to be recognized")
+	}
+	parsed, ok = other.Parse("2024-03-16 08:12:04,123\tdone")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-16T08:12:04Z")
+	if parsed.Message != "done" {
+		t.Errorf("unexpected message %q", parsed.Message)
+	}
+}
+
+func TestCSVParserConfiguredRoles(t *testing.T) {
+	p, err := NewCSVParser(CSVConfig{
+		Header: []string{"when", "body", "EventTemplate"},
+		Roles:  map[string]ColumnRole{"when": RoleTimestamp, "Body": RoleMessage, "eventtemplate": RoleIgnore},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, ok := p.NewSource().Parse("2024-03-16T08:12:04Z,\"stack:\n  at main\",<*>")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-16T08:12:04Z")
+	if parsed.Message != "stack:\n  at main" {
+		t.Errorf("unexpected message %q", parsed.Message)
+	}
+	if len(parsed.Event.Attrs) != 0 {
+		t.Errorf("expected no attrs, got %v", parsed.Event.Attrs)
+	}
+
+	if _, err := NewCSVParser(CSVConfig{Roles: map[string]ColumnRole{"x": "bogus"}}); err == nil {
+		t.Error("expected an unknown role to be rejected")
+	}
+}
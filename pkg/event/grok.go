@@ -0,0 +1,300 @@
+package event
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	goerrors "github.com/go-errors/errors"
+	"github.com/strrl/lapp/pkg/timestamp"
+)
+
+//go:embed grokpatterns/default
+var defaultGrokPatterns string
+
+// DefaultGrokExpressions are the expressions of the default Grok parser:
+// syslog lines and Apache/Nginx access logs.
+var DefaultGrokExpressions = []string{
+	"%{SYSLOGLINE}",
+	"%{COMBINEDAPACHELOG}",
+	"%{COMMONAPACHELOG}",
+}
+
+// grokReference matches %{PATTERN}, %{PATTERN:field} and
+// %{PATTERN:field:type}.
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([\w.@\[\]-]+))?(?::(int|float|string))?\}`)
+
+var grokPatternName = regexp.MustCompile(`^\w+$`)
+
+// inlineGroupName matches the (?P<name> and (?<name> groups written inline
+// in an expression or pattern.
+var inlineGroupName = regexp.MustCompile(`\(\?P?<(\w+)>`)
+
+// grokGroupMarker stands in for the prefix of the synthetic capture names of
+// %{PATTERN:field} until Compile picks one no inline group name starts with.
+const grokGroupMarker = "(?P<\x00"
+
+// maxGrokDepth bounds pattern nesting, so a pattern that refers to itself
+// fails to compile instead of recursing forever.
+const maxGrokDepth = 32
+
+// Grok is a library of named Grok patterns that expressions are compiled
+// against. NewGrok starts from the standard library; AddPatterns adds custom
+// definitions, which may override standard ones.
+type Grok struct {
+	patterns map[string]string
+}
+
+// NewGrok returns a library with the standard patterns: numbers, words,
+// quoted strings, IPs, hosts, paths, URIs, dates, log levels, syslog and
+// Apache common/combined access logs.
+func NewGrok() *Grok {
+	g := &Grok{patterns: make(map[string]string)}
+	if err := g.AddPatterns(strings.NewReader(defaultGrokPatterns)); err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// AddPattern defines or redefines a named pattern.
+func (g *Grok) AddPattern(name, pattern string) error {
+	if !grokPatternName.MatchString(name) {
+		return goerrors.Errorf("add grok pattern: invalid name %q", name)
+	}
+	g.patterns[name] = pattern
+	return nil
+}
+
+// AddPatterns reads pattern definitions in the Logstash patterns file
+// format: one "NAME regexp" per line, with blank lines and lines starting
+// with # ignored.
+func (g *Grok) AddPatterns(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, pattern, ok := strings.Cut(line, " ")
+		if !ok {
+			return goerrors.Errorf("line %d: expected NAME PATTERN", lineNum)
+		}
+		if err := g.AddPattern(name, strings.TrimSpace(pattern)); err != nil {
+			return goerrors.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return goerrors.Errorf("read grok patterns: %w", err)
+	}
+	return nil
+}
+
+// AddPatternsFile adds the pattern definitions in the file at path.
+func (g *Grok) AddPatternsFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return goerrors.Errorf("open grok patterns: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if err := g.AddPatterns(f); err != nil {
+		return goerrors.Errorf("grok patterns %s: %w", path, err)
+	}
+	return nil
+}
+
+// GrokExpression is a compiled Grok expression.
+type GrokExpression struct {
+	expr   string
+	re     *regexp.Regexp
+	fields []grokField
+}
+
+// grokField is a named capture of an expression. %{PATTERN:field}
+// captures are numbered rather than named in the regexp, since nested
+// patterns may reuse a name.
+type grokField struct {
+	name    string
+	typ     string
+	group   int
+	pattern string
+}
+
+// Compile expands the pattern references in expr and compiles it. The
+// expression must match the whole line. Inline (?<name>...) groups are
+// captured like %{PATTERN:name}.
+func (g *Grok) Compile(expr string) (*GrokExpression, error) {
+	var fields []grokField
+	expanded, err := g.expand(expr, 0, &fields)
+	if err != nil {
+		return nil, goerrors.Errorf("compile grok %q: %w", expr, err)
+	}
+	inline := inlineGroupName.FindAllStringSubmatch(expanded, -1)
+	prefix := "grok"
+	for slices.ContainsFunc(inline, func(m []string) bool { return strings.HasPrefix(m[1], prefix) }) {
+		prefix += "_"
+	}
+	expanded = strings.ReplaceAll(expanded, grokGroupMarker, "(?P<"+prefix)
+
+	re, err := regexp.Compile(`^(?:` + expanded + `)$`)
+	if err != nil {
+		return nil, goerrors.Errorf("compile grok %q: %w", expr, err)
+	}
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if n, ok := strings.CutPrefix(name, prefix); ok {
+			idx, err := strconv.Atoi(n)
+			if err != nil || idx >= len(fields) {
+				return nil, goerrors.Errorf("compile grok %q: unexpected group %q", expr, name)
+			}
+			fields[idx].group = i
+			continue
+		}
+		fields = append(fields, grokField{name: name, group: i})
+	}
+	return &GrokExpression{expr: expr, re: re, fields: fields}, nil
+}
+
+func (g *Grok) expand(expr string, depth int, fields *[]grokField) (string, error) {
+	if depth > maxGrokDepth {
+		return "", goerrors.New("patterns nested too deeply")
+	}
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(expr, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		m := grokReference.FindStringSubmatch(ref)
+		name, field, typ := m[1], m[2], m[3]
+		pattern, ok := g.patterns[name]
+		if !ok {
+			err = goerrors.Errorf("unknown pattern %q", name)
+			return ""
+		}
+		var inner string
+		inner, err = g.expand(pattern, depth+1, fields)
+		if err != nil {
+			return ""
+		}
+		if field == "" {
+			return "(?:" + inner + ")"
+		}
+		*fields = append(*fields, grokField{name: field, typ: typ, pattern: name})
+		return grokGroupMarker + strconv.Itoa(len(*fields)-1) + ">" + inner + ")"
+	})
+	return expanded, err
+}
+
+// String returns the expression as written.
+func (e *GrokExpression) String() string {
+	return e.expr
+}
+
+// Match returns the named captures of line, or false if the expression
+// does not match it. Captures that did not participate are omitted; when
+// nested patterns capture the same name, the first non-empty value wins.
+func (e *GrokExpression) Match(line string) (map[string]string, bool) {
+	m := e.re.FindStringSubmatchIndex(line)
+	if m == nil {
+		return nil, false
+	}
+	captures := make(map[string]string, len(e.fields))
+	for _, f := range e.fields {
+		start, end := m[2*f.group], m[2*f.group+1]
+		if start < 0 || start == end {
+			continue
+		}
+		if _, ok := captures[f.name]; ok {
+			continue
+		}
+		captures[f.name] = f.convert(line[start:end])
+	}
+	return captures, true
+}
+
+// convert normalizes a captured value by the field's type; quoted strings
+// lose their quotes.
+func (f grokField) convert(value string) string {
+	switch f.typ {
+	case "int":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return strconv.FormatInt(n, 10)
+		}
+	case "float":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return strconv.FormatFloat(n, 'f', -1, 64)
+		}
+	}
+	if (f.pattern == "QS" || f.pattern == "QUOTEDSTRING") && len(value) >= 2 {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// GrokParser is a Parser that tries Grok expressions in order. The
+// captures of the first matching one become attrs, except timestamp and
+// level fields, which are normalized like those of other formats.
+type GrokParser struct {
+	expressions []*GrokExpression
+	// now anchors the year of year-less syslog timestamps.
+	now func() time.Time
+}
+
+var _ Parser = (*GrokParser)(nil)
+
+// NewGrokParser compiles exprs against g.
+func NewGrokParser(g *Grok, exprs ...string) (*GrokParser, error) {
+	p := &GrokParser{now: time.Now}
+	for _, expr := range exprs {
+		e, err := g.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		p.expressions = append(p.expressions, e)
+	}
+	return p, nil
+}
+
+// newDefaultGrokParser returns the parser registered as ParserGrok.
+func newDefaultGrokParser() *GrokParser {
+	p, err := NewGrokParser(NewGrok(), DefaultGrokExpressions...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Parse implements Parser.
+func (p *GrokParser) Parse(line string) (*ParsedLine, bool) {
+	for _, e := range p.expressions {
+		captures, ok := e.Match(line)
+		if !ok {
+			continue
+		}
+		event := newBaseEvent(line)
+		for key, value := range captures {
+			if isTimestampKey(strings.ToLower(key)) {
+				if ts, ok := p.parseTimestamp(value); ok {
+					event.Timestamp = &ts
+				}
+				continue
+			}
+			assignField(&event, key, value)
+		}
+		return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event}, true
+	}
+	return nil, false
+}
+
+func (p *GrokParser) parseTimestamp(value string) (time.Time, bool) {
+	return timestamp.ParseAt(strings.Join(strings.Fields(value), " "), p.now())
+}
@@ -0,0 +1,143 @@
+package event
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrokCombinedApacheLog(t *testing.T) {
+	line := `203.0.113.9 - frank [28/Mar/2024:13:45:30 +0000] "GET /api/users?id=7 HTTP/1.1" 404 512 "https://example.com/" "curl/8.4.0"`
+
+	p, err := NewGrokParser(NewGrok(), "%{COMBINEDAPACHELOG}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, ok := p.Parse(line)
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-28T13:45:30Z")
+	assertAttr(t, parsed.Event.Attrs, "clientip", "203.0.113.9")
+	assertAttr(t, parsed.Event.Attrs, "auth", "frank")
+	assertAttr(t, parsed.Event.Attrs, "verb", "GET")
+	assertAttr(t, parsed.Event.Attrs, "request", "/api/users?id=7")
+	assertAttr(t, parsed.Event.Attrs, "response", "404")
+	assertAttr(t, parsed.Event.Attrs, "bytes", "512")
+	assertAttr(t, parsed.Event.Attrs, "referrer", "https://example.com/")
+	assertAttr(t, parsed.Event.Attrs, "agent", "curl/8.4.0")
+}
+
+func TestParseLine_GrokSyslog(t *testing.T) {
+	line := "Mar  6 08:12:04 web-1 sshd[4211]: Accepted publickey for deploy from 10.0.0.5"
+
+	parsed := ParseLine(line)
+
+	if parsed.Parser != ParserGrok {
+		t.Fatalf("expected grok parser, got %q", parsed.Parser)
+	}
+	if parsed.Event.Timestamp == nil || parsed.Event.Timestamp.Month() != time.March || parsed.Event.Timestamp.Day() != 6 || parsed.Event.Timestamp.Year() == 0 {
+		t.Errorf("expected March 6 with an inferred year, got %v", parsed.Event.Timestamp)
+	}
+	assertAttr(t, parsed.Event.Attrs, "logsource", "web-1")
+	assertAttr(t, parsed.Event.Attrs, "program", "sshd")
+	assertAttr(t, parsed.Event.Attrs, "pid", "4211")
+	assertAttr(t, parsed.Event.Attrs, "message", "Accepted publickey for deploy from 10.0.0.5")
+}
+
+func TestGrokSyslogYearInference(t *testing.T) {
+	p, err := NewGrokParser(NewGrok(), "%{SYSLOGLINE}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.now = func() time.Time { return time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC) }
+
+	parsed, ok := p.Parse("Dec 31 23:59:59 host app: bye")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-12-31T23:59:59Z")
+}
+
+func TestGrokCustomPatternsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "patterns")
+	if err := os.WriteFile(path, []byte("# order service\nORDERID ORD-[0-9]{6}\nORDERLINE %{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} order=%{ORDERID:order_id} took=%{NUMBER:took_ms:float}ms\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewGrok()
+	if err := g.AddPatternsFile(path); err != nil {
+		t.Fatalf("AddPatternsFile: %v", err)
+	}
+	p, err := NewGrokParser(g, "%{ORDERLINE}")
+	if err != nil {
+		t.Fatalf("NewGrokParser: %v", err)
+	}
+
+	parsed, ok := p.Parse("2024-03-28T13:45:30Z WARN order=ORD-000042 took=12.50ms")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-28T13:45:30Z")
+	assertAttr(t, parsed.Event.Attrs, "level", "warn")
+	assertAttr(t, parsed.Event.Attrs, "order_id", "ORD-000042")
+	assertAttr(t, parsed.Event.Attrs, "took_ms", "12.5")
+
+	if _, ok := p.Parse("2024-03-28T13:45:30Z WARN order=42 took=1ms"); ok {
+		t.Error("expected malformed order id not to match")
+	}
+}
+
+func TestGrokCompileErrors(t *testing.T) {
+	g := NewGrok()
+	if _, err := g.Compile("%{NOPE:x}"); err == nil || !strings.Contains(err.Error(), "unknown pattern") {
+		t.Errorf("expected unknown pattern error, got %v", err)
+	}
+	if err := g.AddPattern("LOOP", "a%{LOOP}"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Compile("%{LOOP}"); err == nil {
+		t.Error("expected recursive pattern to fail")
+	}
+	if err := g.AddPatterns(strings.NewReader("JUSTANAME\n")); err == nil {
+		t.Error("expected malformed definition to fail")
+	}
+}
+
+func TestGrokInlineNamedGroups(t *testing.T) {
+	g := NewGrok()
+	tests := []struct {
+		expr string
+		line string
+		want map[string]string
+	}{
+		{`(?<from>\S+)`, "hello", map[string]string{"from": "hello"}},
+		{`%{WORD:x} (?<from>\S+)`, "hello world", map[string]string{"x": "hello", "from": "world"}},
+		{`(?P<grok0>\S+) %{WORD:x}`, "hello world", map[string]string{"grok0": "hello", "x": "world"}},
+	}
+	for _, tt := range tests {
+		e, err := g.Compile(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		got, ok := e.Match(tt.line)
+		if !ok {
+			t.Fatalf("%s: no match for %q", tt.expr, tt.line)
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestGrokStandardPatternsCompile(t *testing.T) {
+	g := NewGrok()
+	for name := range g.patterns {
+		if _, err := g.Compile("%{" + name + "}"); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package event
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	goerrors "github.com/go-errors/errors"
+)
+
+// ArrayMode says how JSONParser turns arrays into attrs.
+type ArrayMode string
+
+const (
+	// ArrayJSON keeps an array as one attr holding its JSON text, with a
+	// list in ParsedLine.Values.
+	ArrayJSON ArrayMode = "json"
+	// ArrayIndex flattens elements into indexed paths: tags.0, tags.1.
+	ArrayIndex ArrayMode = "index"
+	// ArrayJoin joins scalar elements with commas. Arrays holding objects
+	// or arrays are kept as JSON.
+	ArrayJoin ArrayMode = "join"
+	// ArrayDrop leaves arrays out.
+	ArrayDrop ArrayMode = "drop"
+)
+
+// JSONOptions configures JSONParser.
+type JSONOptions struct {
+	// KeepNested keeps nested objects as one attr holding their JSON text
+	// instead of flattening them into paths such as http.request.method.
+	KeepNested bool
+	// Separator joins the keys of a path. Default: ".".
+	Separator string
+	// Arrays says how arrays are handled. Default: ArrayJSON.
+	Arrays ArrayMode
+	// MaxDepth is how many levels of nested objects are flattened; deeper
+	// objects are kept as JSON text. 0 means no limit.
+	MaxDepth int
+}
+
+// JSONParser parses lines holding one JSON object. Nested objects are
+// flattened into dotted paths, so {"http":{"request":{"method":"GET"}}}
+// gives http.request.method=GET, and well-known keys such as ts, level or
+// log.level are normalized. Numbers, booleans and arrays keep their type
+// in ParsedLine.Values.
+type JSONParser struct {
+	opts JSONOptions
+}
+
+var _ Parser = (*JSONParser)(nil)
+
+// NewJSONParser returns a JSON parser for opts.
+func NewJSONParser(opts JSONOptions) (*JSONParser, error) {
+	switch opts.Arrays {
+	case "", ArrayJSON, ArrayIndex, ArrayJoin, ArrayDrop:
+	default:
+		return nil, goerrors.Errorf("unknown array mode %q", opts.Arrays)
+	}
+	if opts.MaxDepth < 0 {
+		return nil, goerrors.Errorf("negative max depth %d", opts.MaxDepth)
+	}
+	return newJSONParser(opts), nil
+}
+
+func newJSONParser(opts JSONOptions) *JSONParser {
+	if opts.Separator == "" {
+		opts.Separator = "."
+	}
+	if opts.Arrays == "" {
+		opts.Arrays = ArrayJSON
+	}
+	return &JSONParser{opts: opts}
+}
+
+// Parse implements Parser.
+func (p *JSONParser) Parse(line string) (*ParsedLine, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") || !strings.HasSuffix(trimmed, "}") {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	dec.UseNumber()
+	var payload map[string]any
+	if err := dec.Decode(&payload); err != nil || dec.More() {
+		return nil, false
+	}
+
+	event := newBaseEvent(line)
+	values := make(map[string]Value)
+	p.flatten(&event, values, "", payload, 0)
+
+	parsed := &ParsedLine{SourceFormat: SourceFormatJSON, Event: event}
+	if len(values) > 0 {
+		parsed.Values = values
+	}
+	return parsed, true
+}
+
+// flatten assigns the fields of object under prefix.
+func (p *JSONParser) flatten(event *Event, values map[string]Value, prefix string, object map[string]any, depth int) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	// Sorted, so the first timestamp key to parse is always the same.
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		path := prefix + key
+		switch value := object[key].(type) {
+		case map[string]any:
+			if p.opts.KeepNested || (p.opts.MaxDepth > 0 && depth >= p.opts.MaxDepth) {
+				assign(event, values, path, valueOf(value))
+				continue
+			}
+			p.flatten(event, values, path+p.opts.Separator, value, depth+1)
+		case []any:
+			p.flattenArray(event, values, path, value, depth)
+		default:
+			assign(event, values, path, valueOf(value))
+		}
+	}
+}
+
+func (p *JSONParser) flattenArray(event *Event, values map[string]Value, path string, array []any, depth int) {
+	switch p.opts.Arrays {
+	case ArrayDrop:
+		return
+	case ArrayIndex:
+		object := make(map[string]any, len(array))
+		for i, item := range array {
+			object[strconv.Itoa(i)] = item
+		}
+		p.flatten(event, values, path+p.opts.Separator, object, depth)
+		return
+	case ArrayJoin:
+		list := valueOf(array)
+		if joined, ok := joinScalars(array); ok {
+			assignAs(event, values, path, joined, list)
+			return
+		}
+	}
+	b, err := json.Marshal(array)
+	if err != nil {
+		return
+	}
+	list := valueOf(array)
+	if list.String() != string(b) {
+		// Holds objects, which a list value keeps only as text.
+		assignField(event, path, string(b))
+		return
+	}
+	assignAs(event, values, path, string(b), list)
+}
+
+// joinScalars joins the elements of array with commas if none is an
+// object or array.
+func joinScalars(array []any) (string, bool) {
+	parts := make([]string, 0, len(array))
+	for _, item := range array {
+		switch item.(type) {
+		case map[string]any, []any:
+			return "", false
+		}
+		if s := valueOf(item).String(); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ","), true
+}
+
+// assign sets one field in its v1 form and records its typed value.
+func assign(event *Event, values map[string]Value, key string, value Value) {
+	assignAs(event, values, key, value.String(), value)
+}
+
+// assignAs sets a field to s and records value as its typed form if the
+// field ended up in the attrs as is, not normalized like a level.
+func assignAs(event *Event, values map[string]Value, key, s string, value Value) {
+	assignField(event, key, s)
+	if value.Kind() == KindString {
+		return
+	}
+	key = strings.TrimSpace(key)
+	if event.Attrs[key] == s {
+		values[key] = value
+	}
+}
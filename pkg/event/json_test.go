@@ -0,0 +1,122 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONParserFlattensNestedObjects(t *testing.T) {
+	parsed, ok := newJSONParser(JSONOptions{}).Parse(`{"@timestamp":"2024-03-16T08:12:04Z","log":{"level":"ERROR"},"http":{"request":{"method":"POST"},"response":{"status_code":502}},"duration_ms":1234.5,"retry":true,"user":null,"msg":"upstream failed"}`)
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-16T08:12:04Z")
+	assertAttr(t, parsed.Event.Attrs, "level", "error")
+	assertAttr(t, parsed.Event.Attrs, "http.request.method", "POST")
+	assertAttr(t, parsed.Event.Attrs, "http.response.status_code", "502")
+	assertAttr(t, parsed.Event.Attrs, "duration_ms", "1234.5")
+	assertAttr(t, parsed.Event.Attrs, "retry", "true")
+	assertAttr(t, parsed.Event.Attrs, "msg", "upstream failed")
+	if _, ok := parsed.Event.Attrs["user"]; ok {
+		t.Error("expected null to be dropped")
+	}
+
+	want := map[string]Value{
+		"http.response.status_code": IntValue(502),
+		"duration_ms":               FloatValue(1234.5),
+		"retry":                     BoolValue(true),
+	}
+	if !reflect.DeepEqual(parsed.Values, want) {
+		t.Errorf("unexpected values %v", parsed.Values)
+	}
+}
+
+func TestJSONParserOptions(t *testing.T) {
+	line := `{"msg":"done","tags":["a","b"],"spans":[{"id":1}],"ctx":{"k8s":{"pod":{"name":"api-0"}}}}`
+	tests := []struct {
+		name   string
+		opts   JSONOptions
+		attrs  map[string]string
+		absent []string
+	}{
+		{
+			name:  "defaults",
+			opts:  JSONOptions{},
+			attrs: map[string]string{"tags": `["a","b"]`, "spans": `[{"id":1}]`, "ctx.k8s.pod.name": "api-0"},
+		},
+		{
+			name:  "keep nested",
+			opts:  JSONOptions{KeepNested: true},
+			attrs: map[string]string{"ctx": `{"k8s":{"pod":{"name":"api-0"}}}`},
+		},
+		{
+			name:   "max depth",
+			opts:   JSONOptions{MaxDepth: 1, Separator: "_"},
+			attrs:  map[string]string{"ctx_k8s": `{"pod":{"name":"api-0"}}`},
+			absent: []string{"ctx_k8s_pod_name"},
+		},
+		{
+			name:   "index",
+			opts:   JSONOptions{Arrays: ArrayIndex},
+			attrs:  map[string]string{"tags.0": "a", "tags.1": "b", "spans.0.id": "1"},
+			absent: []string{"tags"},
+		},
+		{
+			name:  "join",
+			opts:  JSONOptions{Arrays: ArrayJoin},
+			attrs: map[string]string{"tags": "a,b", "spans": `[{"id":1}]`},
+		},
+		{
+			name:   "drop",
+			opts:   JSONOptions{Arrays: ArrayDrop},
+			attrs:  map[string]string{"msg": "done"},
+			absent: []string{"tags", "spans"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewJSONParser(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, ok := p.Parse(line)
+			if !ok {
+				t.Fatal("expected match")
+			}
+			for key, value := range tt.attrs {
+				assertAttr(t, parsed.Event.Attrs, key, value)
+			}
+			for _, key := range tt.absent {
+				if _, ok := parsed.Event.Attrs[key]; ok {
+					t.Errorf("expected no %s attr, got %q", key, parsed.Event.Attrs[key])
+				}
+			}
+		})
+	}
+}
+
+func TestJSONParserArrayValues(t *testing.T) {
+	parsed, ok := newJSONParser(JSONOptions{Arrays: ArrayJoin}).Parse(`{"ports":[80,443]}`)
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertAttr(t, parsed.Event.Attrs, "ports", "80,443")
+	if want := ListValue(IntValue(80), IntValue(443)); !reflect.DeepEqual(parsed.Values["ports"], want) {
+		t.Errorf("unexpected value %v", parsed.Values["ports"])
+	}
+}
+
+func TestNewJSONParserRejectsInvalidOptions(t *testing.T) {
+	if _, err := NewJSONParser(JSONOptions{Arrays: "flatten"}); err == nil {
+		t.Error("expected an unknown array mode to be rejected")
+	}
+	if _, err := NewJSONParser(JSONOptions{MaxDepth: -1}); err == nil {
+		t.Error("expected a negative depth to be rejected")
+	}
+}
+
+func TestJSONParserRejectsTrailingData(t *testing.T) {
+	if _, ok := newJSONParser(JSONOptions{}).Parse(`{"a":1} {"b":2}`); ok {
+		t.Error("expected two objects on one line to be rejected")
+	}
+}
@@ -0,0 +1,103 @@
+package event
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/strrl/lapp/pkg/timestamp"
+)
+
+// klogHeaderPattern matches the klog/glog header
+// "Lmmdd hh:mm:ss.uuuuuu threadid file:line] ".
+var klogHeaderPattern = regexp.MustCompile(`^([IWEF])(\d{2})(\d{2}) (\d{2}:\d{2}:\d{2}\.\d{6})\s+(\d+) ([^ :\]]+):(\d+)\] ?`)
+
+var klogSeverities = map[string]string{
+	"I": "info",
+	"W": "warn",
+	"E": "error",
+	"F": "fatal",
+}
+
+// KlogParser parses the klog/glog format of Kubernetes control-plane
+// components, e.g.
+//
+//	I0314 12:00:00.123456   12345 controller.go:42] "Pod updated" pod="kube-system/coredns"
+//
+// into level, timestamp, thread_id, caller and msg, plus attrs from the
+// key=value pairs of structured (InfoS/ErrorS) messages. klog writes no
+// year or zone: the year comes from Year, and times are floating until
+// Registry.Parse places them in its location (see SetLocation).
+type KlogParser struct {
+	// Year is the year of the timestamps. 0 infers it from the current
+	// date, as for syslog.
+	Year int
+
+	now func() time.Time
+}
+
+var _ Parser = (*KlogParser)(nil)
+
+// Parse implements Parser.
+func (p *KlogParser) Parse(line string) (*ParsedLine, bool) {
+	m := klogHeaderPattern.FindStringSubmatchIndex(line)
+	if m == nil {
+		return nil, false
+	}
+	group := func(i int) string { return line[m[2*i]:m[2*i+1]] }
+
+	ts, ok := timestamp.ParseLayout("0102 15:04:05.000000", group(2)+group(3)+" "+group(4), nowOrDefault(p.now))
+	if !ok {
+		return nil, false
+	}
+	if p.Year != 0 {
+		ts = ts.AddDate(p.Year-ts.Year(), 0, 0)
+	}
+
+	event := newBaseEvent(line)
+	event.Timestamp = &ts
+	event.Attrs["level"] = klogSeverities[group(1)]
+	event.Attrs["thread_id"] = group(5)
+	event.Attrs["caller"] = group(6) + ":" + group(7)
+
+	// Continuation lines (e.g. a goroutine dump) are not part of the
+	// message.
+	message, _, _ := strings.Cut(line[m[1]:], "\n")
+	msg, assignments := splitKlogMessage(message)
+	setAttr(&event, "msg", msg)
+	for _, a := range assignments {
+		// The header is authoritative for these.
+		switch strings.ToLower(a.key) {
+		case "level", "severity", "lvl", "ts", "time", "timestamp":
+			continue
+		}
+		assignField(&event, a.key, a.value)
+	}
+	return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event}, true
+}
+
+// splitKlogMessage separates the message of a klog line from a trailing
+// run of key=value pairs. Structured logging quotes the message; otherwise
+// the pairs are the longest suffix that parses as logfmt.
+func splitKlogMessage(message string) (string, []assignment) {
+	if strings.HasPrefix(message, `"`) {
+		if quoted := scanQuotedValue(message, 1); quoted.ok {
+			rest := strings.TrimSpace(message[quoted.next:])
+			if rest == "" {
+				return quoted.value, nil
+			}
+			if assignments, _, ok := scanAssignments(rest, true); ok {
+				return quoted.value, assignments
+			}
+		}
+	}
+	for i := 0; i < len(message); i++ {
+		if message[i] != ' ' || !strings.Contains(message[i:], "=") {
+			continue
+		}
+		if assignments, _, ok := scanAssignments(message[i+1:], true); ok {
+			return strings.TrimSpace(message[:i]), assignments
+		}
+	}
+	return message, nil
+}
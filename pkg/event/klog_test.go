@@ -0,0 +1,55 @@
+package event
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLine_Klog(t *testing.T) {
+	parsed := ParseLine(`E0314 12:00:00.123456   12345 reflector.go:138] k8s.io/client-go/informers/factory.go:134: Failed to watch *v1.Pod: the server is unavailable`)
+
+	if parsed.Parser != ParserKlog {
+		t.Fatalf("expected %s, got %q", ParserKlog, parsed.Parser)
+	}
+	if parsed.Event.Timestamp == nil || parsed.Event.Timestamp.Month() != time.March || parsed.Event.Timestamp.Day() != 14 || parsed.Event.Timestamp.Nanosecond() != 123456000 {
+		t.Errorf("unexpected timestamp %v", parsed.Event.Timestamp)
+	}
+	assertAttr(t, parsed.Event.Attrs, "level", "error")
+	assertAttr(t, parsed.Event.Attrs, "thread_id", "12345")
+	assertAttr(t, parsed.Event.Attrs, "caller", "reflector.go:138")
+	assertAttr(t, parsed.Event.Attrs, "msg", "k8s.io/client-go/informers/factory.go:134: Failed to watch *v1.Pod: the server is unavailable")
+}
+
+func TestKlogStructured(t *testing.T) {
+	p := &KlogParser{Year: 2024}
+
+	parsed, ok := p.Parse(`I0314 12:00:00.000001       1 kubelet.go:2446] "SyncLoop (PLEG): event for pod" pod="kube-system/coredns-5d78c9869d-x7k2p" event={"ID":"1"} err="context deadline exceeded"`)
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-03-14T12:00:00Z")
+	assertAttr(t, parsed.Event.Attrs, "level", "info")
+	assertAttr(t, parsed.Event.Attrs, "msg", "SyncLoop (PLEG): event for pod")
+	assertAttr(t, parsed.Event.Attrs, "pod", "kube-system/coredns-5d78c9869d-x7k2p")
+	assertAttr(t, parsed.Event.Attrs, "event", `{"ID":"1"}`)
+	assertAttr(t, parsed.Event.Attrs, "err", "context deadline exceeded")
+
+	parsed, ok = p.Parse("W0314 12:00:01.000000    7 controller.go:88] Starting workers count=5 queue=default")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertAttr(t, parsed.Event.Attrs, "level", "warn")
+	assertAttr(t, parsed.Event.Attrs, "msg", "Starting workers")
+	assertAttr(t, parsed.Event.Attrs, "count", "5")
+	assertAttr(t, parsed.Event.Attrs, "queue", "default")
+}
+
+func TestKlogYearInference(t *testing.T) {
+	p := &KlogParser{now: func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }}
+
+	parsed, ok := p.Parse("I1231 23:59:59.000000 1 main.go:1] bye")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	assertTimestamp(t, parsed.Event.Timestamp, "2024-12-31T23:59:59Z")
+}
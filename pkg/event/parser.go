@@ -1,42 +1,33 @@
 package event
 
 import (
-	"encoding/json"
-	"fmt"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.com/strrl/lapp/pkg/timestamp"
 )
 
 // ParsedLine is the normalized result of parsing a single raw log line.
 type ParsedLine struct {
 	SourceFormat string
-	Event        Event
-}
-
-type lineParser interface {
-	Parse(line string) (*ParsedLine, bool)
-}
-
-var defaultParsers = []lineParser{
-	jsonLineParser{},
-	logfmtLineParser{},
-	keyValueLineParser{},
-	prefixLineParser{},
-	plainTextLineParser{},
+	// Parser is the registry name of the parser that recognized the line.
+	Parser string
+	Event  Event
+	// Message is the part of the line to mine patterns from, e.g. the msg
+	// field of a JSON line or the message column of a CSV record. Empty
+	// means the whole line.
+	Message string
+	// MessageKey is the key of the Event.Attrs field Message was taken
+	// from, empty if Message is not kept in Attrs.
+	MessageKey string
+	// Values holds the typed values of the Event.Attrs the source types,
+	// such as JSON numbers, booleans and arrays; Attrs has them in string
+	// form. See EventV2.
+	Values map[string]Value
 }
 
 var (
-	timestampLayouts = []string{
-		time.RFC3339Nano,
-		"2006-01-02 15:04:05.999999999Z07:00",
-		"2006-01-02 15:04:05.999999999",
-		"2006-01-02 15:04:05Z07:00",
-		"2006-01-02 15:04:05",
-		"2006-01-02T15:04:05",
-	}
-
 	levelValues = map[string]string{
 		"trace":   "trace",
 		"debug":   "debug",
@@ -53,41 +44,10 @@ var (
 	levelPrefixPattern              = regexp.MustCompile(`^\s*\[?([A-Za-z]+)\]?:?\b`)
 )
 
-// ParseLine runs the default parser chain and always returns a normalized event.
+// ParseLine runs the parser chain of DefaultRegistry and always returns a
+// normalized event.
 func ParseLine(line string) ParsedLine {
-	for _, parser := range defaultParsers {
-		if parsed, ok := parser.Parse(line); ok {
-			return *parsed
-		}
-	}
-	return ParsedLine{
-		SourceFormat: SourceFormatPlainText,
-		Event:        newBaseEvent(line),
-	}
-}
-
-type jsonLineParser struct{}
-
-func (jsonLineParser) Parse(line string) (*ParsedLine, bool) {
-	trimmed := strings.TrimSpace(line)
-	if !strings.HasPrefix(trimmed, "{") || !strings.HasSuffix(trimmed, "}") {
-		return nil, false
-	}
-
-	var payload map[string]any
-	if err := json.Unmarshal([]byte(trimmed), &payload); err != nil {
-		return nil, false
-	}
-
-	event := newBaseEvent(line)
-	for key, value := range payload {
-		assignField(&event, key, stringifyValue(value))
-	}
-
-	return &ParsedLine{
-		SourceFormat: SourceFormatJSON,
-		Event:        event,
-	}, true
+	return DefaultRegistry.Parse(line)
 }
 
 type logfmtLineParser struct{}
@@ -248,15 +208,10 @@ func isTimestampKey(key string) bool {
 	}
 }
 
+// parseTimestamp parses raw with timestamp.Parse. Zone-less timestamps are
+// floating until Registry.Parse places them in its location.
 func parseTimestamp(raw string) (time.Time, bool) {
-	value := strings.TrimSpace(raw)
-	for _, layout := range timestampLayouts {
-		ts, err := time.Parse(layout, value)
-		if err == nil {
-			return ts, true
-		}
-	}
-	return time.Time{}, false
+	return timestamp.Parse(raw)
 }
 
 func canonicalizeLevel(raw string) (string, bool) {
@@ -265,29 +220,6 @@ func canonicalizeLevel(raw string) (string, bool) {
 	return canonical, ok
 }
 
-func stringifyValue(value any) string {
-	switch v := value.(type) {
-	case nil:
-		return ""
-	case string:
-		return v
-	case bool:
-		return strconv.FormatBool(v)
-	case float64:
-		return strconv.FormatFloat(v, 'f', -1, 64)
-	case json.Number:
-		return v.String()
-	case []any, map[string]any:
-		b, err := json.Marshal(v)
-		if err != nil {
-			return ""
-		}
-		return string(b)
-	default:
-		return fmt.Sprint(v)
-	}
-}
-
 func scanAssignments(line string, allowQuotedValues bool) ([]assignment, bool, bool) {
 	var (
 		assignments    []assignment
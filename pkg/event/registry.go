@@ -0,0 +1,359 @@
+package event
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	goerrors "github.com/go-errors/errors"
+	"github.com/strrl/lapp/pkg/timestamp"
+)
+
+// Names of the built-in parsers, in their default chain order.
+const (
+	ParserJSON      = "json"
+	ParserCEF       = "cef"
+	ParserLEEF      = "leef"
+	ParserAuditd    = "auditd"
+	ParserLogfmt    = "logfmt"
+	ParserKeyValue  = "key_value"
+	ParserAccessLog = "access_log"
+	ParserELB       = "elb"
+	ParserW3C       = "w3c"
+	ParserKlog      = "klog"
+	ParserPrefix    = "prefix"
+	ParserGrok      = "grok"
+	ParserPlain     = "plain"
+)
+
+// Parser recognizes one log line format. Parse reports false if the line is
+// not in that format, so the next parser in the chain is tried.
+type Parser interface {
+	Parse(line string) (*ParsedLine, bool)
+}
+
+// SourceParser is implemented by parsers that keep state per log source,
+// such as a header line read once per file. Registry.ForSource gives each
+// source its own instance; other registries parse every line with fresh
+// state.
+type SourceParser interface {
+	Parser
+	// NewSource returns a parser with fresh state for another source.
+	NewSource() Parser
+}
+
+// StatefulParser is a SourceParser whose state can be saved, e.g. with an
+// ingest checkpoint, so that a source resumed mid-file is parsed as if it
+// were read from the start.
+type StatefulParser interface {
+	SourceParser
+	// State returns the state the next line is parsed with, or "" if the
+	// parser has none yet.
+	State() string
+	// WithState returns a parser for the source in a state State returned.
+	WithState(state string) (Parser, error)
+}
+
+// ParserFunc adapts an ordinary function to Parser.
+type ParserFunc func(line string) (*ParsedLine, bool)
+
+// Parse implements Parser.
+func (f ParserFunc) Parse(line string) (*ParsedLine, bool) {
+	return f(line)
+}
+
+// DefaultMessageKeys are the fields a structured line's message is taken
+// from, in order of preference.
+var DefaultMessageKeys = []string{"msg", "message", "text"}
+
+// DefaultRegistry is the registry used by ParseLine.
+var DefaultRegistry = NewRegistry()
+
+// Registry holds named parsers and the chain they are tried in; the first
+// parser in the chain that recognizes a line wins. Registered parsers that
+// are not in the chain are disabled. A Registry is safe for concurrent use.
+type Registry struct {
+	mu          sync.RWMutex
+	parsers     map[string]Parser
+	chain       []string
+	messageKeys []string
+	location    *time.Location
+	// source is set on registries returned by ForSource, whose
+	// SourceParsers keep their state between lines.
+	source bool
+}
+
+// NewRegistry returns a registry with the built-in parsers chained as
+// json → cef → leef → auditd → logfmt → key_value → access_log → elb →
+// w3c → klog → prefix → grok → plain. The security formats come before
+// logfmt, which would otherwise accept their key=value fields.
+func NewRegistry() *Registry {
+	r := &Registry{parsers: make(map[string]Parser), messageKeys: slices.Clone(DefaultMessageKeys)}
+	for _, p := range []struct {
+		name   string
+		parser Parser
+	}{
+		{ParserJSON, newJSONParser(JSONOptions{})},
+		{ParserCEF, cefParser{}},
+		{ParserLEEF, leefParser{}},
+		{ParserAuditd, &auditdParser{}},
+		{ParserLogfmt, logfmtLineParser{}},
+		{ParserKeyValue, keyValueLineParser{}},
+		{ParserAccessLog, accessLogParser{}},
+		{ParserELB, elbParser{}},
+		{ParserW3C, &w3cParser{}},
+		{ParserKlog, &KlogParser{}},
+		{ParserPrefix, prefixLineParser{}},
+		{ParserGrok, newDefaultGrokParser()},
+		{ParserPlain, plainTextLineParser{}},
+	} {
+		r.parsers[p.name] = p.parser
+		r.chain = append(r.chain, p.name)
+	}
+	return r
+}
+
+// Register adds a named parser to the chain, ahead of the plain-text
+// fallback if that is enabled (which accepts every line) and last
+// otherwise. Names must be unique.
+func (r *Registry) Register(name string, p Parser) error {
+	if name == "" || p == nil {
+		return goerrors.New("parser name and parser are required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.parsers[name]; ok {
+		return goerrors.Errorf("register parser: %q already registered", name)
+	}
+	r.parsers[name] = p
+	at := len(r.chain)
+	if i := slices.Index(r.chain, ParserPlain); i >= 0 {
+		at = i
+	}
+	r.chain = slices.Insert(r.chain, at, name)
+	return nil
+}
+
+// Replace swaps the parser registered under name for p, keeping its place
+// in the chain, e.g. to configure a built-in parser differently.
+func (r *Registry) Replace(name string, p Parser) error {
+	if p == nil {
+		return goerrors.New("parser is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.parsers[name]; !ok {
+		return goerrors.Errorf("replace parser: unknown parser %q", name)
+	}
+	r.parsers[name] = p
+	return nil
+}
+
+// SetChain replaces the chain with the named parsers in the given order,
+// disabling all others. Every name must be registered.
+func (r *Registry) SetChain(names ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := r.parsers[name]; !ok {
+			return goerrors.Errorf("set parser chain: unknown parser %q", name)
+		}
+		if seen[name] {
+			return goerrors.Errorf("set parser chain: parser %q listed twice", name)
+		}
+		seen[name] = true
+	}
+	r.chain = slices.Clone(names)
+	return nil
+}
+
+// Disable removes the named parser from the chain. It stays registered and
+// can be chained again with SetChain.
+func (r *Registry) Disable(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.parsers[name]; !ok {
+		return goerrors.Errorf("disable parser: unknown parser %q", name)
+	}
+	r.chain = slices.DeleteFunc(r.chain, func(n string) bool { return n == name })
+	return nil
+}
+
+// SetMessageKeys sets the fields the message of JSON, logfmt and
+// key=value lines is taken from (see ParsedLine.Message), in order of
+// preference. Keys match case-insensitively.
+func (r *Registry) SetMessageKeys(keys ...string) error {
+	if len(keys) == 0 {
+		return goerrors.New("at least one message key is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messageKeys = slices.Clone(keys)
+	return nil
+}
+
+// MessageKeys returns the fields messages are taken from.
+func (r *Registry) MessageKeys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.messageKeys)
+}
+
+// SetLocation sets the time zone of timestamps written without one, such
+// as "2024-03-16 08:12:04" or syslog's "Mar 16 08:12:04". nil means UTC,
+// the default.
+func (r *Registry) SetLocation(loc *time.Location) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.location = loc
+}
+
+// Location returns the time zone of timestamps written without one.
+func (r *Registry) Location() *time.Location {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.location == nil {
+		return time.UTC
+	}
+	return r.location
+}
+
+// Chain returns the names of the enabled parsers in the order they are
+// tried.
+func (r *Registry) Chain() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.chain)
+}
+
+// Clone returns an independent copy of the registry, e.g. to customize the
+// chain of one workspace without affecting DefaultRegistry. The copy keeps
+// no per-source state; see ForSource.
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c := &Registry{
+		parsers:     make(map[string]Parser, len(r.parsers)),
+		chain:       slices.Clone(r.chain),
+		messageKeys: slices.Clone(r.messageKeys),
+		location:    r.location,
+	}
+	for name, p := range r.parsers {
+		c.parsers[name] = p
+	}
+	return c
+}
+
+// ForSource returns a copy of the registry for parsing the lines of one
+// source in order: each SourceParser is replaced by a fresh instance that
+// keeps its state, such as a W3C #Fields header, from line to line.
+func (r *Registry) ForSource() *Registry {
+	c := r.Clone()
+	c.source = true
+	for name, p := range c.parsers {
+		if sp, ok := p.(SourceParser); ok {
+			c.parsers[name] = sp.NewSource()
+		}
+	}
+	return c
+}
+
+// SourceState returns the state of the StatefulParsers of a registry
+// returned by ForSource, by parser name. Parsers without state are omitted.
+func (r *Registry) SourceState() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	states := make(map[string]string)
+	for name, p := range r.parsers {
+		if sp, ok := p.(StatefulParser); ok {
+			if state := sp.State(); state != "" {
+				states[name] = state
+			}
+		}
+	}
+	return states
+}
+
+// RestoreSourceState puts the StatefulParsers of a registry returned by
+// ForSource into states SourceState returned. States of parsers no longer
+// registered, or no longer stateful, are ignored.
+func (r *Registry) RestoreSourceState(states map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, state := range states {
+		sp, ok := r.parsers[name].(StatefulParser)
+		if !ok {
+			continue
+		}
+		p, err := sp.WithState(state)
+		if err != nil {
+			return goerrors.Errorf("restore parser %q: %w", name, err)
+		}
+		r.parsers[name] = p
+	}
+	return nil
+}
+
+// Parse runs the chain over line and always returns a normalized event:
+// lines no parser recognizes become plain text. Unless the registry was
+// returned by ForSource, SourceParsers see each line on its own.
+func (r *Registry) Parse(line string) ParsedLine {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, name := range r.chain {
+		p := r.parsers[name]
+		if sp, ok := p.(SourceParser); ok && !r.source {
+			p = sp.NewSource()
+		}
+		if parsed, ok := p.Parse(line); ok && parsed != nil {
+			parsed.Parser = name
+			if parsed.Message == "" {
+				parsed.MessageKey, parsed.Message = r.structuredMessage(parsed)
+			}
+			if ts := parsed.Event.Timestamp; ts != nil {
+				placed := timestamp.In(*ts, r.location)
+				parsed.Event.Timestamp = &placed
+			}
+			extractCorrelation(&parsed.Event)
+			return *parsed
+		}
+	}
+	event := newBaseEvent(line)
+	extractCorrelation(&event)
+	return ParsedLine{
+		SourceFormat: SourceFormatPlainText,
+		Event:        event,
+	}
+}
+
+// structuredMessage returns the key and value of the message field of a
+// JSON, logfmt or key=value line, so that patterns are mined from the
+// message rather than from the whole line with its IDs and timestamps.
+// The field stays in Attrs.
+func (r *Registry) structuredMessage(parsed *ParsedLine) (string, string) {
+	switch parsed.SourceFormat {
+	case SourceFormatJSON, SourceFormatLogfmt, SourceFormatKeyValue:
+	default:
+		return "", ""
+	}
+	for _, want := range r.messageKeys {
+		for key, value := range parsed.Event.Attrs {
+			if strings.EqualFold(key, want) && value != "" {
+				return key, value
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,181 @@
+package event
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strrl/lapp/pkg/timestamp"
+)
+
+func TestRegistryDefaultChain(t *testing.T) {
+	r := NewRegistry()
+
+	want := []string{ParserJSON, ParserCEF, ParserLEEF, ParserAuditd, ParserLogfmt, ParserKeyValue, ParserAccessLog, ParserELB, ParserW3C, ParserKlog, ParserPrefix, ParserGrok, ParserPlain}
+	if got := r.Chain(); !slices.Equal(got, want) {
+		t.Fatalf("chain: got %v, want %v", got, want)
+	}
+
+	for line, parser := range map[string]string{
+		`{"level":"info","msg":"ok"}`:      ParserJSON,
+		`level=info msg="ok"`:              ParserLogfmt,
+		`level=info msg=ok`:                ParserKeyValue,
+		`2026-03-10T21:03:45Z ERROR stall`: ParserPrefix,
+		`just words`:                       ParserPlain,
+	} {
+		if got := r.Parse(line).Parser; got != parser {
+			t.Errorf("Parse(%q).Parser: got %q, want %q", line, got, parser)
+		}
+	}
+}
+
+func TestRegistryRegisterCustomParser(t *testing.T) {
+	r := NewRegistry()
+	pipe := ParserFunc(func(line string) (*ParsedLine, bool) {
+		fields := strings.Split(line, "|")
+		if len(fields) != 3 {
+			return nil, false
+		}
+		event := newBaseEvent(line)
+		event.Attrs["component"] = fields[0]
+		event.Attrs["level"] = fields[1]
+		return &ParsedLine{SourceFormat: SourceFormatPlainText, Event: event}, true
+	})
+
+	if err := r.Register("pipe", pipe); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := r.Register("pipe", pipe); err == nil {
+		t.Error("expected duplicate name to be rejected")
+	}
+	if got := r.Chain(); got[len(got)-2] != "pipe" || got[len(got)-1] != ParserPlain {
+		t.Errorf("expected custom parser ahead of the plain fallback, got %v", got)
+	}
+
+	parsed := r.Parse("db|error|connection lost")
+	if parsed.Parser != "pipe" {
+		t.Fatalf("expected pipe parser, got %q", parsed.Parser)
+	}
+	assertAttr(t, parsed.Event.Attrs, "component", "db")
+}
+
+func TestRegistrySetChainAndDisable(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.SetChain(ParserKeyValue, ParserPlain); err != nil {
+		t.Fatalf("SetChain: %v", err)
+	}
+	if got := r.Parse(`{"level":"info"}`).Parser; got != ParserPlain {
+		t.Errorf("expected disabled json parser to be skipped, got %q", got)
+	}
+	if err := r.SetChain("nope"); err == nil {
+		t.Error("expected unknown parser to be rejected")
+	}
+
+	if err := r.Disable(ParserPlain); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	parsed := r.Parse("just words")
+	if parsed.Parser != "" || parsed.SourceFormat != SourceFormatPlainText || parsed.Event.Text != "just words" {
+		t.Errorf("expected unparsed plain text, got %+v", parsed)
+	}
+
+	if got := DefaultRegistry.Chain(); !slices.Equal(got, NewRegistry().Chain()) {
+		t.Errorf("DefaultRegistry changed: %v", got)
+	}
+}
+
+func TestRegistryClone(t *testing.T) {
+	r := NewRegistry()
+	c := r.Clone()
+	if err := c.Disable(ParserJSON); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if got := r.Parse(`{"a":"b"}`).Parser; got != ParserJSON {
+		t.Errorf("clone changed the original: %q", got)
+	}
+}
+
+func TestRegistryReplace(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Replace(ParserKlog, &KlogParser{Year: 2020}); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	parsed := r.Parse("I0314 12:00:00.000000 1 main.go:1] hi")
+	if parsed.Parser != ParserKlog || parsed.Event.Timestamp.Year() != 2020 {
+		t.Errorf("expected the replacement klog parser, got %q at %v", parsed.Parser, parsed.Event.Timestamp)
+	}
+	if err := r.Replace("nope", &KlogParser{}); err == nil {
+		t.Error("expected unknown parser to be rejected")
+	}
+}
+
+func TestRegistryStructuredMessage(t *testing.T) {
+	r := NewRegistry()
+
+	for line, want := range map[string]string{
+		`{"ts":"2024-03-16T08:12:04Z","request_id":"a1","msg":"user logged in"}`: "user logged in",
+		`level=info Message="cache miss" key=user:42`:                            "cache miss",
+		`level=info text=ok`:                          "ok",
+		`{"level":"info","event":"no message field"}`: "",
+		`2026-03-10T21:03:45Z ERROR msg=stall`:        "",
+	} {
+		parsed := r.Parse(line)
+		if parsed.Message != want {
+			t.Errorf("Parse(%q).Message: got %q, want %q", line, parsed.Message, want)
+		}
+		if want != "" && parsed.Event.Attrs[parsed.MessageKey] != want {
+			t.Errorf("Parse(%q).MessageKey: %q does not hold the message in %v", line, parsed.MessageKey, parsed.Event.Attrs)
+		}
+		if want != "" && parsed.Event.Attrs["msg"] == "" && parsed.Event.Attrs["Message"] == "" && parsed.Event.Attrs["text"] == "" {
+			t.Errorf("Parse(%q): expected the message to stay in attrs, got %v", line, parsed.Event.Attrs)
+		}
+	}
+
+	if err := r.SetMessageKeys("event"); err != nil {
+		t.Fatalf("SetMessageKeys: %v", err)
+	}
+	if got := r.Parse(`{"event":"signup","msg":"ignored"}`); got.Message != "signup" || got.MessageKey != "event" {
+		t.Errorf("expected the configured key to be used, got %q from %q", got.Message, got.MessageKey)
+	}
+	// Another field with the same value is not the message field.
+	if got := r.Parse(`{"msg":"started","event":"started"}`); got.MessageKey != "event" {
+		t.Errorf("expected the message key event, got %q", got.MessageKey)
+	}
+	if err := r.SetMessageKeys(); err == nil {
+		t.Error("expected an empty key list to be rejected")
+	}
+	if got := DefaultRegistry.MessageKeys(); !slices.Equal(got, DefaultMessageKeys) {
+		t.Errorf("DefaultRegistry changed: %v", got)
+	}
+}
+
+func TestRegistryLocation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	r := NewRegistry()
+	r.SetLocation(berlin)
+
+	for line, want := range map[string]string{
+		`2024-03-16 08:12:04 ERROR disk full`:       "2024-03-16T07:12:04Z",
+		`ts="2024-03-16 08:12:04,500" level=info`:   "2024-03-16T07:12:04Z",
+		`2024-03-16T08:12:04Z ERROR disk full`:      "2024-03-16T08:12:04Z",
+		`{"ts":1710576724123,"msg":"epoch millis"}`: "2024-03-16T08:12:04Z",
+	} {
+		parsed := r.Parse(line)
+		assertTimestamp(t, parsed.Event.Timestamp, want)
+		if parsed.Event.Timestamp.Location() == timestamp.Floating {
+			t.Errorf("Parse(%q): expected the timestamp placed in a zone", line)
+		}
+	}
+
+	if got := DefaultRegistry.Location(); got != time.UTC {
+		t.Errorf("DefaultRegistry location: got %v, want UTC", got)
+	}
+	if got := r.Clone().Location(); got != berlin {
+		t.Errorf("Clone location: got %v", got)
+	}
+}
@@ -0,0 +1,174 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	goerrors "github.com/go-errors/errors"
+)
+
+// ValueKind is the type of a Value.
+type ValueKind int
+
+const (
+	KindString ValueKind = iota
+	KindInt
+	KindFloat
+	KindBool
+	KindList
+)
+
+// Value is a typed attribute value of the v2 event model, mirroring
+// lapp.event.v2.Value in proto/lapp/event/v2/event.proto. The zero Value is
+// the empty string.
+type Value struct {
+	kind ValueKind
+	str  string
+	i    int64
+	f    float64
+	b    bool
+	list []Value
+}
+
+func StringValue(s string) Value     { return Value{kind: KindString, str: s} }
+func IntValue(i int64) Value         { return Value{kind: KindInt, i: i} }
+func FloatValue(f float64) Value     { return Value{kind: KindFloat, f: f} }
+func BoolValue(b bool) Value         { return Value{kind: KindBool, b: b} }
+func ListValue(items ...Value) Value { return Value{kind: KindList, list: items} }
+
+// Kind returns the type of v.
+func (v Value) Kind() ValueKind { return v.kind }
+
+// Int returns an integer value.
+func (v Value) Int() (int64, bool) { return v.i, v.kind == KindInt }
+
+// Float returns a numeric value; integers are converted.
+func (v Value) Float() (float64, bool) {
+	switch v.kind {
+	case KindFloat:
+		return v.f, true
+	case KindInt:
+		return float64(v.i), true
+	}
+	return 0, false
+}
+
+// Bool returns a boolean value.
+func (v Value) Bool() (bool, bool) { return v.b, v.kind == KindBool }
+
+// List returns the elements of a list value.
+func (v Value) List() []Value { return v.list }
+
+// String returns the v1 form of v: numbers and booleans as Go formats
+// them, lists as JSON.
+func (v Value) String() string {
+	switch v.kind {
+	case KindInt:
+		return strconv.FormatInt(v.i, 10)
+	case KindFloat:
+		return strconv.FormatFloat(v.f, 'f', -1, 64)
+	case KindBool:
+		return strconv.FormatBool(v.b)
+	case KindList:
+		b, _ := json.Marshal(v.Any())
+		return string(b)
+	}
+	return v.str
+}
+
+// Any returns v as a plain Go value: string, int64, float64, bool or []any.
+func (v Value) Any() any {
+	switch v.kind {
+	case KindInt:
+		return v.i
+	case KindFloat:
+		return v.f
+	case KindBool:
+		return v.b
+	case KindList:
+		items := make([]any, len(v.list))
+		for i, item := range v.list {
+			items[i] = item.Any()
+		}
+		return items
+	}
+	return v.str
+}
+
+// MarshalJSON encodes v as the JSON value of its type.
+func (v Value) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Any())
+}
+
+// UnmarshalJSON decodes a JSON string, number, boolean or array. Objects
+// are kept as their JSON text.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var x any
+	if err := dec.Decode(&x); err != nil {
+		return goerrors.Errorf("decode value: %w", err)
+	}
+	*v = valueOf(x)
+	return nil
+}
+
+// valueOf converts a value decoded with json.Decoder.UseNumber.
+func valueOf(x any) Value {
+	switch x := x.(type) {
+	case nil:
+		return Value{}
+	case string:
+		return StringValue(x)
+	case bool:
+		return BoolValue(x)
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return IntValue(i)
+		}
+		f, _ := x.Float64()
+		return FloatValue(f)
+	case []any:
+		items := make([]Value, len(x))
+		for i, item := range x {
+			items[i] = valueOf(item)
+		}
+		return ListValue(items...)
+	}
+	b, _ := json.Marshal(x)
+	return StringValue(string(b))
+}
+
+// EventV2 mirrors the v2 schema in proto/lapp/event/v2/event.proto: the v1
+// Event with typed attribute values.
+type EventV2 struct {
+	Timestamp *time.Time       `json:"ts,omitempty"`
+	Text      string           `json:"text"`
+	Attrs     map[string]Value `json:"attrs"`
+	Inferred  *Inferred        `json:"inferred"`
+}
+
+// V1 returns the event with its attribute values in string form.
+func (e EventV2) V1() Event {
+	attrs := make(map[string]string, len(e.Attrs))
+	for key, value := range e.Attrs {
+		attrs[key] = value.String()
+	}
+	return Event{Timestamp: e.Timestamp, Text: e.Text, Attrs: attrs, Inferred: e.Inferred}
+}
+
+// EventV2 returns the parsed event with typed values: those in Values, and
+// strings for the other attrs.
+func (p ParsedLine) EventV2() EventV2 {
+	attrs := make(map[string]Value, len(p.Event.Attrs))
+	for key, value := range p.Event.Attrs {
+		if typed, ok := p.Values[key]; ok {
+			attrs[key] = typed
+			continue
+		}
+		attrs[key] = StringValue(value)
+	}
+	return EventV2{Timestamp: p.Event.Timestamp, Text: p.Event.Text, Attrs: attrs, Inferred: p.Event.Inferred}
+}
@@ -0,0 +1,46 @@
+package event
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValueJSONRoundTrip(t *testing.T) {
+	values := map[string]Value{
+		"s": StringValue("x"),
+		"i": IntValue(9007199254740993),
+		"f": FloatValue(0.25),
+		"b": BoolValue(false),
+		"l": ListValue(StringValue("a"), IntValue(1)),
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"b":false,"f":0.25,"i":9007199254740993,"l":["a",1],"s":"x"}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	var decoded map[string]Value
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, values) {
+		t.Errorf("round trip: got %v, want %v", decoded, values)
+	}
+}
+
+func TestParsedLineEventV2(t *testing.T) {
+	parsed := DefaultRegistry.Parse(`{"level":"warn","status":404,"path":"/x","msg":"not found"}`)
+	v2 := parsed.EventV2()
+	if got, ok := v2.Attrs["status"].Int(); !ok || got != 404 {
+		t.Errorf("status: got %v, want int 404", v2.Attrs["status"])
+	}
+	if v2.Attrs["path"].Kind() != KindString || v2.Attrs["level"].String() != "warn" {
+		t.Errorf("unexpected attrs %v", v2.Attrs)
+	}
+	if v1 := v2.V1(); !reflect.DeepEqual(v1.Attrs, parsed.Event.Attrs) {
+		t.Errorf("V1: got %v, want %v", v1.Attrs, parsed.Event.Attrs)
+	}
+}
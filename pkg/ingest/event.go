@@ -0,0 +1,33 @@
+package ingest
+
+import (
+	"context"
+
+	"github.com/strrl/lapp/pkg/event"
+)
+
+// EventParser is the default Parser: it runs the event package's parser
+// chain (JSON, logfmt, key=value, timestamp/level prefix) over the line.
+type EventParser struct {
+	// Registry supplies the parser chain; nil means event.DefaultRegistry.
+	Registry *event.Registry
+}
+
+var _ Parser = EventParser{}
+
+// Parse implements Parser. Lines no parser recognizes yield no timestamp
+// and no attrs rather than an error.
+func (p EventParser) Parse(_ context.Context, raw string) (*ParseResult, error) {
+	registry := p.Registry
+	if registry == nil {
+		registry = event.DefaultRegistry
+	}
+	parsed := registry.Parse(raw)
+	return &ParseResult{
+		Timestamp:  parsed.Event.Timestamp,
+		Attrs:      parsed.Event.Attrs,
+		Message:    parsed.Message,
+		MessageKey: parsed.MessageKey,
+		Values:     parsed.Values,
+	}, nil
+}
@@ -0,0 +1,78 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/strrl/lapp/pkg/event"
+	"github.com/strrl/lapp/pkg/logsource"
+)
+
+func TestEventParser(t *testing.T) {
+	ctx := context.Background()
+	runtime := time.Date(2024, 3, 16, 10, 0, 9, 0, time.UTC)
+
+	outcome, err := FromRawLine(ctx, &logsource.LogLine{
+		LineNumber: 1,
+		Content:    `{"ts":"2024-03-16T10:00:01Z","level":"ERROR","msg":"boom"}`,
+		Timestamp:  &runtime,
+		Attrs:      map[string]string{"stream": "stderr"},
+	}, EventParser{})
+	if err != nil {
+		t.Fatalf("FromRawLine: %v", err)
+	}
+	want := time.Date(2024, 3, 16, 10, 0, 1, 0, time.UTC)
+	if !outcome.LogEntry.Timestamp.Equal(want) {
+		t.Errorf("Timestamp: got %v, want the line's own %v", outcome.LogEntry.Timestamp, want)
+	}
+	if outcome.LogEntry.Attrs["level"] != "error" || outcome.LogEntry.Attrs["stream"] != "stderr" {
+		t.Errorf("Attrs: got %v", outcome.LogEntry.Attrs)
+	}
+	if outcome.LogEntry.Message != "boom" {
+		t.Errorf("Message: got %q, want the msg field", outcome.LogEntry.Message)
+	}
+
+	outcome, err = FromRawLine(ctx, &logsource.LogLine{
+		LineNumber: 2,
+		Content:    `{"msg":"served","http":{"status":200},"cached":false}`,
+	}, EventParser{})
+	if err != nil {
+		t.Fatalf("FromRawLine: %v", err)
+	}
+	if outcome.LogEntry.Attrs["http.status"] != "200" {
+		t.Errorf("Attrs: got %v, want nested keys flattened", outcome.LogEntry.Attrs)
+	}
+	if outcome.LogEntry.Values["http.status"] != int64(200) || outcome.LogEntry.Values["cached"] != false {
+		t.Errorf("Values: got %v", outcome.LogEntry.Values)
+	}
+
+	outcome, err = FromRawLine(ctx, &logsource.LogLine{LineNumber: 3, Content: "plain text", Timestamp: &runtime}, EventParser{})
+	if err != nil {
+		t.Fatalf("FromRawLine: %v", err)
+	}
+	if !outcome.LogEntry.Timestamp.Equal(runtime) {
+		t.Errorf("plain line should keep the source timestamp, got %v", outcome.LogEntry.Timestamp)
+	}
+	if outcome.LogEntry.Message != "" {
+		t.Errorf("plain line should be clustered whole, got message %q", outcome.LogEntry.Message)
+	}
+}
+
+func TestEventParserRegistry(t *testing.T) {
+	registry := event.NewRegistry()
+	if err := registry.SetChain(event.ParserPlain); err != nil {
+		t.Fatalf("SetChain: %v", err)
+	}
+
+	outcome, err := FromRawLine(context.Background(), &logsource.LogLine{
+		LineNumber: 1,
+		Content:    `{"level":"ERROR","msg":"boom"}`,
+	}, EventParser{Registry: registry})
+	if err != nil {
+		t.Fatalf("FromRawLine: %v", err)
+	}
+	if len(outcome.LogEntry.Attrs) != 0 {
+		t.Errorf("expected the JSON parser to be disabled, got attrs %v", outcome.LogEntry.Attrs)
+	}
+}
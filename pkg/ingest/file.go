@@ -0,0 +1,323 @@
+package ingest
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/go-errors/errors"
+	"github.com/strrl/lapp/pkg/event"
+	"github.com/strrl/lapp/pkg/logsource"
+	"github.com/strrl/lapp/pkg/multiline"
+	"github.com/strrl/lapp/pkg/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// fileBatchSize is how many entries are committed with each checkpoint.
+const fileBatchSize = 1000
+
+// FileOptions tunes IngestFile.
+type FileOptions struct {
+	// FallbackCharset is the charset assumed for files without a BOM that
+	// are not UTF-8 (see logsource.FileOptions).
+	FallbackCharset string
+	// Parsers is the parser chain entries are parsed with; nil means
+	// event.DefaultRegistry.
+	Parsers *event.Registry
+	// QuotedRecords reads the files as CSV-style records, merging lines
+	// only inside quoted fields (see multiline.DetectorConfig).
+	QuotedRecords bool
+}
+
+// IngestFile stores the log file at path in dst under source and returns
+// the number of entries inserted.
+//
+// Ingestion resumes from the source's checkpoint: only lines appended since
+// the last run are read, and each batch of entries is committed together
+// with the checkpoint that follows it, so an interrupted run never inserts an
+// entry twice. The checkpoint also keeps the parsers' state, such as a W3C
+// #Fields header, so resumed lines are parsed as before. The last entry may
+// still grow while the file is written, by the rest of a line without a
+// newline or by lines merged into it, so it is stored but the checkpoint
+// stays before it, and the next run reads it again and replaces it. A file
+// that was replaced or truncated is ingested again from the start.
+// Container envelopes are decoded and multiline entries merged; journalctl
+// dumps are read record by record and, having no stable line offsets, are
+// only ever ingested whole. The count returned leaves out replaced entries.
+func IngestFile(ctx context.Context, dst store.Store, source, path string, opts FileOptions) (int, error) {
+	ctx, span := otel.Tracer("lapp/ingest").Start(ctx, "ingest.IngestFile")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("source", source), attribute.String("file.path", path))
+
+	// Stop the reading goroutines if we return early.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	journalFormat, err := logsource.SniffJournal(path)
+	if err != nil {
+		return 0, errors.Errorf("sniff %s: %w", filepath.Base(path), err)
+	}
+
+	stored, err := dst.Checkpoint(ctx, source)
+	if err != nil {
+		return 0, err
+	}
+	cp := fromStoreCheckpoint(stored)
+	pos, restart, err := logsource.Resume(path, cp)
+	if err != nil {
+		return 0, err
+	}
+	if journalFormat != logsource.JournalFormatNone && cp != nil && !restart {
+		id, err := logsource.StatFileIdentity(path)
+		if err != nil {
+			return 0, err
+		}
+		if id.Size == cp.Offset {
+			return 0, nil
+		}
+		restart = true
+	}
+	if restart {
+		if err := dst.DeleteSource(ctx, source); err != nil {
+			return 0, err
+		}
+		pos = logsource.Position{}
+	}
+	span.SetAttributes(attribute.Int64("resume.offset", pos.Offset), attribute.Bool("resume.restart", restart))
+
+	parsers := sourceParsers(opts.Parsers)
+	replaced := 0
+	if stored != nil && !restart {
+		if err := parsers.RestoreSourceState(stored.ParserState); err != nil {
+			return 0, errors.Errorf("resume %s: %w", source, err)
+		}
+		// The last entry of the previous run is read again.
+		if replaced, err = dst.DeleteEntriesAfter(ctx, source, pos.LineNumber); err != nil {
+			return 0, err
+		}
+	}
+
+	var entries <-chan multiline.MergeResult
+	if journalFormat != logsource.JournalFormatNone {
+		entries, err = readJournal(ctx, path, journalFormat)
+	} else {
+		entries, err = readMerged(ctx, path, logsource.FileOptions{Start: pos, FallbackCharset: opts.FallbackCharset}, opts.QuotedRecords)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	w := &checkpointWriter{
+		dst:     dst,
+		source:  source,
+		path:    path,
+		journal: journalFormat != logsource.JournalFormatNone,
+		parsers: parsers,
+		end:     pos,
+	}
+	for mr := range entries {
+		if mr.Err != nil {
+			return w.inserted, errors.Errorf("read %s: %w", filepath.Base(path), mr.Err)
+		}
+		if err := w.add(ctx, mr.Value); err != nil {
+			return w.inserted, err
+		}
+	}
+	if err := w.finish(ctx); err != nil {
+		return w.inserted, err
+	}
+
+	span.SetAttributes(attribute.Int("entries.inserted", w.inserted), attribute.Int("entries.replaced", replaced))
+	return max(w.inserted-replaced, 0), nil
+}
+
+// sourceParsers returns the parser chain for one source, with fresh state
+// for parsers that read per-source headers.
+func sourceParsers(registry *event.Registry) *event.Registry {
+	if registry == nil {
+		registry = event.DefaultRegistry
+	}
+	return registry.ForSource()
+}
+
+// readMerged streams the file through container decoding and multiline
+// merging.
+func readMerged(ctx context.Context, path string, opts logsource.FileOptions, quotedRecords bool) (<-chan multiline.MergeResult, error) {
+	detector, err := multiline.NewDetector(multiline.DetectorConfig{
+		StackTraces:   true,
+		Payloads:      true,
+		Learn:         multiline.LearnExtend,
+		QuotedRecords: quotedRecords,
+	})
+	if err != nil {
+		return nil, errors.Errorf("multiline detector: %w", err)
+	}
+	lines, err := logsource.IngestWithOptions(ctx, path, opts)
+	if err != nil {
+		return nil, errors.Errorf("ingest %s: %w", filepath.Base(path), err)
+	}
+	return multiline.Merge(ctx, logsource.DecodeContainer(ctx, lines), detector), nil
+}
+
+// readJournal streams a journalctl dump. Each record is already a complete
+// entry, so multiline merging is skipped.
+func readJournal(ctx context.Context, path string, format logsource.JournalFormat) (<-chan multiline.MergeResult, error) {
+	records, err := logsource.IngestJournal(ctx, path, format)
+	if err != nil {
+		return nil, errors.Errorf("ingest journal %s: %w", filepath.Base(path), err)
+	}
+
+	out := make(chan multiline.MergeResult, 100)
+	go func() {
+		defer close(out)
+		for rr := range records {
+			var mr multiline.MergeResult
+			if rr.Err != nil {
+				mr.Err = rr.Err
+			} else {
+				mr.Value = &multiline.MergedLine{
+					StartLine: rr.Value.LineNumber,
+					EndLine:   rr.Value.LineNumber,
+					Content:   rr.Value.Content,
+					Timestamp: rr.Value.Timestamp,
+					Attrs:     rr.Value.Attrs,
+				}
+			}
+			select {
+			case out <- mr:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// checkpointWriter batches entries and commits each batch with the
+// checkpoint just past its last entry, except for the last entry of the
+// file (see IngestFile).
+type checkpointWriter struct {
+	dst      store.Store
+	source   string
+	path     string
+	journal  bool
+	parsers  *event.Registry
+	batch    []store.LogEntry
+	end      logsource.Position
+	inserted int
+	// pending is the latest entry, written once another follows it.
+	pending *multiline.MergedLine
+	// tail is the checkpoint before the last entry, once it is written.
+	tail *store.Checkpoint
+}
+
+func (w *checkpointWriter) add(ctx context.Context, m *multiline.MergedLine) error {
+	if w.journal {
+		return w.write(ctx, m)
+	}
+	if w.pending != nil {
+		if err := w.write(ctx, w.pending); err != nil {
+			return err
+		}
+	}
+	w.pending = m
+	return nil
+}
+
+// finish writes the last entry and commits, with the checkpoint before
+// that entry and the parser state it was parsed with.
+func (w *checkpointWriter) finish(ctx context.Context) error {
+	if w.pending != nil {
+		cp, err := logsource.NewCheckpoint(w.path, w.end)
+		if err != nil {
+			return err
+		}
+		tail := toStoreCheckpoint(w.source, cp)
+		tail.ParserState = w.parsers.SourceState()
+		w.tail = &tail
+		if err := w.write(ctx, w.pending); err != nil {
+			return err
+		}
+	}
+	return w.commit(ctx)
+}
+
+func (w *checkpointWriter) write(ctx context.Context, m *multiline.MergedLine) error {
+	outcome, err := FromRawLine(ctx, &logsource.LogLine{
+		LineNumber: m.StartLine,
+		Content:    m.Content,
+		Timestamp:  m.Timestamp,
+		Attrs:      m.Attrs,
+	}, EventParser{Registry: w.parsers})
+	if err != nil {
+		return err
+	}
+	entry := outcome.LogEntry
+	entry.EndLineNumber = m.EndLine
+	entry.Source = w.source
+	entry.Truncated = m.Truncated
+	entry.Continuation = m.Continuation
+
+	w.batch = append(w.batch, entry)
+	w.end = logsource.Position{Offset: m.EndOffset, LineNumber: m.EndLine}
+	// A journal dump is committed in one piece; see IngestFile.
+	if !w.journal && len(w.batch) >= fileBatchSize {
+		return w.commit(ctx)
+	}
+	return nil
+}
+
+func (w *checkpointWriter) commit(ctx context.Context) error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	if w.journal {
+		id, err := logsource.StatFileIdentity(w.path)
+		if err != nil {
+			return err
+		}
+		w.end.Offset = id.Size
+	}
+
+	var stored store.Checkpoint
+	if w.tail != nil {
+		stored = *w.tail
+	} else {
+		cp, err := logsource.NewCheckpoint(w.path, w.end)
+		if err != nil {
+			return err
+		}
+		stored = toStoreCheckpoint(w.source, cp)
+		stored.ParserState = w.parsers.SourceState()
+	}
+	if err := w.dst.CommitLogBatch(ctx, w.batch, stored); err != nil {
+		return errors.Errorf("commit %s: %w", w.source, err)
+	}
+	w.inserted += len(w.batch)
+	w.batch = w.batch[:0]
+	return nil
+}
+
+func toStoreCheckpoint(source string, cp logsource.Checkpoint) store.Checkpoint {
+	return store.Checkpoint{
+		Source:      source,
+		Device:      cp.Device,
+		Inode:       cp.Inode,
+		Size:        cp.Size,
+		Fingerprint: cp.Fingerprint,
+		Offset:      cp.Offset,
+		LineNumber:  cp.LineNumber,
+	}
+}
+
+func fromStoreCheckpoint(cp *store.Checkpoint) *logsource.Checkpoint {
+	if cp == nil {
+		return nil
+	}
+	return &logsource.Checkpoint{
+		FileIdentity: logsource.FileIdentity{Device: cp.Device, Inode: cp.Inode, Size: cp.Size},
+		Fingerprint:  cp.Fingerprint,
+		Position:     logsource.Position{Offset: cp.Offset, LineNumber: cp.LineNumber},
+	}
+}
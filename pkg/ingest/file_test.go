@@ -0,0 +1,361 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strrl/lapp/pkg/event"
+	"github.com/strrl/lapp/pkg/store"
+)
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func sourceEntries(t *testing.T, s *store.DuckDBStore, source string) []store.LogEntry {
+	t.Helper()
+
+	entries, err := s.QueryLogs(context.Background(), store.QueryOpts{Source: source})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	return entries
+}
+
+func TestIngestFileResumesFromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	appendFile(t, path, "2024-03-16 10:00:00 INFO started\n2024-03-16 10:00:01 ERROR boom\n  at main.go:10\n")
+	n, err := IngestFile(ctx, s, "app.log", path, FileOptions{})
+	if err != nil {
+		t.Fatalf("IngestFile: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("first run: inserted %d, want 2", n)
+	}
+
+	// Nothing new: nothing inserted.
+	if n, err := IngestFile(ctx, s, "app.log", path, FileOptions{}); err != nil || n != 0 {
+		t.Fatalf("second run: inserted %d, err %v", n, err)
+	}
+
+	appendFile(t, path, "2024-03-16 10:00:02 INFO recovered\n")
+	if n, err := IngestFile(ctx, s, "app.log", path, FileOptions{}); err != nil || n != 1 {
+		t.Fatalf("after append: inserted %d, err %v", n, err)
+	}
+
+	entries := sourceEntries(t, s, "app.log")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if entries[1].LineNumber != 2 || entries[1].EndLineNumber != 3 {
+		t.Errorf("merged entry bounds: got %d-%d", entries[1].LineNumber, entries[1].EndLineNumber)
+	}
+	if entries[2].Raw != "2024-03-16 10:00:02 INFO recovered" || entries[2].LineNumber != 4 {
+		t.Errorf("resumed entry: got %d %q", entries[2].LineNumber, entries[2].Raw)
+	}
+
+	// The last entry may still grow, so the checkpoint stays before it.
+	cp, err := s.Checkpoint(ctx, "app.log")
+	if err != nil || cp == nil || cp.LineNumber != 3 {
+		t.Fatalf("checkpoint: got %+v %v", cp, err)
+	}
+}
+
+func TestIngestFileResumesPartialLastLine(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	// The writer is in the middle of a line.
+	appendFile(t, path, "2024-03-16 10:00:00 INFO started\n2024-03-16 10:00:01 INFO parti")
+	if n, err := IngestFile(ctx, s, "app.log", path, FileOptions{}); err != nil || n != 2 {
+		t.Fatalf("first run: inserted %d, err %v", n, err)
+	}
+	appendFile(t, path, "al write\n2024-03-16 10:00:02 ERROR boom\n")
+	if n, err := IngestFile(ctx, s, "app.log", path, FileOptions{}); err != nil || n != 1 {
+		t.Fatalf("after append: inserted %d, err %v", n, err)
+	}
+
+	entries := sourceEntries(t, s, "app.log")
+	var raws []string
+	for _, e := range entries {
+		raws = append(raws, e.Raw)
+	}
+	want := []string{"2024-03-16 10:00:00 INFO started", "2024-03-16 10:00:01 INFO partial write", "2024-03-16 10:00:02 ERROR boom"}
+	if !slices.Equal(raws, want) {
+		t.Fatalf("entries: got %q, want %q", raws, want)
+	}
+}
+
+func TestIngestFileResumesOpenMultilineEntry(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	appendFile(t, path, "2024-03-16 10:00:00 INFO started\n2024-03-16 10:00:01 ERROR boom\n  at main.go:10\n")
+	if _, err := IngestFile(ctx, s, "app.log", path, FileOptions{}); err != nil {
+		t.Fatalf("IngestFile: %v", err)
+	}
+	appendFile(t, path, "  at main.go:20\n2024-03-16 10:00:02 INFO recovered\n")
+	if n, err := IngestFile(ctx, s, "app.log", path, FileOptions{}); err != nil || n != 1 {
+		t.Fatalf("after append: inserted %d, err %v", n, err)
+	}
+
+	entries := sourceEntries(t, s, "app.log")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if e := entries[1]; e.LineNumber != 2 || e.EndLineNumber != 4 || !strings.HasSuffix(e.Raw, "at main.go:20") {
+		t.Errorf("stack trace not merged across runs: %d-%d %q", e.LineNumber, e.EndLineNumber, e.Raw)
+	}
+}
+
+func TestIngestFileRestartsReplacedFile(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	appendFile(t, path, "old one\nold two\n")
+	if _, err := IngestFile(ctx, s, "app.log", path, FileOptions{}); err != nil {
+		t.Fatalf("IngestFile: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("new one\n"), 0o644); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if n, err := IngestFile(ctx, s, "app.log", path, FileOptions{}); err != nil || n != 1 {
+		t.Fatalf("after rewrite: inserted %d, err %v", n, err)
+	}
+
+	entries := sourceEntries(t, s, "app.log")
+	if len(entries) != 1 || entries[0].Raw != "new one" {
+		t.Fatalf("expected only the new content, got %+v", entries)
+	}
+}
+
+func TestIngestFileJournalIsIngestedWhole(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "journal.json")
+
+	appendFile(t, path, `{"__CURSOR":"s=1","__REALTIME_TIMESTAMP":"1710576724000000","MESSAGE":"first","PRIORITY":"6"}`+"\n")
+	if n, err := IngestFile(ctx, s, "journal.json", path, FileOptions{}); err != nil || n != 1 {
+		t.Fatalf("first run: inserted %d, err %v", n, err)
+	}
+	if n, err := IngestFile(ctx, s, "journal.json", path, FileOptions{}); err != nil || n != 0 {
+		t.Fatalf("unchanged: inserted %d, err %v", n, err)
+	}
+
+	appendFile(t, path, `{"__CURSOR":"s=2","__REALTIME_TIMESTAMP":"1710576725000000","MESSAGE":"second","PRIORITY":"3"}`+"\n")
+	if _, err := IngestFile(ctx, s, "journal.json", path, FileOptions{}); err != nil {
+		t.Fatalf("after append: %v", err)
+	}
+	entries := sourceEntries(t, s, "journal.json")
+	if len(entries) != 2 || entries[0].Raw != "first" || entries[1].Attrs["level"] != "error" {
+		t.Fatalf("journal entries: got %+v", entries)
+	}
+}
+
+func TestIngestFileParsesPrettyJSON(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "api.log")
+
+	appendFile(t, path, "2024-03-16 10:00:00 INFO request received\n"+
+		"{\n"+
+		"  \"ts\": \"2024-03-16T10:00:01Z\",\n"+
+		"  \"level\": \"error\",\n"+
+		"  \"msg\": \"upstream failed\"\n"+
+		"}\n"+
+		"2024-03-16 10:00:02 INFO retrying\n")
+	if _, err := IngestFile(ctx, s, "api.log", path, FileOptions{}); err != nil {
+		t.Fatalf("IngestFile: %v", err)
+	}
+
+	entries := sourceEntries(t, s, "api.log")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	payload := entries[1]
+	if payload.LineNumber != 2 || payload.EndLineNumber != 6 {
+		t.Errorf("payload lines: got %d-%d, want 2-6", payload.LineNumber, payload.EndLineNumber)
+	}
+	if payload.Attrs["level"] != "error" || payload.Timestamp.Format(time.RFC3339) != "2024-03-16T10:00:01Z" {
+		t.Errorf("payload not parsed as JSON: ts %v attrs %v", payload.Timestamp, payload.Attrs)
+	}
+}
+
+func TestIngestFileMarksSplitEntries(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "api.log")
+
+	// A stack trace larger than the 64 KiB entry limit.
+	var b strings.Builder
+	b.WriteString("2024-03-16 10:00:00 ERROR request failed\n")
+	for range 1000 {
+		b.WriteString("\tat com.example.service.Handler.process(Handler.java:42) frame padding\n")
+	}
+	b.WriteString("2024-03-16 10:00:01 INFO recovered\n")
+	appendFile(t, path, b.String())
+	if _, err := IngestFile(ctx, s, "api.log", path, FileOptions{}); err != nil {
+		t.Fatalf("IngestFile: %v", err)
+	}
+
+	entries := sourceEntries(t, s, "api.log")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if !entries[0].Truncated || entries[0].Continuation {
+		t.Errorf("head: got truncated=%v continuation=%v", entries[0].Truncated, entries[0].Continuation)
+	}
+	if entries[1].Truncated || !entries[1].Continuation {
+		t.Errorf("tail: got truncated=%v continuation=%v", entries[1].Truncated, entries[1].Continuation)
+	}
+	if entries[2].Truncated || entries[2].Continuation {
+		t.Errorf("next entry marked as split: %+v", entries[2])
+	}
+}
+
+func TestIngestFileParsesW3CHeaderPerSource(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	dir := t.TempDir()
+
+	appendFile(t, filepath.Join(dir, "u_ex1.log"), "#Software: Microsoft Internet Information Services 10.0\n"+
+		"#Fields: date time cs-method cs-uri-stem sc-status time-taken\n"+
+		"2024-03-28 13:45:30 GET /index.html 200 15\n")
+	appendFile(t, filepath.Join(dir, "other.log"), "2024-03-28 13:45:31 GET /index.html 200 15\n")
+	for _, name := range []string{"u_ex1.log", "other.log"} {
+		if _, err := IngestFile(ctx, s, name, filepath.Join(dir, name), FileOptions{}); err != nil {
+			t.Fatalf("IngestFile %s: %v", name, err)
+		}
+	}
+
+	entries := sourceEntries(t, s, "u_ex1.log")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if entries[2].Attrs["status"] != "200" || entries[2].Attrs["latency_ms"] != "15" {
+		t.Errorf("W3C line not parsed: %v", entries[2].Attrs)
+	}
+	if other := sourceEntries(t, s, "other.log"); other[0].Attrs["status"] != "" {
+		t.Errorf("header of another source applied: %v", other[0].Attrs)
+	}
+}
+
+func TestIngestFileResumesW3CHeader(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "u_ex1.log")
+
+	appendFile(t, path, "#Fields: date time cs-method cs-uri-stem sc-status time-taken\n"+
+		"2024-03-28 13:45:30 GET /index.html 200 15\n")
+	if _, err := IngestFile(ctx, s, "u_ex1.log", path, FileOptions{}); err != nil {
+		t.Fatalf("IngestFile: %v", err)
+	}
+	appendFile(t, path, "2024-03-28 13:45:31 GET /missing 404 3\n")
+	if n, err := IngestFile(ctx, s, "u_ex1.log", path, FileOptions{}); err != nil || n != 1 {
+		t.Fatalf("IngestFile resume: inserted %d, err %v", n, err)
+	}
+
+	entries := sourceEntries(t, s, "u_ex1.log")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if appended := entries[2]; appended.Attrs["status"] != "404" || appended.Timestamp.IsZero() {
+		t.Errorf("resumed W3C line not parsed: %+v", appended)
+	}
+}
+
+func TestIngestFileCSVRecords(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "jobs.csv")
+
+	csvParser, err := event.NewCSVParser(event.CSVConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := event.NewRegistry()
+	if err := registry.Register("csv", csvParser); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.SetChain("csv", event.ParserPlain); err != nil {
+		t.Fatal(err)
+	}
+
+	appendFile(t, path, "Time,Level,Content\n"+
+		"2024-03-16 10:00:00,ERROR,\"job failed:\n  exit 1\"\n"+
+		"2024-03-16 10:00:01,INFO,job done\n")
+	if _, err := IngestFile(ctx, s, "jobs.csv", path, FileOptions{Parsers: registry, QuotedRecords: true}); err != nil {
+		t.Fatalf("IngestFile: %v", err)
+	}
+
+	entries := sourceEntries(t, s, "jobs.csv")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	failed := entries[1]
+	if failed.LineNumber != 2 || failed.EndLineNumber != 3 {
+		t.Errorf("expected the quoted record to span lines 2-3, got %d-%d", failed.LineNumber, failed.EndLineNumber)
+	}
+	if failed.Message != "job failed:\n  exit 1" || failed.Attrs["level"] != "error" {
+		t.Errorf("CSV record not parsed: message %q, attrs %v", failed.Message, failed.Attrs)
+	}
+}
+
+func TestIngestFileResumesCSVHeader(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "jobs.csv")
+
+	csvParser, err := event.NewCSVParser(event.CSVConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := event.NewRegistry()
+	if err := registry.Register("csv", csvParser); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.SetChain("csv", event.ParserPlain); err != nil {
+		t.Fatal(err)
+	}
+	opts := FileOptions{Parsers: registry, QuotedRecords: true}
+
+	appendFile(t, path, "Time,Level,Content\n2024-03-16 10:00:00,INFO,first\n")
+	if _, err := IngestFile(ctx, s, "jobs.csv", path, opts); err != nil {
+		t.Fatalf("IngestFile: %v", err)
+	}
+	appendFile(t, path, "2024-03-16 10:00:01,WARN,second\n2024-03-16 10:00:02,ERROR,third\n")
+	if n, err := IngestFile(ctx, s, "jobs.csv", path, opts); err != nil || n != 2 {
+		t.Fatalf("IngestFile resume: inserted %d, err %v", n, err)
+	}
+
+	entries := sourceEntries(t, s, "jobs.csv")
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+	for i, want := range []struct{ level, message string }{{"warn", "second"}, {"error", "third"}} {
+		if e := entries[2+i]; e.Attrs["level"] != want.level || e.Message != want.message {
+			t.Errorf("resumed record %d not parsed with the header: message %q, attrs %v", i, e.Message, e.Attrs)
+		}
+	}
+}
@@ -16,6 +16,13 @@ type ParseResult struct {
 	Attrs     map[string]string
 	Inferred  *event.Inferred
 	Labels    map[string]string
+	// Message is the part of the line to mine patterns from; empty means
+	// the whole line.
+	Message string
+	// MessageKey is the attr Message was taken from, if any.
+	MessageKey string
+	// Values holds the typed values of the Attrs the source typed.
+	Values map[string]event.Value
 }
 
 // Parser extracts structured data from a raw log line.
@@ -51,7 +58,19 @@ func FromRawLine(ctx context.Context, line *logsource.LogLine, parser Parser) (O
 		},
 	}
 
+	// Source-level metadata (e.g. container stream and runtime timestamp)
+	// seeds the event; parser output takes precedence below.
+	if line.Timestamp != nil {
+		ts := *line.Timestamp
+		outcome.Event.Timestamp = &ts
+		outcome.LogEntry.Timestamp = ts
+	}
+	for key, value := range line.Attrs {
+		outcome.Event.Attrs[key] = value
+	}
+
 	if parser == nil {
+		outcome.LogEntry.Attrs = cloneMap(outcome.Event.Attrs)
 		return outcome, nil
 	}
 
@@ -64,8 +83,8 @@ func FromRawLine(ctx context.Context, line *logsource.LogLine, parser Parser) (O
 			outcome.Event.Timestamp = &ts
 			outcome.LogEntry.Timestamp = ts
 		}
-		if parsed.Attrs != nil {
-			outcome.Event.Attrs = cloneMap(parsed.Attrs)
+		for key, value := range parsed.Attrs {
+			outcome.Event.Attrs[key] = value
 		}
 		if parsed.Inferred != nil {
 			outcome.Event.Inferred = &event.Inferred{
@@ -76,7 +95,16 @@ func FromRawLine(ctx context.Context, line *logsource.LogLine, parser Parser) (O
 		if parsed.Labels != nil {
 			outcome.LogEntry.Labels = cloneMap(parsed.Labels)
 		}
+		outcome.LogEntry.Message = parsed.Message
+		outcome.LogEntry.MessageKey = parsed.MessageKey
+		if len(parsed.Values) > 0 {
+			outcome.LogEntry.Values = make(map[string]any, len(parsed.Values))
+			for key, value := range parsed.Values {
+				outcome.LogEntry.Values[key] = value.Any()
+			}
+		}
 	}
+	outcome.LogEntry.Attrs = cloneMap(outcome.Event.Attrs)
 
 	return outcome, nil
 }
@@ -166,3 +166,35 @@ func TestStoreRawLineUsesParsedFieldsButKeepsRawText(t *testing.T) {
 		t.Fatalf("stored labels[pattern]: got %q, want %q", stored[0].Labels["pattern"], "user-authenticated")
 	}
 }
+
+func TestFromRawLineSeedsEventFromSourceMetadata(t *testing.T) {
+	ts := time.Date(2026, 3, 10, 21, 0, 0, 0, time.UTC)
+	line := &logsource.LogLine{
+		LineNumber: 4,
+		Content:    "worker stalled",
+		Timestamp:  &ts,
+		Attrs:      map[string]string{"stream": "stderr"},
+	}
+
+	outcome, err := FromRawLine(context.Background(), line, stubParser{
+		parse: func(context.Context, string) (*ParseResult, error) {
+			return &ParseResult{Attrs: map[string]string{"level": "warn"}}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("FromRawLine: %v", err)
+	}
+
+	if outcome.Event.Timestamp == nil || !outcome.Event.Timestamp.Equal(ts) {
+		t.Fatalf("Event.Timestamp: got %v, want %v", outcome.Event.Timestamp, ts)
+	}
+	if outcome.LogEntry.Timestamp != ts {
+		t.Fatalf("LogEntry.Timestamp: got %v, want %v", outcome.LogEntry.Timestamp, ts)
+	}
+	if outcome.Event.Attrs["stream"] != "stderr" {
+		t.Fatalf("Event.Attrs[stream]: got %q, want %q", outcome.Event.Attrs["stream"], "stderr")
+	}
+	if outcome.Event.Attrs["level"] != "warn" {
+		t.Fatalf("Event.Attrs[level]: got %q, want %q", outcome.Event.Attrs["level"], "warn")
+	}
+}
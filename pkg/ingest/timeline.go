@@ -0,0 +1,61 @@
+package ingest
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/strrl/lapp/pkg/logsource"
+	"github.com/strrl/lapp/pkg/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// BuildTimeline merges the stored entries of sources into a single timeline
+// ordered by timestamp (see logsource.MergeTimeline), records each entry's
+// position as its TimelineSeq and returns the entries in timeline order.
+func BuildTimeline(ctx context.Context, st store.Store, sources []string, cfg logsource.TimelineConfig) ([]store.LogEntry, error) {
+	ctx, span := otel.Tracer("lapp/ingest").Start(ctx, "ingest.BuildTimeline")
+	defer span.End()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	inputs := make([]<-chan logsource.Result[store.LogEntry], len(sources))
+	total := 0
+	for i, source := range sources {
+		entries, err := st.QueryLogs(ctx, store.QueryOpts{Source: source})
+		if err != nil {
+			return nil, errors.Errorf("load %s: %w", source, err)
+		}
+		ch := make(chan logsource.Result[store.LogEntry], len(entries))
+		for _, e := range entries {
+			ch <- logsource.Result[store.LogEntry]{Value: e}
+		}
+		close(ch)
+		inputs[i] = ch
+		total += len(entries)
+	}
+
+	timeOf := func(e store.LogEntry) (time.Time, bool) {
+		return e.Timestamp, !e.Timestamp.IsZero()
+	}
+	timeline := make([]store.LogEntry, 0, total)
+	ids := make([]int64, 0, total)
+	for r := range logsource.MergeTimeline(ctx, inputs, timeOf, cfg) {
+		if r.Err != nil {
+			return nil, errors.Errorf("merge timeline: %w", r.Err)
+		}
+		e := r.Value.Value
+		e.TimelineSeq = r.Value.Seq
+		timeline = append(timeline, e)
+		ids = append(ids, e.ID)
+	}
+
+	if err := st.SetTimelineOrder(ctx, ids); err != nil {
+		return nil, errors.Errorf("store timeline: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("timeline.sources", len(sources)), attribute.Int("timeline.entries", len(timeline)))
+	return timeline, nil
+}
@@ -0,0 +1,56 @@
+package ingest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/strrl/lapp/pkg/logsource"
+	"github.com/strrl/lapp/pkg/store"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	dir := t.TempDir()
+
+	appendFile(t, filepath.Join(dir, "api.log"),
+		"2024-03-16 10:00:00 INFO request received\n"+
+			"2024-03-16 10:00:03 ERROR upstream failed\n"+
+			"  at client.go:42\n")
+	appendFile(t, filepath.Join(dir, "worker.log"),
+		`{"ts":"2024-03-16T10:00:01Z","msg":"job picked"}`+"\n"+
+			`{"ts":"2024-03-16T10:00:04Z","msg":"job retried"}`+"\n")
+	for _, name := range []string{"api.log", "worker.log"} {
+		if _, err := IngestFile(ctx, s, name, filepath.Join(dir, name), FileOptions{}); err != nil {
+			t.Fatalf("IngestFile %s: %v", name, err)
+		}
+	}
+
+	timeline, err := BuildTimeline(ctx, s, []string{"api.log", "worker.log"}, logsource.TimelineConfig{})
+	if err != nil {
+		t.Fatalf("BuildTimeline: %v", err)
+	}
+	want := []struct {
+		source string
+		line   int
+	}{{"api.log", 1}, {"worker.log", 1}, {"api.log", 2}, {"worker.log", 2}}
+	if len(timeline) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(timeline))
+	}
+	for i, w := range want {
+		if timeline[i].Source != w.source || timeline[i].LineNumber != w.line {
+			t.Errorf("entry %d: got %s:%d, want %s:%d", i, timeline[i].Source, timeline[i].LineNumber, w.source, w.line)
+		}
+	}
+
+	stored, err := s.QueryLogs(ctx, store.QueryOpts{Timeline: true})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	for i, e := range stored {
+		if e.TimelineSeq != int64(i) || e.Source != timeline[i].Source || e.LineNumber != timeline[i].LineNumber {
+			t.Errorf("stored %d: got %s:%d seq %d", i, e.Source, e.LineNumber, e.TimelineSeq)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package logsource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"github.com/go-errors/errors"
+)
+
+// fingerprintBytes is how much of a file's head the fingerprint covers.
+const fingerprintBytes = 1024
+
+// Position is where file ingestion starts: a byte offset at a line boundary
+// and the number of lines before it.
+type Position struct {
+	Offset     int64
+	LineNumber int
+}
+
+// FileIdentity identifies a file across appends. Device and Inode are zero
+// on platforms without them.
+type FileIdentity struct {
+	Device uint64
+	Inode  uint64
+	Size   int64
+}
+
+// StatFileIdentity returns the identity of the file at path.
+func StatFileIdentity(path string) (FileIdentity, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileIdentity{}, errors.Errorf("stat log file: %w", err)
+	}
+	device, inode := fileDeviceInode(info)
+	return FileIdentity{Device: device, Inode: inode, Size: info.Size()}, nil
+}
+
+// Fingerprint hashes the first min(limit, 1KB) bytes of the file at path.
+// Limiting it to what has already been ingested keeps the fingerprint
+// stable while a short file grows.
+func Fingerprint(path string, limit int64) (string, error) {
+	limit = min(limit, fingerprintBytes)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.Errorf("open log file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, limit); err != nil && !errors.Is(err, io.EOF) {
+		return "", errors.Errorf("fingerprint log file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Checkpoint records how far a file has been ingested, together with what
+// is needed to recognize the same file later. It is persisted by the
+// workspace store (see store.Checkpoint).
+type Checkpoint struct {
+	FileIdentity
+	// Fingerprint covers the first min(Offset, 1KB) bytes of the file.
+	Fingerprint string
+	Position
+}
+
+// NewCheckpoint records that the file at path has been ingested up to pos.
+func NewCheckpoint(path string, pos Position) (Checkpoint, error) {
+	id, err := StatFileIdentity(path)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	fp, err := Fingerprint(path, pos.Offset)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	return Checkpoint{FileIdentity: id, Fingerprint: fp, Position: pos}, nil
+}
+
+// Resume decides where ingestion of path continues given its last
+// checkpoint (nil if none). restart reports that the file was replaced or
+// truncated since cp was taken, so entries ingested from it before must be
+// discarded and reading starts over.
+func Resume(path string, cp *Checkpoint) (pos Position, restart bool, err error) {
+	if cp == nil {
+		return Position{}, false, nil
+	}
+
+	id, err := StatFileIdentity(path)
+	if err != nil {
+		return Position{}, false, err
+	}
+	if id.Device != cp.Device || id.Inode != cp.Inode || id.Size < cp.Offset {
+		return Position{}, true, nil
+	}
+
+	fp, err := Fingerprint(path, cp.Offset)
+	if err != nil {
+		return Position{}, false, err
+	}
+	if fp != cp.Fingerprint {
+		return Position{}, true, nil
+	}
+	return cp.Position, false, nil
+}
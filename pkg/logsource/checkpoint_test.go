@@ -0,0 +1,93 @@
+package logsource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func collectLines(t *testing.T, ch <-chan Result[*LogLine]) []*LogLine {
+	t.Helper()
+
+	var got []*LogLine
+	for rr := range ch {
+		if rr.Err != nil {
+			t.Fatalf("unexpected error: %v", rr.Err)
+		}
+		got = append(got, rr.Value)
+	}
+	return got
+}
+
+func TestIngestFromResumesAtOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("first\r\nsecond\nthird\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	ch, err := Ingest(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	all := collectLines(t, ch)
+	if len(all) != 3 || all[0].Offset != 7 || all[1].Offset != 14 || all[2].Offset != 20 {
+		t.Fatalf("offsets: got %+v", all)
+	}
+
+	ch, err = IngestFrom(context.Background(), path, Position{Offset: all[0].Offset, LineNumber: 1})
+	if err != nil {
+		t.Fatalf("IngestFrom: %v", err)
+	}
+	rest := collectLines(t, ch)
+	if len(rest) != 2 || rest[0].Content != "second" || rest[0].LineNumber != 2 || rest[1].Offset != 20 {
+		t.Fatalf("resumed lines: got %+v", rest)
+	}
+}
+
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	pos, restart, err := Resume(path, nil)
+	if err != nil || restart || pos != (Position{}) {
+		t.Fatalf("no checkpoint: got %+v %v %v", pos, restart, err)
+	}
+
+	cp, err := NewCheckpoint(path, Position{Offset: 8, LineNumber: 2})
+	if err != nil {
+		t.Fatalf("NewCheckpoint: %v", err)
+	}
+
+	// Appending keeps the identity and resumes after the checkpoint.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, _ = f.WriteString("three\n")
+	_ = f.Close()
+
+	pos, restart, err = Resume(path, &cp)
+	if err != nil || restart || pos != cp.Position {
+		t.Fatalf("appended: got %+v %v %v", pos, restart, err)
+	}
+
+	// Rewriting the head in place is detected by the fingerprint.
+	if err := os.WriteFile(path, []byte("ONE\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if _, restart, _ := Resume(path, &cp); !restart {
+		t.Error("expected restart after rewrite")
+	}
+
+	// Truncation is detected by size.
+	if err := os.WriteFile(path, []byte("one\n"), 0o644); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	if _, restart, _ := Resume(path, &cp); !restart {
+		t.Error("expected restart after truncation")
+	}
+}
@@ -0,0 +1,238 @@
+package logsource
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ContainerFormat identifies the runtime envelope wrapping each log line.
+type ContainerFormat string
+
+const (
+	// ContainerFormatNone means lines are passed through untouched.
+	ContainerFormatNone ContainerFormat = ""
+	// ContainerFormatDocker is the Docker json-file driver format:
+	// {"log":"...\n","stream":"stdout","time":"..."}.
+	ContainerFormatDocker ContainerFormat = "docker"
+	// ContainerFormatCRI is the Kubernetes CRI format:
+	// <RFC3339Nano> <stream> <P|F> <message>.
+	ContainerFormatCRI ContainerFormat = "cri"
+)
+
+// containerRecord is one decoded envelope before partial lines are joined.
+type containerRecord struct {
+	stream    string
+	timestamp time.Time
+	message   string
+	partial   bool
+}
+
+type dockerEnvelope struct {
+	Log    *string `json:"log"`
+	Stream string  `json:"stream"`
+	Time   string  `json:"time"`
+}
+
+// DetectContainerFormat reports which container envelope, if any, wraps line.
+func DetectContainerFormat(line string) ContainerFormat {
+	if _, ok := decodeDocker(line); ok {
+		return ContainerFormatDocker
+	}
+	if _, ok := decodeCRI(line); ok {
+		return ContainerFormatCRI
+	}
+	return ContainerFormatNone
+}
+
+func decodeDocker(line string) (containerRecord, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return containerRecord{}, false
+	}
+
+	var env dockerEnvelope
+	if err := json.Unmarshal([]byte(trimmed), &env); err != nil || env.Log == nil || env.Stream == "" {
+		return containerRecord{}, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, env.Time)
+	if err != nil {
+		return containerRecord{}, false
+	}
+
+	// The json-file driver splits long lines into 16KB chunks; only the
+	// final chunk carries the trailing newline.
+	msg, complete := strings.CutSuffix(*env.Log, "\n")
+	return containerRecord{
+		stream:    env.Stream,
+		timestamp: ts,
+		message:   strings.TrimSuffix(msg, "\r"),
+		partial:   !complete,
+	}, true
+}
+
+func decodeCRI(line string) (containerRecord, bool) {
+	rawTS, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return containerRecord{}, false
+	}
+	stream, rest, ok := strings.Cut(rest, " ")
+	if !ok || (stream != "stdout" && stream != "stderr") {
+		return containerRecord{}, false
+	}
+	tag, msg, _ := strings.Cut(rest, " ")
+	// The tag may carry further ':'-separated flags; the first one is P or F.
+	tag, _, _ = strings.Cut(tag, ":")
+	if tag != "P" && tag != "F" {
+		return containerRecord{}, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, rawTS)
+	if err != nil {
+		return containerRecord{}, false
+	}
+	return containerRecord{
+		stream:    stream,
+		timestamp: ts,
+		message:   msg,
+		partial:   tag == "P",
+	}, true
+}
+
+// pendingLine accumulates partial chunks for one output stream.
+type pendingLine struct {
+	line    *LogLine
+	builder strings.Builder
+}
+
+// containerDecoder unwraps envelopes and joins partial lines per stream.
+type containerDecoder struct {
+	format   ContainerFormat
+	detected bool
+	pending  map[string]*pendingLine
+}
+
+func newContainerDecoder() *containerDecoder {
+	return &containerDecoder{pending: make(map[string]*pendingLine)}
+}
+
+// decode consumes one physical line and returns the lines that are complete.
+func (d *containerDecoder) decode(line *LogLine) []*LogLine {
+	if !d.detected {
+		if strings.TrimSpace(line.Content) == "" {
+			return []*LogLine{line}
+		}
+		d.format = DetectContainerFormat(line.Content)
+		d.detected = true
+	}
+
+	var (
+		rec containerRecord
+		ok  bool
+	)
+	switch d.format {
+	case ContainerFormatDocker:
+		rec, ok = decodeDocker(line.Content)
+	case ContainerFormatCRI:
+		rec, ok = decodeCRI(line.Content)
+	case ContainerFormatNone:
+	}
+	if !ok {
+		// Not an envelope: emit whatever was pending, then the line verbatim.
+		return append(d.flush(), line)
+	}
+
+	p, exists := d.pending[rec.stream]
+	if !exists {
+		ts := rec.timestamp
+		attrs := cloneAttrs(line.Attrs)
+		attrs["stream"] = rec.stream
+		p = &pendingLine{line: &LogLine{
+			LineNumber: line.LineNumber,
+			Timestamp:  &ts,
+			Attrs:      attrs,
+		}}
+	}
+	p.builder.WriteString(rec.message)
+	p.line.Offset = line.Offset
+
+	if rec.partial {
+		d.pending[rec.stream] = p
+		return nil
+	}
+	delete(d.pending, rec.stream)
+	p.line.Content = p.builder.String()
+	return []*LogLine{p.line}
+}
+
+// flush returns all pending partial lines in input order.
+func (d *containerDecoder) flush() []*LogLine {
+	if len(d.pending) == 0 {
+		return nil
+	}
+	out := make([]*LogLine, 0, len(d.pending))
+	for stream, p := range d.pending {
+		p.line.Content = p.builder.String()
+		out = append(out, p.line)
+		delete(d.pending, stream)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LineNumber < out[j].LineNumber })
+	return out
+}
+
+func cloneAttrs(src map[string]string) map[string]string {
+	attrs := make(map[string]string, len(src)+1)
+	for k, v := range src {
+		attrs[k] = v
+	}
+	return attrs
+}
+
+// DecodeContainer unwraps Docker json-file and Kubernetes CRI envelopes.
+// The format is detected from the first non-empty line; input that is not
+// wrapped passes through unchanged. Partial lines (CRI "P" tags, Docker
+// chunks without a trailing newline) are joined per stream, and the stream
+// and runtime timestamp are exposed via LogLine.Attrs and LogLine.Timestamp.
+// A joined line keeps the line number of its first chunk and the offset of
+// its last.
+func DecodeContainer(ctx context.Context, in <-chan Result[*LogLine]) <-chan Result[*LogLine] {
+	_, span := otel.Tracer("lapp/logsource").Start(ctx, "logsource.DecodeContainer")
+
+	out := make(chan Result[*LogLine], 100)
+	go func() {
+		defer close(out)
+		defer span.End()
+
+		dec := newContainerDecoder()
+		emit := func(lines []*LogLine) bool {
+			for _, l := range lines {
+				select {
+				case out <- Result[*LogLine]{Value: l}:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		for rr := range in {
+			if rr.Err != nil {
+				select {
+				case out <- rr:
+				case <-ctx.Done():
+				}
+				return
+			}
+			if !emit(dec.decode(rr.Value)) {
+				return
+			}
+		}
+		emit(dec.flush())
+		span.SetAttributes(attribute.String("container.format", string(dec.format)))
+	}()
+	return out
+}
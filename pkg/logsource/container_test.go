@@ -0,0 +1,128 @@
+package logsource
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func decodeAll(t *testing.T, lines []string) []LogLine {
+	t.Helper()
+
+	in := make(chan Result[*LogLine], len(lines))
+	for i, l := range lines {
+		in <- Result[*LogLine]{Value: &LogLine{LineNumber: i + 1, Content: l}}
+	}
+	close(in)
+
+	var got []LogLine
+	for rr := range DecodeContainer(context.Background(), in) {
+		if rr.Err != nil {
+			t.Fatalf("unexpected error: %v", rr.Err)
+		}
+		got = append(got, *rr.Value)
+	}
+	return got
+}
+
+func TestDetectContainerFormat(t *testing.T) {
+	tests := []struct {
+		line string
+		want ContainerFormat
+	}{
+		{`{"log":"hello\n","stream":"stdout","time":"2024-03-28T13:45:30.123456789Z"}`, ContainerFormatDocker},
+		{"2024-03-28T13:45:30.123456789Z stderr F hello", ContainerFormatCRI},
+		{"2024-03-28T13:45:30.123456789+00:00 stdout P part", ContainerFormatCRI},
+		{`{"level":"info","msg":"hello"}`, ContainerFormatNone},
+		{"2024-03-28 13:45:30 INFO hello", ContainerFormatNone},
+		{"", ContainerFormatNone},
+	}
+
+	for _, tt := range tests {
+		if got := DetectContainerFormat(tt.line); got != tt.want {
+			t.Errorf("DetectContainerFormat(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeContainerDocker(t *testing.T) {
+	got := decodeAll(t, []string{
+		`{"log":"2024-03-28 13:45:30 INFO started\n","stream":"stdout","time":"2024-03-28T13:45:30.000000001Z"}`,
+		`{"log":"first half ","stream":"stderr","time":"2024-03-28T13:45:31Z"}`,
+		`{"log":"interleaved\n","stream":"stdout","time":"2024-03-28T13:45:31.5Z"}`,
+		`{"log":"second half\n","stream":"stderr","time":"2024-03-28T13:45:32Z"}`,
+	})
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %+v", len(got), got)
+	}
+	if got[0].Content != "2024-03-28 13:45:30 INFO started" {
+		t.Errorf("line 0: got %q", got[0].Content)
+	}
+	if got[0].Attrs["stream"] != "stdout" {
+		t.Errorf("line 0 stream: got %q", got[0].Attrs["stream"])
+	}
+	want := time.Date(2024, 3, 28, 13, 45, 30, 1, time.UTC)
+	if got[0].Timestamp == nil || !got[0].Timestamp.Equal(want) {
+		t.Errorf("line 0 timestamp: got %v, want %v", got[0].Timestamp, want)
+	}
+	if got[1].Content != "interleaved" || got[1].LineNumber != 3 {
+		t.Errorf("line 1: got %d %q", got[1].LineNumber, got[1].Content)
+	}
+	if got[2].Content != "first half second half" || got[2].LineNumber != 2 {
+		t.Errorf("line 2: got %d %q", got[2].LineNumber, got[2].Content)
+	}
+	if got[2].Attrs["stream"] != "stderr" {
+		t.Errorf("line 2 stream: got %q", got[2].Attrs["stream"])
+	}
+}
+
+func TestDecodeContainerCRI(t *testing.T) {
+	got := decodeAll(t, []string{
+		"2024-03-28T13:45:30.1Z stdout P java.lang.IllegalStateException: ",
+		"2024-03-28T13:45:30.2Z stdout F boom",
+		"2024-03-28T13:45:30.3Z stdout F \tat com.example.Foo.bar(Foo.java:42)",
+		"2024-03-28T13:45:30.4Z stderr F",
+		"2024-03-28T13:45:30.5Z stdout P dangling",
+	})
+
+	want := []struct {
+		line    int
+		content string
+		stream  string
+	}{
+		{1, "java.lang.IllegalStateException: boom", "stdout"},
+		{3, "\tat com.example.Foo.bar(Foo.java:42)", "stdout"},
+		{4, "", "stderr"},
+		{5, "dangling", "stdout"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d lines, got %d: %+v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		if got[i].LineNumber != w.line || got[i].Content != w.content || got[i].Attrs["stream"] != w.stream {
+			t.Errorf("line %d: got (%d, %q, %q), want (%d, %q, %q)",
+				i, got[i].LineNumber, got[i].Content, got[i].Attrs["stream"], w.line, w.content, w.stream)
+		}
+	}
+}
+
+func TestDecodeContainerPassThrough(t *testing.T) {
+	lines := []string{
+		"2024-03-28 13:45:30 INFO plain",
+		`{"log":"looks like docker\n","stream":"stdout","time":"2024-03-28T13:45:30Z"}`,
+	}
+
+	got := decodeAll(t, lines)
+	if len(got) != len(lines) {
+		t.Fatalf("expected %d lines, got %d", len(lines), len(got))
+	}
+	for i, l := range got {
+		if l.Content != lines[i] {
+			t.Errorf("line %d: got %q, want %q", i, l.Content, lines[i])
+		}
+		if l.Timestamp != nil || l.Attrs != nil {
+			t.Errorf("line %d: expected no source metadata, got %v %v", i, l.Timestamp, l.Attrs)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package logsource
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/go-errors/errors"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// DefaultFallbackCharset is assumed for files that carry no BOM and are not
+// UTF-8. Every byte sequence is valid ISO-8859-1, so nothing is lost.
+const DefaultFallbackCharset = "ISO-8859-1"
+
+// encodingSniffBytes is how much of a file's head encoding detection reads.
+const encodingSniffBytes = 64 * 1024
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// textDecoder splits a file into lines in its source encoding and transcodes
+// each line to UTF-8, so byte offsets keep referring to the source file.
+type textDecoder struct {
+	name   string
+	bomLen int
+	split  bufio.SplitFunc
+	// decode returns the UTF-8 text of one line and how many invalid
+	// sequences were replaced with U+FFFD.
+	decode func([]byte) (string, int)
+}
+
+// detectEncoding chooses the decoder for a file from its head: a BOM wins,
+// then UTF-16 recognized by its NUL bytes, then UTF-8 if the head is
+// (mostly) valid UTF-8, and finally the fallback charset. truncated reports
+// that head was cut short of the end of the file.
+func detectEncoding(head []byte, truncated bool, fallback string) (*textDecoder, error) {
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		return utf8Decoder(len(bomUTF8)), nil
+	case bytes.HasPrefix(head, bomUTF16LE):
+		return utf16Decoder(false, len(bomUTF16LE)), nil
+	case bytes.HasPrefix(head, bomUTF16BE):
+		return utf16Decoder(true, len(bomUTF16BE)), nil
+	}
+
+	if bigEndian, ok := sniffUTF16(head); ok {
+		return utf16Decoder(bigEndian, 0), nil
+	}
+	if looksUTF8(head, truncated) {
+		return utf8Decoder(0), nil
+	}
+	return charsetDecoder(fallback)
+}
+
+// sniffUTF16 recognizes BOM-less UTF-16 by the NUL high bytes that mostly
+// ASCII text has in every other position.
+func sniffUTF16(head []byte) (bigEndian, ok bool) {
+	n := min(len(head), 1024) &^ 1
+	if n < 4 {
+		return false, false
+	}
+	var evenNUL, oddNUL int
+	for i := 0; i < n; i += 2 {
+		if head[i] == 0 {
+			evenNUL++
+		}
+		if head[i+1] == 0 {
+			oddNUL++
+		}
+	}
+	units := n / 2
+	switch {
+	case oddNUL*10 >= units*7 && evenNUL*10 < units:
+		return false, true
+	case evenNUL*10 >= units*7 && oddNUL*10 < units:
+		return true, true
+	}
+	return false, false
+}
+
+// looksUTF8 reports whether head is UTF-8: either fully valid, or with more
+// valid multi-byte characters than invalid sequences (a few corrupt bytes in
+// an otherwise UTF-8 log should not switch the whole file to a legacy
+// charset).
+func looksUTF8(head []byte, truncated bool) bool {
+	if truncated {
+		// Ignore a multi-byte character cut by the sniff limit.
+		for i := len(head) - 1; i >= 0 && i >= len(head)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(head[i]) {
+				if !utf8.FullRune(head[i:]) {
+					head = head[:i]
+				}
+				break
+			}
+		}
+	}
+	if utf8.Valid(head) {
+		return true
+	}
+
+	var multiByte, invalid int
+	for len(head) > 0 {
+		r, size := utf8.DecodeRune(head)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			invalid++
+		case size > 1:
+			multiByte++
+		}
+		head = head[size:]
+	}
+	return multiByte > invalid
+}
+
+func utf8Decoder(bomLen int) *textDecoder {
+	return &textDecoder{name: "UTF-8", bomLen: bomLen, split: bufio.ScanLines, decode: decodeUTF8}
+}
+
+// decodeUTF8 replaces each run of invalid bytes with one U+FFFD.
+func decodeUTF8(b []byte) (string, int) {
+	if utf8.Valid(b) {
+		return string(b), 0
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(b))
+	replaced := 0
+	inInvalid := false
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			if !inInvalid {
+				sb.WriteRune(utf8.RuneError)
+				replaced++
+				inInvalid = true
+			}
+		} else {
+			sb.WriteRune(r)
+			inInvalid = false
+		}
+		b = b[size:]
+	}
+	return sb.String(), replaced
+}
+
+func utf16Decoder(bigEndian bool, bomLen int) *textDecoder {
+	name := "UTF-16LE"
+	if bigEndian {
+		name = "UTF-16BE"
+	}
+	return &textDecoder{
+		name:   name,
+		bomLen: bomLen,
+		split:  scanUTF16Lines(bigEndian),
+		decode: func(b []byte) (string, int) { return decodeUTF16(b, bigEndian) },
+	}
+}
+
+func utf16Unit(b []byte, bigEndian bool) uint16 {
+	if bigEndian {
+		return uint16(b[0])<<8 | uint16(b[1])
+	}
+	return uint16(b[1])<<8 | uint16(b[0])
+}
+
+// scanUTF16Lines is bufio.ScanLines for UTF-16: it splits on newline code
+// units and drops a trailing carriage return unit.
+func scanUTF16Lines(bigEndian bool) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		for i := 0; i+1 < len(data); i += 2 {
+			if utf16Unit(data[i:], bigEndian) != '\n' {
+				continue
+			}
+			line := data[:i]
+			if n := len(line); n >= 2 && utf16Unit(line[n-2:], bigEndian) == '\r' {
+				line = line[:n-2]
+			}
+			return i + 2, line, nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// decodeUTF16 transcodes one line, replacing unpaired surrogates and a
+// dangling odd byte with U+FFFD.
+func decodeUTF16(b []byte, bigEndian bool) (string, int) {
+	var sb strings.Builder
+	sb.Grow(len(b) / 2)
+	replaced := 0
+	for len(b) >= 2 {
+		u := utf16Unit(b, bigEndian)
+		b = b[2:]
+		switch {
+		case utf16.IsSurrogate(rune(u)):
+			if u < 0xDC00 && len(b) >= 2 {
+				if r := utf16.DecodeRune(rune(u), rune(utf16Unit(b, bigEndian))); r != utf8.RuneError {
+					sb.WriteRune(r)
+					b = b[2:]
+					continue
+				}
+			}
+			sb.WriteRune(utf8.RuneError)
+			replaced++
+		default:
+			sb.WriteRune(rune(u))
+		}
+	}
+	if len(b) == 1 {
+		sb.WriteRune(utf8.RuneError)
+		replaced++
+	}
+	return sb.String(), replaced
+}
+
+// charsetDecoder transcodes from a named legacy charset (IANA or WHATWG
+// name, e.g. "latin1", "windows-1252", "shift_jis"). Such charsets keep
+// ASCII newlines intact, so lines are split before transcoding.
+func charsetDecoder(charset string) (*textDecoder, error) {
+	switch strings.ToUpper(charset) {
+	case "":
+		charset = DefaultFallbackCharset
+	case "UTF-16", "UTF-16LE":
+		return utf16Decoder(false, 0), nil
+	case "UTF-16BE":
+		return utf16Decoder(true, 0), nil
+	}
+	enc, err := lookupCharset(charset)
+	if err != nil {
+		return nil, err
+	}
+	if enc == encoding.Nop {
+		return utf8Decoder(0), nil
+	}
+
+	name, _ := ianaindex.MIME.Name(enc)
+	if name == "" {
+		name = charset
+	}
+	dec := enc.NewDecoder()
+	return &textDecoder{
+		name:  name,
+		split: bufio.ScanLines,
+		decode: func(b []byte) (string, int) {
+			out, err := dec.Bytes(b)
+			if err != nil {
+				return decodeUTF8(b)
+			}
+			// Charset decoders map undefined bytes to U+FFFD themselves.
+			return string(out), bytes.Count(out, []byte(string(utf8.RuneError)))
+		},
+	}, nil
+}
+
+func lookupCharset(charset string) (encoding.Encoding, error) {
+	if strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "utf8") {
+		return encoding.Nop, nil
+	}
+	if enc, err := ianaindex.IANA.Encoding(charset); err == nil && enc != nil {
+		return enc, nil
+	}
+	if enc, err := htmlindex.Get(charset); err == nil {
+		return enc, nil
+	}
+	return nil, errors.Errorf("unsupported charset %q", charset)
+}
@@ -0,0 +1,129 @@
+package logsource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+func encodeUTF16LE(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+func encodeUTF16BE(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return b
+}
+
+func TestDetectEncoding(t *testing.T) {
+	for name, tc := range map[string]struct {
+		head     []byte
+		fallback string
+		want     string
+		bomLen   int
+	}{
+		"utf-8 bom":        {head: append([]byte{0xEF, 0xBB, 0xBF}, "hello\n"...), want: "UTF-8", bomLen: 3},
+		"utf-16le bom":     {head: append([]byte{0xFF, 0xFE}, encodeUTF16LE("hello\n")...), want: "UTF-16LE", bomLen: 2},
+		"utf-16be bom":     {head: append([]byte{0xFE, 0xFF}, encodeUTF16BE("hello\n")...), want: "UTF-16BE", bomLen: 2},
+		"utf-16le no bom":  {head: encodeUTF16LE("2024-03-16 INFO started\n"), want: "UTF-16LE"},
+		"utf-16be no bom":  {head: encodeUTF16BE("2024-03-16 INFO started\n"), want: "UTF-16BE"},
+		"plain utf-8":      {head: []byte("Grüße aus Köln\n"), want: "UTF-8"},
+		"mostly utf-8":     {head: []byte("Grüße aus Köln \xff\n"), want: "UTF-8"},
+		"latin-1 default":  {head: []byte("caf\xe9 cr\xe8me\n"), want: "ISO-8859-1"},
+		"windows-1252 set": {head: []byte("price \x80 5\n"), fallback: "windows-1252", want: "windows-1252"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dec, err := detectEncoding(tc.head, false, tc.fallback)
+			if err != nil {
+				t.Fatalf("detectEncoding: %v", err)
+			}
+			if dec.name != tc.want || dec.bomLen != tc.bomLen {
+				t.Errorf("got %s (bom %d), want %s (bom %d)", dec.name, dec.bomLen, tc.want, tc.bomLen)
+			}
+		})
+	}
+
+	if _, err := detectEncoding([]byte("caf\xe9\n"), false, "no-such-charset"); err == nil {
+		t.Error("expected error for unknown charset")
+	}
+}
+
+func TestDecodeReplacesAndCountsInvalidSequences(t *testing.T) {
+	got, n := decodeUTF8([]byte("ok \xff\xfe then \xc3"))
+	if got != "ok � then �" || n != 2 {
+		t.Errorf("decodeUTF8: got %q (%d)", got, n)
+	}
+
+	// A lone high surrogate followed by 'A', then a dangling odd byte.
+	got, n = decodeUTF16([]byte{0x00, 0xD8, 'A', 0x00, 'B'}, false)
+	if got != "�A�" || n != 2 {
+		t.Errorf("decodeUTF16: got %q (%d)", got, n)
+	}
+
+	dec, err := charsetDecoder("latin1")
+	if err != nil {
+		t.Fatalf("charsetDecoder: %v", err)
+	}
+	if got, n := dec.decode([]byte("caf\xe9")); got != "café" || n != 0 {
+		t.Errorf("latin1: got %q (%d)", got, n)
+	}
+}
+
+func TestIngestTranscodesUTF16WithBOM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "windows.log")
+	data := append([]byte{0xFF, 0xFE}, encodeUTF16LE("Dienst gestartet\r\nÜberlauf 𝄞\r\n")...)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	ch, err := Ingest(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	lines := collectLines(t, ch)
+	if len(lines) != 2 || lines[0].Content != "Dienst gestartet" || lines[1].Content != "Überlauf 𝄞" {
+		t.Fatalf("lines: got %+v", lines)
+	}
+	if lines[1].Offset != int64(len(data)) {
+		t.Errorf("offset: got %d, want %d", lines[1].Offset, len(data))
+	}
+
+	// Offsets stay in source bytes, so resuming lands on a line boundary.
+	ch, err = IngestFrom(context.Background(), path, Position{Offset: lines[0].Offset, LineNumber: 1})
+	if err != nil {
+		t.Fatalf("IngestFrom: %v", err)
+	}
+	rest := collectLines(t, ch)
+	if len(rest) != 1 || rest[0].Content != "Überlauf 𝄞" || rest[0].LineNumber != 2 {
+		t.Fatalf("resumed: got %+v", rest)
+	}
+}
+
+func TestIngestWithFallbackCharset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.log")
+	if err := os.WriteFile(path, []byte("caf\xe9 cr\xe8me\nprice \x80 5\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	ch, err := IngestWithOptions(context.Background(), path, FileOptions{FallbackCharset: "windows-1252"})
+	if err != nil {
+		t.Fatalf("IngestWithOptions: %v", err)
+	}
+	lines := collectLines(t, ch)
+	if len(lines) != 2 || lines[0].Content != "café crème" || lines[1].Content != "price € 5" {
+		t.Fatalf("lines: got %+v", lines)
+	}
+
+	if _, err := IngestWithOptions(context.Background(), path, FileOptions{FallbackCharset: "bogus"}); err == nil {
+		t.Error("expected error for unknown charset")
+	}
+}
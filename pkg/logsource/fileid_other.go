@@ -0,0 +1,9 @@
+//go:build !unix
+
+package logsource
+
+import "os"
+
+func fileDeviceInode(os.FileInfo) (uint64, uint64) {
+	return 0, 0
+}
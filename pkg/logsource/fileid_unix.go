@@ -0,0 +1,16 @@
+//go:build unix
+
+package logsource
+
+import (
+	"os"
+	"syscall"
+)
+
+func fileDeviceInode(info os.FileInfo) (uint64, uint64) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0
+	}
+	return uint64(st.Dev), uint64(st.Ino) //nolint:unconvert // Dev is not uint64 on every unix
+}
@@ -0,0 +1,394 @@
+package logsource
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// forwardEventTimeExt is the MessagePack extension type of Fluent EventTime.
+const forwardEventTimeExt = 0
+
+func init() {
+	msgpack.RegisterExt(forwardEventTimeExt, (*ForwardEventTime)(nil))
+}
+
+// ForwardEventTime is the Fluent EventTime extension: big-endian uint32
+// seconds followed by uint32 nanoseconds.
+type ForwardEventTime struct {
+	time.Time
+}
+
+// MarshalMsgpack implements msgpack.Marshaler.
+func (t *ForwardEventTime) MarshalMsgpack() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	return b, nil
+}
+
+// UnmarshalMsgpack implements msgpack.Unmarshaler.
+func (t *ForwardEventTime) UnmarshalMsgpack(b []byte) error {
+	if len(b) != 8 {
+		return errors.Errorf("invalid EventTime length %d", len(b))
+	}
+	t.Time = time.Unix(int64(binary.BigEndian.Uint32(b)), int64(binary.BigEndian.Uint32(b[4:])))
+	return nil
+}
+
+// ForwardConfig configures the Fluent Forward receiver.
+type ForwardConfig struct {
+	// Addr is the TCP listen address. Default: ":24224".
+	Addr string
+}
+
+func (c *ForwardConfig) defaults() {
+	if c.Addr == "" {
+		c.Addr = ":24224"
+	}
+}
+
+var _ ingestor = (*ForwardServer)(nil)
+
+// ForwardServer receives Fluentd / Fluent Bit records over the Forward
+// protocol v1 (Message, Forward, PackedForward and CompressedPackedForward
+// modes). Chunks carrying a "chunk" option are acknowledged once their
+// records have been handed off. Shared-key handshakes are not supported.
+type ForwardServer struct {
+	cfg ForwardConfig
+	ln  net.Listener
+	seq atomic.Int64
+}
+
+// NewForwardServer binds the listen address. Serving starts with Ingest.
+func NewForwardServer(cfg ForwardConfig) (*ForwardServer, error) {
+	cfg.defaults()
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, errors.Errorf("listen forward: %w", err)
+	}
+	return &ForwardServer{cfg: cfg, ln: ln}, nil
+}
+
+// Addr returns the bound listen address.
+func (s *ForwardServer) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Ingest serves until ctx is cancelled and streams one LogLine per record.
+// The Fluent tag names the record's source and is kept as the "source"
+// attr. LineNumber is the 1-based arrival order across connections.
+func (s *ForwardServer) Ingest(ctx context.Context) (<-chan Result[*LogLine], error) {
+	ctx, span := otel.Tracer("lapp/logsource").Start(ctx, "logsource.ForwardServer.Ingest")
+	span.SetAttributes(attribute.String("forward.addr", s.Addr().String()))
+
+	ch := make(chan Result[*LogLine], 100)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.serve(ctx, ch, &wg)
+	}()
+
+	go func() {
+		<-ctx.Done()
+		_ = s.ln.Close()
+	}()
+
+	go func() {
+		wg.Wait()
+		close(ch)
+		span.End()
+	}()
+
+	return ch, nil
+}
+
+// ListenForward binds a ForwardServer and starts serving it.
+func ListenForward(ctx context.Context, cfg ForwardConfig) (<-chan Result[*LogLine], error) {
+	s, err := NewForwardServer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return s.Ingest(ctx)
+}
+
+func (s *ForwardServer) serve(ctx context.Context, ch chan<- Result[*LogLine], wg *sync.WaitGroup) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				select {
+				case ch <- Result[*LogLine]{Err: errors.Errorf("accept forward: %w", err)}:
+				case <-ctx.Done():
+				}
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn, ch)
+		}()
+	}
+}
+
+// serveConn decodes one Forward message at a time from conn. A malformed
+// message closes the connection; the client will reconnect and resend
+// unacknowledged chunks.
+func (s *ForwardServer) serveConn(ctx context.Context, conn net.Conn, ch chan<- Result[*LogLine]) {
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	dec := msgpack.NewDecoder(bufio.NewReaderSize(conn, 64*1024))
+	for {
+		var msg []any
+		if err := dec.Decode(&msg); err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				s.report(ctx, ch, errors.Errorf("decode forward message: %w", err))
+			}
+			return
+		}
+
+		lines, chunk, err := DecodeForwardMessage(msg)
+		if err != nil {
+			s.report(ctx, ch, err)
+			return
+		}
+		for _, line := range lines {
+			line.LineNumber = int(s.seq.Add(1))
+			select {
+			case ch <- Result[*LogLine]{Value: line}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if chunk != "" {
+			ack, err := msgpack.Marshal(map[string]string{"ack": chunk})
+			if err != nil {
+				return
+			}
+			if _, err := conn.Write(ack); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *ForwardServer) report(ctx context.Context, ch chan<- Result[*LogLine], err error) {
+	select {
+	case ch <- Result[*LogLine]{Err: err}:
+	case <-ctx.Done():
+	}
+}
+
+// DecodeForwardMessage decodes one Forward protocol message into lines and
+// returns the chunk ID to acknowledge, if the client asked for one. The mode
+// is told apart by the type of the second element:
+//
+//	Message:        [tag, time, record, option?]
+//	Forward:        [tag, [[time, record], ...], option?]
+//	PackedForward:  [tag, bin(msgpack stream of [time, record]), option?]
+func DecodeForwardMessage(msg []any) ([]*LogLine, string, error) {
+	if len(msg) < 2 {
+		return nil, "", errors.Errorf("forward message has %d elements", len(msg))
+	}
+	tag, ok := msg[0].(string)
+	if !ok {
+		return nil, "", errors.Errorf("forward tag is %T, not a string", msg[0])
+	}
+
+	var (
+		lines   []*LogLine
+		options map[string]any
+		err     error
+	)
+	switch entries := msg[1].(type) {
+	case []any:
+		options = forwardOptions(msg, 2)
+		for _, entry := range entries {
+			pair, ok := entry.([]any)
+			if !ok || len(pair) < 2 {
+				return nil, "", errors.New("forward entry is not a [time, record] pair")
+			}
+			line, err := forwardEntryToLine(tag, pair[0], pair[1])
+			if err != nil {
+				return nil, "", err
+			}
+			lines = append(lines, line)
+		}
+	case []byte:
+		options = forwardOptions(msg, 2)
+		lines, err = decodePackedForward(tag, entries, options)
+	case string:
+		options = forwardOptions(msg, 2)
+		lines, err = decodePackedForward(tag, []byte(entries), options)
+	default:
+		if len(msg) < 3 {
+			return nil, "", errors.New("forward message mode needs a time and a record")
+		}
+		options = forwardOptions(msg, 3)
+		var line *LogLine
+		line, err = forwardEntryToLine(tag, msg[1], msg[2])
+		lines = []*LogLine{line}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	chunk, _ := options["chunk"].(string)
+	return lines, chunk, nil
+}
+
+func forwardOptions(msg []any, i int) map[string]any {
+	if len(msg) <= i {
+		return nil
+	}
+	options, _ := msg[i].(map[string]any)
+	return options
+}
+
+// decodePackedForward decodes the concatenated [time, record] entries of
+// PackedForward mode, gunzipping them first for CompressedPackedForward.
+func decodePackedForward(tag string, packed []byte, options map[string]any) ([]*LogLine, error) {
+	var r io.Reader = bytes.NewReader(packed)
+	if options["compressed"] == "gzip" {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.Errorf("open compressed entries: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	var lines []*LogLine
+	dec := msgpack.NewDecoder(r)
+	for {
+		var pair []any
+		if err := dec.Decode(&pair); err != nil {
+			if errors.Is(err, io.EOF) {
+				return lines, nil
+			}
+			return nil, errors.Errorf("decode packed entry: %w", err)
+		}
+		if len(pair) < 2 {
+			return nil, errors.New("packed entry is not a [time, record] pair")
+		}
+		line, err := forwardEntryToLine(tag, pair[0], pair[1])
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+}
+
+func forwardEntryToLine(tag string, rawTime, rawRecord any) (*LogLine, error) {
+	ts, err := forwardTime(rawTime)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := normalizeMsgpack(rawRecord).(map[string]any)
+	if !ok {
+		return nil, errors.Errorf("forward record is %T, not a map", rawRecord)
+	}
+
+	line := structuredRecordLine(record)
+	if line.Content == "" {
+		// No recognizable message field: keep the whole record as text.
+		raw, err := json.Marshal(record)
+		if err != nil {
+			return nil, errors.Errorf("encode forward record: %w", err)
+		}
+		line.Content = string(raw)
+	}
+	ts = ts.UTC()
+	line.Timestamp = &ts
+	line.Attrs["source"] = tag
+	return line, nil
+}
+
+// forwardTime accepts both EventTime and the legacy integer-seconds form.
+func forwardTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case *ForwardEventTime:
+		return t.Time, nil
+	case ForwardEventTime:
+		return t.Time, nil
+	case float64:
+		sec := int64(t)
+		return time.Unix(sec, int64((t-float64(sec))*1e9)), nil
+	}
+	if sec, ok := msgpackInt(v); ok {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Time{}, errors.Errorf("forward time is %T", v)
+}
+
+func msgpackInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// normalizeMsgpack converts binary strings and non-string map keys, which
+// Fluentd still emits for records, into their JSON-compatible forms.
+func normalizeMsgpack(v any) any {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case map[string]any:
+		for k, val := range t {
+			t[k] = normalizeMsgpack(val)
+		}
+		return t
+	case map[any]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			key, ok := normalizeMsgpack(k).(string)
+			if !ok {
+				b, _ := json.Marshal(k)
+				key = string(b)
+			}
+			out[key] = normalizeMsgpack(val)
+		}
+		return out
+	case []any:
+		for i, val := range t {
+			t[i] = normalizeMsgpack(val)
+		}
+		return t
+	}
+	return v
+}
@@ -0,0 +1,205 @@
+package logsource
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// roundTripForward encodes msg the way a Fluent client would and decodes it
+// back, so the tests see the same types as the server.
+func roundTripForward(t *testing.T, msg []any) []any {
+	t.Helper()
+
+	raw, err := msgpack.Marshal(msg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded []any
+	if err := msgpack.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return decoded
+}
+
+func packForwardEntries(t *testing.T, entries ...[]any) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			t.Fatalf("encode entry: %v", err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeForwardMessageMode(t *testing.T) {
+	ts := time.Date(2026, 3, 10, 21, 0, 0, 123, time.UTC)
+	msg := roundTripForward(t, []any{
+		"app.web",
+		&ForwardEventTime{ts},
+		map[string]any{"log": []byte("GET /health 200"), "container": map[string]any{"name": "web-1"}, "status": 200},
+		map[string]any{"chunk": "c1"},
+	})
+
+	lines, chunk, err := DecodeForwardMessage(msg)
+	if err != nil {
+		t.Fatalf("DecodeForwardMessage: %v", err)
+	}
+	if chunk != "c1" {
+		t.Errorf("chunk: got %q", chunk)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d", len(lines))
+	}
+	line := lines[0]
+	if line.Content != "GET /health 200" {
+		t.Errorf("Content: got %q", line.Content)
+	}
+	if line.Timestamp == nil || !line.Timestamp.Equal(ts) {
+		t.Errorf("Timestamp: got %v, want %v", line.Timestamp, ts)
+	}
+	for key, value := range map[string]string{"source": "app.web", "container.name": "web-1", "status": "200"} {
+		if line.Attrs[key] != value {
+			t.Errorf("attr %s: got %q, want %q", key, line.Attrs[key], value)
+		}
+	}
+}
+
+func TestDecodeForwardForwardMode(t *testing.T) {
+	msg := roundTripForward(t, []any{
+		"batch",
+		[]any{
+			[]any{int64(1710576724), map[string]any{"message": "one"}},
+			[]any{int64(1710576725), map[string]any{"code": "E42"}},
+		},
+	})
+
+	lines, chunk, err := DecodeForwardMessage(msg)
+	if err != nil {
+		t.Fatalf("DecodeForwardMessage: %v", err)
+	}
+	if chunk != "" {
+		t.Errorf("expected no chunk, got %q", chunk)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[0].Content != "one" || !lines[0].Timestamp.Equal(time.Unix(1710576724, 0)) {
+		t.Errorf("line 0: got %q %v", lines[0].Content, lines[0].Timestamp)
+	}
+	if lines[1].Content != `{"code":"E42"}` {
+		t.Errorf("line 1 without message field: got %q", lines[1].Content)
+	}
+}
+
+func TestDecodeForwardPackedModes(t *testing.T) {
+	ts := time.Date(2026, 3, 10, 21, 0, 0, 0, time.UTC)
+	packed := packForwardEntries(t,
+		[]any{&ForwardEventTime{ts}, map[string]any{"msg": "first"}},
+		[]any{&ForwardEventTime{ts.Add(time.Second)}, map[string]any{"msg": "second"}},
+	)
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write(packed)
+	_ = w.Close()
+
+	for name, msg := range map[string][]any{
+		"PackedForward":           {"packed", packed, map[string]any{"size": 2}},
+		"CompressedPackedForward": {"packed", gz.Bytes(), map[string]any{"size": 2, "compressed": "gzip", "chunk": "c2"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			lines, _, err := DecodeForwardMessage(roundTripForward(t, msg))
+			if err != nil {
+				t.Fatalf("DecodeForwardMessage: %v", err)
+			}
+			if len(lines) != 2 || lines[0].Content != "first" || lines[1].Content != "second" {
+				t.Fatalf("unexpected lines: %+v", lines)
+			}
+			if !lines[1].Timestamp.Equal(ts.Add(time.Second)) {
+				t.Errorf("Timestamp: got %v", lines[1].Timestamp)
+			}
+		})
+	}
+}
+
+func TestDecodeForwardMessageErrors(t *testing.T) {
+	for name, msg := range map[string][]any{
+		"too short":      {"tag"},
+		"tag not string": {int64(1), int64(2), map[string]any{}},
+		"bad record":     {"tag", int64(1), "not a map"},
+		"bad entry":      {"tag", []any{"not a pair"}},
+	} {
+		if _, _, err := DecodeForwardMessage(roundTripForward(t, msg)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestForwardServerAck(t *testing.T) {
+	server, err := NewForwardServer(ForwardConfig{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("NewForwardServer: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := server.Ingest(ctx)
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	enc := msgpack.NewEncoder(conn)
+	if err := enc.Encode([]any{"no.ack", int64(1710576724), map[string]any{"message": "plain"}}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := enc.Encode([]any{"with.ack", &ForwardEventTime{time.Now()}, map[string]any{"message": "acked"}, map[string]any{"chunk": "abc123"}}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var ack map[string]string
+	if err := msgpack.NewDecoder(conn).Decode(&ack); err != nil {
+		t.Fatalf("read ack: %v", err)
+	}
+	if ack["ack"] != "abc123" {
+		t.Errorf("ack: got %v", ack)
+	}
+
+	var got []*LogLine
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case rr := <-ch:
+			if rr.Err != nil {
+				t.Fatalf("unexpected error: %v", rr.Err)
+			}
+			got = append(got, rr.Value)
+		case <-timeout:
+			t.Fatalf("timed out after %d lines", len(got))
+		}
+	}
+	if got[0].Content != "plain" || got[0].Attrs["source"] != "no.ack" || got[0].LineNumber != 1 {
+		t.Errorf("line 0: got %d %q %v", got[0].LineNumber, got[0].Content, got[0].Attrs)
+	}
+	if got[1].Content != "acked" || got[1].Attrs["source"] != "with.ack" {
+		t.Errorf("line 1: got %q %v", got[1].Content, got[1].Attrs)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
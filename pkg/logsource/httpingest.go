@@ -0,0 +1,620 @@
+package logsource
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/golang/snappy"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	lokiPushPath = "/loki/api/v1/push"
+	bulkPath     = "/_bulk"
+)
+
+// recordMessageKeys and bulkTimestampKeys are the document fields, in order
+// of preference, used as the message text and the timestamp of a structured
+// record.
+var (
+	recordMessageKeys = []string{"message", "msg", "log", "text"}
+	bulkTimestampKeys = []string{"@timestamp", "timestamp", "time", "ts"}
+)
+
+// HTTPIngestConfig configures the Loki/Elasticsearch compatible receiver.
+type HTTPIngestConfig struct {
+	// Addr is the HTTP listen address. Default: ":3100".
+	Addr string
+	// MaxBodyBytes bounds a single (decompressed) request. Default: 16MB.
+	MaxBodyBytes int64
+}
+
+func (c *HTTPIngestConfig) defaults() {
+	if c.Addr == "" {
+		c.Addr = ":3100"
+	}
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = 16 * 1024 * 1024
+	}
+}
+
+var _ ingestor = (*HTTPIngestServer)(nil)
+
+// HTTPIngestServer implements the ingest side of two widely supported push
+// APIs so existing shippers (Promtail, Fluent Bit, Vector) can target LAPP:
+//
+//   - Loki POST /loki/api/v1/push (JSON, or snappy-compressed protobuf)
+//   - Elasticsearch POST /_bulk and /<index>/_bulk (NDJSON)
+type HTTPIngestServer struct {
+	cfg HTTPIngestConfig
+	ln  net.Listener
+	seq atomic.Int64
+}
+
+// NewHTTPIngestServer binds the listen address. Serving starts with Ingest.
+func NewHTTPIngestServer(cfg HTTPIngestConfig) (*HTTPIngestServer, error) {
+	cfg.defaults()
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, errors.Errorf("listen http ingest: %w", err)
+	}
+	return &HTTPIngestServer{cfg: cfg, ln: ln}, nil
+}
+
+// Addr returns the bound listen address.
+func (s *HTTPIngestServer) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Ingest serves until ctx is cancelled and streams one LogLine per Loki
+// entry or bulk document. LineNumber is the 1-based arrival order.
+func (s *HTTPIngestServer) Ingest(ctx context.Context) (<-chan Result[*LogLine], error) {
+	ctx, span := otel.Tracer("lapp/logsource").Start(ctx, "logsource.HTTPIngestServer.Ingest")
+	span.SetAttributes(attribute.String("http.addr", s.Addr().String()))
+
+	ch := make(chan Result[*LogLine], 100)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+lokiPushPath, func(w http.ResponseWriter, r *http.Request) {
+		s.handleLokiPush(ctx, w, r, ch)
+	})
+	mux.HandleFunc("POST "+bulkPath, func(w http.ResponseWriter, r *http.Request) {
+		s.handleBulk(ctx, w, r, ch, "")
+	})
+	mux.HandleFunc("POST /{index}"+bulkPath, func(w http.ResponseWriter, r *http.Request) {
+		s.handleBulk(ctx, w, r, ch, r.PathValue("index"))
+	})
+	mux.HandleFunc("GET /{$}", handleElasticsearchInfo)
+	mux.HandleFunc("GET /ready", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ready\n"))
+	})
+
+	var handlers handlerGate
+	srv := &http.Server{
+		Handler:           handlers.wrap(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		defer span.End()
+		// Shutdown may time out with handlers still sending, so ch is
+		// closed only once they have all returned.
+		defer close(ch)
+		defer handlers.shutdown()
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- srv.Serve(s.ln) }()
+
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		case err := <-errCh:
+			if !errors.Is(err, http.ErrServerClosed) {
+				select {
+				case ch <- Result[*LogLine]{Err: errors.Errorf("serve http ingest: %w", err)}:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// ListenHTTPIngest binds an HTTPIngestServer and starts serving it.
+func ListenHTTPIngest(ctx context.Context, cfg HTTPIngestConfig) (<-chan Result[*LogLine], error) {
+	s, err := NewHTTPIngestServer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return s.Ingest(ctx)
+}
+
+// send forwards lines to ch, reporting false when the server or the request
+// is shutting down.
+func (s *HTTPIngestServer) send(ctx context.Context, r *http.Request, ch chan<- Result[*LogLine], lines []*LogLine) bool {
+	for _, line := range lines {
+		line.LineNumber = int(s.seq.Add(1))
+		select {
+		case ch <- Result[*LogLine]{Value: line}:
+		case <-ctx.Done():
+			return false
+		case <-r.Context().Done():
+			return false
+		}
+	}
+	return true
+}
+
+func (s *HTTPIngestServer) handleLokiPush(ctx context.Context, w http.ResponseWriter, r *http.Request, ch chan<- Result[*LogLine]) {
+	body, err := readRequestBody(r, s.cfg.MaxBodyBytes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var lines []*LogLine
+	if mediaType == contentTypeJSON {
+		lines, err = DecodeLokiJSON(body)
+	} else {
+		// Promtail and most clients send snappy-compressed protobuf by default.
+		lines, err = DecodeLokiProtobuf(body)
+	}
+	if err != nil {
+		http.Error(w, "decode push request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !s.send(ctx, r, ch, lines) {
+		http.Error(w, "receiver shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *HTTPIngestServer) handleBulk(ctx context.Context, w http.ResponseWriter, r *http.Request, ch chan<- Result[*LogLine], defaultIndex string) {
+	body, err := readRequestBody(r, s.cfg.MaxBodyBytes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lines, items, err := DecodeBulk(body, defaultIndex)
+	if err != nil {
+		http.Error(w, "decode bulk request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.send(ctx, r, ch, lines) {
+		http.Error(w, "receiver shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	resp := struct {
+		Took   int              `json:"took"`
+		Errors bool             `json:"errors"`
+		Items  []map[string]any `json:"items"`
+	}{Items: items}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handleElasticsearchInfo answers the version probe shippers send before
+// their first bulk request.
+func handleElasticsearchInfo(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentTypeJSON)
+	_, _ = w.Write([]byte(`{"name":"lapp","cluster_name":"lapp","version":{"number":"8.0.0","build_flavor":"default"},"tagline":"You Know, for Search"}` + "\n"))
+}
+
+// DecodeLokiJSON decodes a Loki JSON push body:
+// {"streams":[{"stream":{...labels},"values":[["<unix ns>","<line>",{...metadata}]]}]}.
+// Stream labels and structured metadata become attrs.
+func DecodeLokiJSON(body []byte) ([]*LogLine, error) {
+	var req struct {
+		Streams []struct {
+			Stream map[string]string   `json:"stream"`
+			Values [][]json.RawMessage `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, errors.Errorf("decode json: %w", err)
+	}
+
+	var lines []*LogLine
+	for _, stream := range req.Streams {
+		for _, value := range stream.Values {
+			if len(value) < 2 {
+				return nil, errors.New("entry needs a timestamp and a line")
+			}
+			var rawTS, text string
+			if err := json.Unmarshal(value[0], &rawTS); err != nil {
+				return nil, errors.Errorf("decode entry timestamp: %w", err)
+			}
+			if err := json.Unmarshal(value[1], &text); err != nil {
+				return nil, errors.Errorf("decode entry line: %w", err)
+			}
+			nanos, err := strconv.ParseInt(rawTS, 10, 64)
+			if err != nil {
+				return nil, errors.Errorf("parse entry timestamp %q: %w", rawTS, err)
+			}
+
+			line := newLokiLine(stream.Stream, time.Unix(0, nanos), text)
+			if len(value) > 2 {
+				var metadata map[string]string
+				if err := json.Unmarshal(value[2], &metadata); err == nil {
+					for k, v := range metadata {
+						line.Attrs[k] = v
+					}
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// DecodeLokiProtobuf decodes a snappy-compressed logproto.PushRequest.
+func DecodeLokiProtobuf(body []byte) ([]*LogLine, error) {
+	raw, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, errors.Errorf("decode snappy: %w", err)
+	}
+
+	var lines []*LogLine
+	// PushRequest { repeated StreamAdapter streams = 1; }
+	err = walkProtoFields(raw, func(num protowire.Number, value []byte) error {
+		if num != 1 {
+			return nil
+		}
+		streamLines, err := decodeLokiStream(value)
+		lines = append(lines, streamLines...)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Errorf("decode push request: %w", err)
+	}
+	return lines, nil
+}
+
+// decodeLokiStream decodes StreamAdapter { string labels = 1; repeated
+// EntryAdapter entries = 2; }.
+func decodeLokiStream(raw []byte) ([]*LogLine, error) {
+	var labels map[string]string
+	var entries [][]byte
+	err := walkProtoFields(raw, func(num protowire.Number, value []byte) error {
+		switch num {
+		case 1:
+			parsed, err := ParseLokiLabels(string(value))
+			if err != nil {
+				return err
+			}
+			labels = parsed
+		case 2:
+			entries = append(entries, value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]*LogLine, 0, len(entries))
+	for _, entry := range entries {
+		line, err := decodeLokiEntry(labels, entry)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// decodeLokiEntry decodes EntryAdapter { Timestamp timestamp = 1; string
+// line = 2; repeated LabelPairAdapter structuredMetadata = 3; }.
+func decodeLokiEntry(labels map[string]string, raw []byte) (*LogLine, error) {
+	var (
+		ts       time.Time
+		text     string
+		metadata = make(map[string]string)
+	)
+	err := walkProtoFields(raw, func(num protowire.Number, value []byte) error {
+		switch num {
+		case 1:
+			var seconds, nanos int64
+			if err := walkProtoVarints(value, func(num protowire.Number, v uint64) {
+				switch num {
+				case 1:
+					seconds = int64(v)
+				case 2:
+					nanos = int64(int32(v))
+				}
+			}); err != nil {
+				return err
+			}
+			ts = time.Unix(seconds, nanos)
+		case 2:
+			text = string(value)
+		case 3:
+			var name, val string
+			if err := walkProtoFields(value, func(num protowire.Number, v []byte) error {
+				switch num {
+				case 1:
+					name = string(v)
+				case 2:
+					val = string(v)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			metadata[name] = val
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	line := newLokiLine(labels, ts, text)
+	for k, v := range metadata {
+		line.Attrs[k] = v
+	}
+	return line, nil
+}
+
+func newLokiLine(labels map[string]string, ts time.Time, text string) *LogLine {
+	ts = ts.UTC()
+	line := &LogLine{Content: text, Timestamp: &ts, Attrs: make(map[string]string, len(labels))}
+	for k, v := range labels {
+		line.Attrs[k] = v
+	}
+	return line
+}
+
+// walkProtoFields calls fn for every length-delimited field in raw and skips
+// all other wire types.
+func walkProtoFields(raw []byte, fn func(protowire.Number, []byte) error) error {
+	for len(raw) > 0 {
+		num, typ, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		raw = raw[n:]
+
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			raw = raw[n:]
+			continue
+		}
+
+		value, n := protowire.ConsumeBytes(raw)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		raw = raw[n:]
+		if err := fn(num, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkProtoVarints calls fn for every varint field in raw and skips all
+// other wire types.
+func walkProtoVarints(raw []byte, fn func(protowire.Number, uint64)) error {
+	for len(raw) > 0 {
+		num, typ, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		raw = raw[n:]
+
+		if typ != protowire.VarintType {
+			n = protowire.ConsumeFieldValue(num, typ, raw)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			raw = raw[n:]
+			continue
+		}
+
+		v, n := protowire.ConsumeVarint(raw)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		raw = raw[n:]
+		fn(num, v)
+	}
+	return nil
+}
+
+// ParseLokiLabels parses a Prometheus-style label set such as
+// `{job="api", env="prod"}`.
+func ParseLokiLabels(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return nil, errors.Errorf("invalid label set %q", s)
+	}
+	s = s[1 : len(s)-1]
+
+	labels := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if s == "" {
+			return labels, nil
+		}
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, errors.Errorf("invalid label %q", s)
+		}
+		rest = strings.TrimSpace(rest)
+		end := closingQuote(rest)
+		if end < 0 {
+			return nil, errors.Errorf("unterminated label value for %q", name)
+		}
+		value, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			return nil, errors.Errorf("unquote label %q: %w", name, err)
+		}
+		labels[strings.TrimSpace(name)] = value
+		s = rest[end+1:]
+	}
+}
+
+// closingQuote returns the index of the quote closing the string that opens
+// at s[0], or -1.
+func closingQuote(s string) int {
+	if s == "" || s[0] != '"' {
+		return -1
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// DecodeBulk decodes an Elasticsearch _bulk NDJSON body. Each index/create
+// action is followed by a document whose message field becomes Content, its
+// timestamp field the Timestamp, and every other field an attr (nested
+// objects flattened to dotted keys); the target index is kept as "index".
+// Delete and update actions are acknowledged but not ingested. The returned
+// items mirror the bulk response.
+func DecodeBulk(body []byte, defaultIndex string) ([]*LogLine, []map[string]any, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+
+	var (
+		lines []*LogLine
+		items []map[string]any
+	)
+	for scanner.Scan() {
+		actionLine := bytes.TrimSpace(scanner.Bytes())
+		if len(actionLine) == 0 {
+			continue
+		}
+
+		var action map[string]struct {
+			Index string `json:"_index"`
+		}
+		if err := json.Unmarshal(actionLine, &action); err != nil || len(action) != 1 {
+			return nil, nil, errors.Errorf("invalid bulk action %q", actionLine)
+		}
+		for op, meta := range action {
+			index := meta.Index
+			if index == "" {
+				index = defaultIndex
+			}
+			items = append(items, map[string]any{op: map[string]any{"_index": index, "status": http.StatusCreated, "result": "created"}})
+
+			if op == "delete" {
+				continue
+			}
+			if !scanner.Scan() {
+				return nil, nil, errors.Errorf("missing document for %s action", op)
+			}
+			if op != "index" && op != "create" {
+				continue
+			}
+			line, err := bulkDocumentToLine(scanner.Bytes(), index)
+			if err != nil {
+				return nil, nil, err
+			}
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, errors.Errorf("read bulk body: %w", err)
+	}
+	return lines, items, nil
+}
+
+func bulkDocumentToLine(raw []byte, index string) (*LogLine, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, errors.Errorf("decode bulk document: %w", err)
+	}
+
+	line := structuredRecordLine(doc)
+	for _, key := range bulkTimestampKeys {
+		raw, ok := line.Attrs[key]
+		if !ok {
+			continue
+		}
+		if ts, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			ts = ts.UTC()
+			line.Timestamp = &ts
+			delete(line.Attrs, key)
+			break
+		}
+	}
+	if line.Content == "" {
+		// No recognizable message field: keep the whole document as text.
+		line.Content = string(bytes.TrimSpace(raw))
+	}
+	if index != "" {
+		line.Attrs["index"] = index
+	}
+	return line, nil
+}
+
+// structuredRecordLine flattens a structured record into attrs and promotes
+// its message field, if any, to Content.
+func structuredRecordLine(doc map[string]any) *LogLine {
+	line := &LogLine{Attrs: make(map[string]string)}
+	flattenDocument("", doc, line.Attrs)
+
+	for _, key := range recordMessageKeys {
+		if msg, ok := line.Attrs[key]; ok {
+			line.Content = msg
+			delete(line.Attrs, key)
+			break
+		}
+	}
+	return line
+}
+
+func flattenDocument(prefix string, doc map[string]any, attrs map[string]string) {
+	for key, value := range doc {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flattenDocument(key, v, attrs)
+		case string:
+			attrs[key] = v
+		case nil:
+		case float64:
+			attrs[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			attrs[key] = strconv.FormatBool(v)
+		default:
+			b, err := json.Marshal(v)
+			if err == nil {
+				attrs[key] = string(b)
+			}
+		}
+	}
+}
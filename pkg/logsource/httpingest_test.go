@@ -0,0 +1,231 @@
+package logsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestParseLokiLabels(t *testing.T) {
+	labels, err := ParseLokiLabels(`{job="api", env="prod",msg="say \"hi\""}`)
+	if err != nil {
+		t.Fatalf("ParseLokiLabels: %v", err)
+	}
+	for key, value := range map[string]string{"job": "api", "env": "prod", "msg": `say "hi"`} {
+		if labels[key] != value {
+			t.Errorf("label %s: got %q, want %q", key, labels[key], value)
+		}
+	}
+
+	if _, err := ParseLokiLabels(`job="api"`); err == nil {
+		t.Error("expected error for label set without braces")
+	}
+}
+
+func TestDecodeLokiJSON(t *testing.T) {
+	body := `{"streams":[{"stream":{"job":"api","level":"warn"},"values":[
+		["1710576724000000000","slow query",{"trace_id":"abc"}],
+		["1710576725000000000","second"]]}]}`
+
+	lines, err := DecodeLokiJSON([]byte(body))
+	if err != nil {
+		t.Fatalf("DecodeLokiJSON: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	want := time.Unix(0, 1710576724000000000).UTC()
+	if lines[0].Content != "slow query" || lines[0].Timestamp == nil || !lines[0].Timestamp.Equal(want) {
+		t.Errorf("line 0: got %q %v", lines[0].Content, lines[0].Timestamp)
+	}
+	if lines[0].Attrs["job"] != "api" || lines[0].Attrs["level"] != "warn" || lines[0].Attrs["trace_id"] != "abc" {
+		t.Errorf("line 0 attrs: got %v", lines[0].Attrs)
+	}
+	if _, ok := lines[1].Attrs["trace_id"]; ok {
+		t.Errorf("structured metadata leaked into line 1: %v", lines[1].Attrs)
+	}
+}
+
+// lokiProtoPush builds a snappy-compressed logproto.PushRequest with a
+// single stream.
+func lokiProtoPush(labels string, ts time.Time, lines ...string) []byte {
+	var stream []byte
+	stream = protowire.AppendTag(stream, 1, protowire.BytesType)
+	stream = protowire.AppendString(stream, labels)
+	for _, line := range lines {
+		var tsMsg []byte
+		tsMsg = protowire.AppendTag(tsMsg, 1, protowire.VarintType)
+		tsMsg = protowire.AppendVarint(tsMsg, uint64(ts.Unix()))
+		tsMsg = protowire.AppendTag(tsMsg, 2, protowire.VarintType)
+		tsMsg = protowire.AppendVarint(tsMsg, uint64(ts.Nanosecond()))
+
+		var meta []byte
+		meta = protowire.AppendTag(meta, 1, protowire.BytesType)
+		meta = protowire.AppendString(meta, "request_id")
+		meta = protowire.AppendTag(meta, 2, protowire.BytesType)
+		meta = protowire.AppendString(meta, "r-1")
+
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, tsMsg)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, line)
+		entry = protowire.AppendTag(entry, 3, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, meta)
+
+		stream = protowire.AppendTag(stream, 2, protowire.BytesType)
+		stream = protowire.AppendBytes(stream, entry)
+	}
+
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, stream)
+	return snappy.Encode(nil, req)
+}
+
+func TestDecodeLokiProtobuf(t *testing.T) {
+	ts := time.Date(2026, 3, 10, 21, 0, 0, 500, time.UTC)
+	lines, err := DecodeLokiProtobuf(lokiProtoPush(`{job="worker"}`, ts, "first", "second"))
+	if err != nil {
+		t.Fatalf("DecodeLokiProtobuf: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[1].Content != "second" || lines[1].Timestamp == nil || !lines[1].Timestamp.Equal(ts) {
+		t.Errorf("line 1: got %q %v", lines[1].Content, lines[1].Timestamp)
+	}
+	if lines[0].Attrs["job"] != "worker" || lines[0].Attrs["request_id"] != "r-1" {
+		t.Errorf("line 0 attrs: got %v", lines[0].Attrs)
+	}
+
+	if _, err := DecodeLokiProtobuf([]byte("not snappy")); err == nil {
+		t.Error("expected error for invalid snappy body")
+	}
+}
+
+func TestDecodeBulk(t *testing.T) {
+	body := strings.Join([]string{
+		`{"index":{"_index":"app-logs"}}`,
+		`{"@timestamp":"2024-03-16T08:12:04.5Z","message":"user login","level":"info","user":{"id":42,"name":"ann"}}`,
+		`{"delete":{"_index":"app-logs","_id":"1"}}`,
+		`{"create":{}}`,
+		`{"status":"no message field"}`,
+		``,
+	}, "\n")
+
+	lines, items, err := DecodeBulk([]byte(body), "default")
+	if err != nil {
+		t.Fatalf("DecodeBulk: %v", err)
+	}
+	if len(items) != 3 {
+		t.Errorf("expected 3 response items, got %d", len(items))
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+
+	want := time.Date(2024, 3, 16, 8, 12, 4, 500000000, time.UTC)
+	if lines[0].Content != "user login" || lines[0].Timestamp == nil || !lines[0].Timestamp.Equal(want) {
+		t.Errorf("line 0: got %q %v", lines[0].Content, lines[0].Timestamp)
+	}
+	for key, value := range map[string]string{"index": "app-logs", "level": "info", "user.id": "42", "user.name": "ann"} {
+		if lines[0].Attrs[key] != value {
+			t.Errorf("attr %s: got %q, want %q", key, lines[0].Attrs[key], value)
+		}
+	}
+	if _, ok := lines[0].Attrs["message"]; ok {
+		t.Errorf("message should not be duplicated as attr: %v", lines[0].Attrs)
+	}
+
+	if lines[1].Content != `{"status":"no message field"}` || lines[1].Attrs["index"] != "default" {
+		t.Errorf("line 1: got %q %v", lines[1].Content, lines[1].Attrs)
+	}
+
+	if _, _, err := DecodeBulk([]byte(`{"index":{}}`), ""); err == nil {
+		t.Error("expected error for action without document")
+	}
+}
+
+func TestHTTPIngestServer(t *testing.T) {
+	server, err := NewHTTPIngestServer(HTTPIngestConfig{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("NewHTTPIngestServer: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := server.Ingest(ctx)
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	base := "http://" + server.Addr().String()
+
+	resp := postIngest(t, base+lokiPushPath, "application/x-protobuf", lokiProtoPush(`{job="promtail"}`, time.Now(), "from loki"))
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("loki push: status %d", resp.StatusCode)
+	}
+
+	bulk := "{\"index\":{}}\n{\"message\":\"from bulk\"}\n"
+	resp = postIngest(t, base+"/filebeat-1/_bulk", "application/x-ndjson", []byte(bulk))
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("bulk: status %d", resp.StatusCode)
+	}
+	var bulkResp struct {
+		Errors bool             `json:"errors"`
+		Items  []map[string]any `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&bulkResp); err != nil {
+		t.Fatalf("decode bulk response: %v", err)
+	}
+	if bulkResp.Errors || len(bulkResp.Items) != 1 {
+		t.Errorf("bulk response: got %+v", bulkResp)
+	}
+
+	var got []*LogLine
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case rr := <-ch:
+			if rr.Err != nil {
+				t.Fatalf("unexpected error: %v", rr.Err)
+			}
+			got = append(got, rr.Value)
+		case <-timeout:
+			t.Fatalf("timed out after %d lines", len(got))
+		}
+	}
+
+	if got[0].Content != "from loki" || got[0].Attrs["job"] != "promtail" || got[0].LineNumber != 1 {
+		t.Errorf("loki line: got %d %q %v", got[0].LineNumber, got[0].Content, got[0].Attrs)
+	}
+	if got[1].Content != "from bulk" || got[1].Attrs["index"] != "filebeat-1" || got[1].LineNumber != 2 {
+		t.Errorf("bulk line: got %d %q %v", got[1].LineNumber, got[1].Content, got[1].Attrs)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func postIngest(t *testing.T, url, contentType string, body []byte) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post %s: %v", url, err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
@@ -3,17 +3,32 @@ package logsource
 import (
 	"bufio"
 	"context"
+	"io"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/go-errors/errors"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 )
 
+// maxLineBytes is the longest physical line the file ingestor accepts.
+const maxLineBytes = 1024 * 1024
+
 // LogLine represents a single raw log line read from input.
 type LogLine struct {
 	LineNumber int
 	Content    string
+	// Timestamp is set when the source itself carries a timestamp
+	// outside of Content (e.g. a container runtime envelope).
+	Timestamp *time.Time
+	// Attrs holds source-level metadata that is not part of Content,
+	// such as the container output stream.
+	Attrs map[string]string
+	// Offset is the byte offset just past this line in the source file,
+	// or 0 when the source is not a file.
+	Offset int64
 }
 
 // Result wraps either a successfully read value or a read error,
@@ -30,23 +45,61 @@ type ingestor interface {
 
 var _ ingestor = (*fileIngestor)(nil)
 
+// FileOptions tunes how a log file is read.
+type FileOptions struct {
+	// Start is where reading begins (see Resume).
+	Start Position
+	// FallbackCharset is the charset assumed when the file has no BOM and
+	// is not UTF-8, e.g. "windows-1252" or "shift_jis".
+	// Default: DefaultFallbackCharset.
+	FallbackCharset string
+}
+
 // fileIngestor reads log lines from a file path.
 type fileIngestor struct {
 	path string
+	opts FileOptions
 }
 
-// Ingest reads log lines from the file.
+// Ingest reads log lines from the file, transcoding them to UTF-8. The
+// encoding is detected from a BOM or the file's head; invalid sequences are
+// replaced with U+FFFD and counted rather than passed through.
 // Cancel the context to stop reading early; the goroutine will exit promptly.
 func (f *fileIngestor) Ingest(ctx context.Context) (<-chan Result[*LogLine], error) {
 	_, span := otel.Tracer("lapp/logsource").Start(ctx, "logsource.Ingest")
 
+	if f.opts.FallbackCharset != "" {
+		if _, err := charsetDecoder(f.opts.FallbackCharset); err != nil {
+			span.End()
+			return nil, err
+		}
+	}
+
 	file, err := os.Open(f.path)
 	if err != nil {
 		span.End()
 		return nil, errors.Errorf("open log file: %w", err)
 	}
 
-	span.SetAttributes(attribute.String("file.path", f.path))
+	dec, err := sniffFileEncoding(file, f.opts.FallbackCharset)
+	if err != nil {
+		_ = file.Close()
+		span.End()
+		return nil, err
+	}
+
+	start := f.opts.Start
+	start.Offset = max(start.Offset, int64(dec.bomLen))
+	span.SetAttributes(
+		attribute.String("file.path", f.path),
+		attribute.String("file.encoding", dec.name),
+		attribute.Int64("file.start_offset", start.Offset),
+	)
+	if _, err := file.Seek(start.Offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		span.End()
+		return nil, errors.Errorf("seek log file: %w", err)
+	}
 
 	ch := make(chan Result[*LogLine], 100)
 	go func() {
@@ -54,7 +107,12 @@ func (f *fileIngestor) Ingest(ctx context.Context) (<-chan Result[*LogLine], err
 		defer span.End()
 
 		var fileErr error
+		replaced := 0
 		defer func() {
+			span.SetAttributes(attribute.Int("file.replaced_sequences", replaced))
+			if replaced > 0 {
+				slog.Warn("Replaced invalid byte sequences", "file", f.path, "encoding", dec.name, "count", replaced)
+			}
 			if cerr := file.Close(); cerr != nil {
 				fileErr = errors.Join(fileErr, errors.Errorf("close log file: %w", cerr))
 			}
@@ -66,12 +124,21 @@ func (f *fileIngestor) Ingest(ctx context.Context) (<-chan Result[*LogLine], err
 			}
 		}()
 
+		offset := start.Offset
 		scanner := bufio.NewScanner(file)
-		lineNum := 0
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := dec.split(data, atEOF)
+			offset += int64(advance)
+			return advance, token, err
+		})
+		lineNum := start.LineNumber
 		for scanner.Scan() {
 			lineNum++
+			content, n := dec.decode(scanner.Bytes())
+			replaced += n
 			select {
-			case ch <- Result[*LogLine]{Value: &LogLine{LineNumber: lineNum, Content: scanner.Text()}}:
+			case ch <- Result[*LogLine]{Value: &LogLine{LineNumber: lineNum, Content: content, Offset: offset}}:
 			case <-ctx.Done():
 				return
 			}
@@ -84,7 +151,28 @@ func (f *fileIngestor) Ingest(ctx context.Context) (<-chan Result[*LogLine], err
 	return ch, nil
 }
 
+// sniffFileEncoding detects the encoding from the head of file.
+func sniffFileEncoding(file *os.File, fallback string) (*textDecoder, error) {
+	head := make([]byte, encodingSniffBytes)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Errorf("read log file: %w", err)
+	}
+	return detectEncoding(head[:n], n == len(head), fallback)
+}
+
 // Ingest is a convenience function that creates a fileIngestor and reads from it.
 func Ingest(ctx context.Context, filePath string) (<-chan Result[*LogLine], error) {
 	return (&fileIngestor{path: filePath}).Ingest(ctx)
 }
+
+// IngestFrom is like Ingest but starts reading at pos, typically obtained
+// from Resume. Line numbers continue after pos.LineNumber.
+func IngestFrom(ctx context.Context, filePath string, pos Position) (<-chan Result[*LogLine], error) {
+	return (&fileIngestor{path: filePath, opts: FileOptions{Start: pos}}).Ingest(ctx)
+}
+
+// IngestWithOptions is Ingest with explicit FileOptions.
+func IngestWithOptions(ctx context.Context, filePath string, opts FileOptions) (<-chan Result[*LogLine], error) {
+	return (&fileIngestor{path: filePath, opts: opts}).Ingest(ctx)
+}
@@ -0,0 +1,338 @@
+package logsource
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// JournalFormat identifies a journalctl output mode.
+type JournalFormat string
+
+const (
+	// JournalFormatNone means the input is not journal output.
+	JournalFormatNone JournalFormat = ""
+	// JournalFormatExport is the `journalctl -o export` serialization.
+	JournalFormatExport JournalFormat = "export"
+	// JournalFormatJSON is the `journalctl -o json` one-object-per-line output.
+	JournalFormatJSON JournalFormat = "json"
+)
+
+// journalSniffBytes is how much of a file SniffJournal inspects.
+const journalSniffBytes = 64 * 1024
+
+// journalAttrFields maps journal fields kept as attrs to their attr names.
+var journalAttrFields = map[string]string{
+	"_SYSTEMD_UNIT":     "unit",
+	"_PID":              "pid",
+	"_HOSTNAME":         "host",
+	"SYSLOG_IDENTIFIER": "syslog_identifier",
+}
+
+// syslogSeverityLevels maps syslog severities (as used by journald PRIORITY)
+// to the canonical event levels.
+var syslogSeverityLevels = [...]string{
+	0: "fatal", // emerg
+	1: "fatal", // alert
+	2: "fatal", // crit
+	3: "error", // err
+	4: "warn",  // warning
+	5: "info",  // notice
+	6: "info",  // info
+	7: "debug", // debug
+}
+
+// syslogSeverityLevel returns the canonical level for a syslog severity.
+func syslogSeverityLevel(severity int) (string, bool) {
+	if severity < 0 || severity >= len(syslogSeverityLevels) {
+		return "", false
+	}
+	return syslogSeverityLevels[severity], true
+}
+
+// SniffJournal reports whether the file at path holds journalctl export or
+// JSON output, judged by the first record.
+func SniffJournal(path string) (JournalFormat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return JournalFormatNone, errors.Errorf("open log file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	head := make([]byte, journalSniffBytes)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return JournalFormatNone, errors.Errorf("read log file: %w", err)
+	}
+	return detectJournalFormat(head[:n]), nil
+}
+
+func detectJournalFormat(head []byte) JournalFormat {
+	firstLine, _, _ := bytes.Cut(head, []byte("\n"))
+	firstLine = bytes.TrimSpace(firstLine)
+
+	if bytes.HasPrefix(firstLine, []byte("{")) {
+		var payload map[string]json.RawMessage
+		if err := json.Unmarshal(firstLine, &payload); err != nil {
+			return JournalFormatNone
+		}
+		_, hasRealtime := payload["__REALTIME_TIMESTAMP"]
+		_, hasCursor := payload["__CURSOR"]
+		if hasRealtime && hasCursor {
+			return JournalFormatJSON
+		}
+		return JournalFormatNone
+	}
+
+	// Export records start with the cursor and carry the realtime timestamp
+	// among the leading address fields.
+	record, _, _ := bytes.Cut(head, []byte("\n\n"))
+	if bytes.HasPrefix(record, []byte("__CURSOR=")) && bytes.Contains(record, []byte("\n__REALTIME_TIMESTAMP=")) {
+		return JournalFormatExport
+	}
+	return JournalFormatNone
+}
+
+var _ ingestor = (*journalIngestor)(nil)
+
+// journalIngestor reads journal records from a journalctl dump file.
+type journalIngestor struct {
+	path   string
+	format JournalFormat
+}
+
+// Ingest reads one LogLine per journal record. LineNumber is the 1-based
+// record index.
+func (j *journalIngestor) Ingest(ctx context.Context) (<-chan Result[*LogLine], error) {
+	_, span := otel.Tracer("lapp/logsource").Start(ctx, "logsource.IngestJournal")
+
+	file, err := os.Open(j.path)
+	if err != nil {
+		span.End()
+		return nil, errors.Errorf("open journal file: %w", err)
+	}
+
+	span.SetAttributes(
+		attribute.String("file.path", j.path),
+		attribute.String("journal.format", string(j.format)),
+	)
+
+	var next func() (map[string]string, error)
+	reader := bufio.NewReaderSize(file, 64*1024)
+	switch j.format {
+	case JournalFormatExport:
+		next = func() (map[string]string, error) { return readExportRecord(reader) }
+	case JournalFormatJSON:
+		next = func() (map[string]string, error) { return readJSONRecord(reader) }
+	case JournalFormatNone:
+		span.End()
+		_ = file.Close()
+		return nil, errors.New("journal format is required")
+	default:
+		span.End()
+		_ = file.Close()
+		return nil, errors.Errorf("unsupported journal format %q", j.format)
+	}
+
+	ch := make(chan Result[*LogLine], 100)
+	go func() {
+		defer close(ch)
+		defer span.End()
+
+		var readErr error
+		defer func() {
+			if cerr := file.Close(); cerr != nil {
+				readErr = errors.Join(readErr, errors.Errorf("close journal file: %w", cerr))
+			}
+			if readErr != nil {
+				select {
+				case ch <- Result[*LogLine]{Err: readErr}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+
+		recordNum := 0
+		for {
+			fields, err := next()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					readErr = errors.Errorf("read journal record %d: %w", recordNum+1, err)
+				}
+				return
+			}
+			recordNum++
+			line, ok := journalRecordToLine(recordNum, fields)
+			if !ok {
+				continue
+			}
+			select {
+			case ch <- Result[*LogLine]{Value: line}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// IngestJournal reads journalctl export or JSON output from filePath.
+// MESSAGE becomes Content, __REALTIME_TIMESTAMP the Timestamp, PRIORITY the
+// canonical "level" attr, and the unit, PID, host and syslog identifier are
+// kept as attrs. Records without a MESSAGE are skipped.
+func IngestJournal(ctx context.Context, filePath string, format JournalFormat) (<-chan Result[*LogLine], error) {
+	return (&journalIngestor{path: filePath, format: format}).Ingest(ctx)
+}
+
+func journalRecordToLine(recordNum int, fields map[string]string) (*LogLine, bool) {
+	message, ok := fields["MESSAGE"]
+	if !ok {
+		return nil, false
+	}
+
+	line := &LogLine{
+		LineNumber: recordNum,
+		Content:    message,
+		Attrs:      make(map[string]string),
+	}
+
+	if raw, ok := fields["__REALTIME_TIMESTAMP"]; ok {
+		if usec, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			ts := time.UnixMicro(usec).UTC()
+			line.Timestamp = &ts
+		}
+	}
+	if raw, ok := fields["PRIORITY"]; ok {
+		if severity, err := strconv.Atoi(raw); err == nil {
+			if level, ok := syslogSeverityLevel(severity); ok {
+				line.Attrs["level"] = level
+			}
+		}
+	}
+	for field, attr := range journalAttrFields {
+		if value := fields[field]; value != "" {
+			line.Attrs[attr] = value
+		}
+	}
+	return line, true
+}
+
+// readExportRecord reads one record of the journal export format: text
+// fields are "NAME=value\n", binary fields are "NAME\n" followed by a
+// little-endian uint64 length, the raw bytes and "\n". Records end with an
+// empty line.
+func readExportRecord(r *bufio.Reader) (map[string]string, error) {
+	fields := make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && line == "" {
+				if len(fields) > 0 {
+					return fields, nil
+				}
+				return nil, io.EOF
+			}
+			if !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(fields) == 0 {
+				continue
+			}
+			return fields, nil
+		}
+
+		if name, value, ok := strings.Cut(line, "="); ok {
+			fields[name] = value
+			continue
+		}
+
+		value, err := readExportBinaryValue(r)
+		if err != nil {
+			return nil, errors.Errorf("read binary field %s: %w", line, err)
+		}
+		fields[line] = value
+	}
+}
+
+func readExportBinaryValue(r *bufio.Reader) (string, error) {
+	var size uint64
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return "", err
+	}
+	if size > maxLineBytes {
+		return "", errors.Errorf("field size %d exceeds limit %d", size, maxLineBytes)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", err
+	}
+	if b, err := r.ReadByte(); err != nil || b != '\n' {
+		return "", errors.New("missing newline after binary field")
+	}
+	return string(data), nil
+}
+
+// readJSONRecord reads one `journalctl -o json` line. Values are strings,
+// null, byte arrays (for non-UTF-8 data) or arrays of those when a field
+// occurs several times; repeated fields keep their first value.
+func readJSONRecord(r *bufio.Reader) (map[string]string, error) {
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || len(line) == 0) {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var payload map[string]json.RawMessage
+		if err := json.Unmarshal(line, &payload); err != nil {
+			return nil, errors.Errorf("decode journal json: %w", err)
+		}
+		fields := make(map[string]string, len(payload))
+		for name, raw := range payload {
+			if value, ok := decodeJournalJSONValue(raw); ok {
+				fields[name] = value
+			}
+		}
+		return fields, nil
+	}
+}
+
+func decodeJournalJSONValue(raw json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, true
+	}
+
+	var byteValues []byte
+	var numbers []int
+	if err := json.Unmarshal(raw, &numbers); err == nil {
+		for _, n := range numbers {
+			byteValues = append(byteValues, byte(n))
+		}
+		return string(byteValues), true
+	}
+
+	var multi []json.RawMessage
+	if err := json.Unmarshal(raw, &multi); err == nil && len(multi) > 0 {
+		return decodeJournalJSONValue(multi[0])
+	}
+	return "", false
+}
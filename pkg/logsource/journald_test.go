@@ -0,0 +1,140 @@
+package logsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeJournalFile(t *testing.T, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "journal.log")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write journal file: %v", err)
+	}
+	return path
+}
+
+func collectJournal(t *testing.T, path string) []LogLine {
+	t.Helper()
+
+	format, err := SniffJournal(path)
+	if err != nil {
+		t.Fatalf("SniffJournal: %v", err)
+	}
+	ch, err := IngestJournal(context.Background(), path, format)
+	if err != nil {
+		t.Fatalf("IngestJournal: %v", err)
+	}
+
+	var got []LogLine
+	for rr := range ch {
+		if rr.Err != nil {
+			t.Fatalf("unexpected error: %v", rr.Err)
+		}
+		got = append(got, *rr.Value)
+	}
+	return got
+}
+
+func TestIngestJournalExport(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("__CURSOR=s=abc;i=1\n")
+	buf.WriteString("__REALTIME_TIMESTAMP=1710576724123456\n")
+	buf.WriteString("PRIORITY=3\n")
+	buf.WriteString("_SYSTEMD_UNIT=nginx.service\n")
+	buf.WriteString("_PID=4242\n")
+	buf.WriteString("MESSAGE=upstream timed out (110: Connection timed out)\n")
+	buf.WriteString("\n")
+	buf.WriteString("__CURSOR=s=abc;i=2\n")
+	buf.WriteString("__REALTIME_TIMESTAMP=1710576725000000\n")
+	buf.WriteString("PRIORITY=6\n")
+	buf.WriteString("MESSAGE\n")
+	msg := "panic: boom\ngoroutine 1 [running]:"
+	_ = binary.Write(&buf, binary.LittleEndian, uint64(len(msg)))
+	buf.WriteString(msg)
+	buf.WriteString("\n\n")
+	buf.WriteString("__CURSOR=s=abc;i=3\n")
+	buf.WriteString("__REALTIME_TIMESTAMP=1710576726000000\n")
+	buf.WriteString("_BOOT_ID=deadbeef\n")
+
+	path := writeJournalFile(t, buf.Bytes())
+	if format, _ := SniffJournal(path); format != JournalFormatExport {
+		t.Fatalf("expected export format, got %q", format)
+	}
+
+	got := collectJournal(t, path)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 records with MESSAGE, got %d", len(got))
+	}
+
+	first := got[0]
+	if first.LineNumber != 1 || first.Content != "upstream timed out (110: Connection timed out)" {
+		t.Errorf("record 1: got %d %q", first.LineNumber, first.Content)
+	}
+	want := time.UnixMicro(1710576724123456).UTC()
+	if first.Timestamp == nil || !first.Timestamp.Equal(want) {
+		t.Errorf("record 1 timestamp: got %v, want %v", first.Timestamp, want)
+	}
+	for key, value := range map[string]string{"level": "error", "unit": "nginx.service", "pid": "4242"} {
+		if first.Attrs[key] != value {
+			t.Errorf("record 1 attr %s: got %q, want %q", key, first.Attrs[key], value)
+		}
+	}
+
+	if got[1].Content != msg {
+		t.Errorf("record 2: binary MESSAGE not decoded, got %q", got[1].Content)
+	}
+	if got[1].Attrs["level"] != "info" {
+		t.Errorf("record 2 level: got %q, want info", got[1].Attrs["level"])
+	}
+}
+
+func TestIngestJournalJSON(t *testing.T) {
+	data := `{"__CURSOR":"s=abc;i=1","__REALTIME_TIMESTAMP":"1710576724000000","PRIORITY":"4","_SYSTEMD_UNIT":"kubelet.service","_PID":"981","MESSAGE":"eviction manager: must evict pod(s)"}
+{"__CURSOR":"s=abc;i=2","__REALTIME_TIMESTAMP":"1710576725000000","PRIORITY":"2","MESSAGE":[104,105,255],"_PID":["17","18"]}
+`
+
+	path := writeJournalFile(t, []byte(data))
+	if format, _ := SniffJournal(path); format != JournalFormatJSON {
+		t.Fatalf("expected json format, got %q", format)
+	}
+
+	got := collectJournal(t, path)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(got))
+	}
+	if got[0].Content != "eviction manager: must evict pod(s)" {
+		t.Errorf("record 1: got %q", got[0].Content)
+	}
+	if got[0].Attrs["level"] != "warn" || got[0].Attrs["unit"] != "kubelet.service" || got[0].Attrs["pid"] != "981" {
+		t.Errorf("record 1 attrs: got %v", got[0].Attrs)
+	}
+	if got[1].Content != "hi\xff" {
+		t.Errorf("record 2: byte-array MESSAGE not decoded, got %q", got[1].Content)
+	}
+	if got[1].Attrs["level"] != "fatal" || got[1].Attrs["pid"] != "17" {
+		t.Errorf("record 2 attrs: got %v", got[1].Attrs)
+	}
+}
+
+func TestSniffJournalPlainText(t *testing.T) {
+	path := writeJournalFile(t, []byte("2024-01-01 INFO Starting service\n"))
+	format, err := SniffJournal(path)
+	if err != nil {
+		t.Fatalf("SniffJournal: %v", err)
+	}
+	if format != JournalFormatNone {
+		t.Fatalf("expected no journal format, got %q", format)
+	}
+
+	path = writeJournalFile(t, []byte(`{"level":"info","msg":"plain json"}`+"\n"))
+	if format, _ := SniffJournal(path); format != JournalFormatNone {
+		t.Fatalf("expected no journal format for app json, got %q", format)
+	}
+}
@@ -0,0 +1,415 @@
+package logsource
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-errors/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	otlpLogsPath        = "/v1/logs"
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// OTLPConfig configures the OTLP/HTTP logs receiver.
+type OTLPConfig struct {
+	// Addr is the HTTP listen address. Default: ":4318".
+	Addr string
+	// MaxBodyBytes bounds a single (decompressed) request. Default: 16MB.
+	MaxBodyBytes int64
+}
+
+func (c *OTLPConfig) defaults() {
+	if c.Addr == "" {
+		c.Addr = ":4318"
+	}
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = 16 * 1024 * 1024
+	}
+}
+
+var _ ingestor = (*OTLPServer)(nil)
+
+// OTLPServer accepts OTLP/HTTP ExportLogsServiceRequest payloads, encoded as
+// protobuf or JSON, on POST /v1/logs.
+type OTLPServer struct {
+	cfg OTLPConfig
+	ln  net.Listener
+	seq atomic.Int64
+}
+
+// NewOTLPServer binds the listen address. Serving starts with Ingest.
+func NewOTLPServer(cfg OTLPConfig) (*OTLPServer, error) {
+	cfg.defaults()
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, errors.Errorf("listen otlp: %w", err)
+	}
+	return &OTLPServer{cfg: cfg, ln: ln}, nil
+}
+
+// Addr returns the bound listen address.
+func (s *OTLPServer) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Ingest serves until ctx is cancelled and streams one LogLine per
+// LogRecord (see OTLPRecordToLine). LineNumber is the 1-based arrival order.
+func (s *OTLPServer) Ingest(ctx context.Context) (<-chan Result[*LogLine], error) {
+	ctx, span := otel.Tracer("lapp/logsource").Start(ctx, "logsource.OTLPServer.Ingest")
+	span.SetAttributes(attribute.String("otlp.addr", s.Addr().String()))
+
+	ch := make(chan Result[*LogLine], 100)
+	mux := http.NewServeMux()
+	mux.HandleFunc(otlpLogsPath, func(w http.ResponseWriter, r *http.Request) {
+		s.handleLogs(ctx, w, r, ch)
+	})
+	var handlers handlerGate
+	srv := &http.Server{
+		Handler:           handlers.wrap(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		defer span.End()
+		// Shutdown may time out with handlers still sending, so ch is
+		// closed only once they have all returned.
+		defer close(ch)
+		defer handlers.shutdown()
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- srv.Serve(s.ln) }()
+
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		case err := <-errCh:
+			if !errors.Is(err, http.ErrServerClosed) {
+				select {
+				case ch <- Result[*LogLine]{Err: errors.Errorf("serve otlp: %w", err)}:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// handlerGate tracks the running handlers of a server that send on its
+// channel, so the channel is closed only once they have returned. Requests
+// arriving after shutdown has begun are rejected rather than tracked.
+type handlerGate struct {
+	mu      sync.Mutex
+	closed  bool
+	running sync.WaitGroup
+}
+
+// wrap returns h served through the gate.
+func (g *handlerGate) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		if g.closed {
+			g.mu.Unlock()
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		g.running.Add(1)
+		g.mu.Unlock()
+		defer g.running.Done()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// shutdown rejects further requests and waits for the running ones.
+func (g *handlerGate) shutdown() {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+	g.running.Wait()
+}
+
+func (s *OTLPServer) handleLogs(ctx context.Context, w http.ResponseWriter, r *http.Request, ch chan<- Result[*LogLine]) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != contentTypeProtobuf && mediaType != contentTypeJSON {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := readRequestBody(r, s.cfg.MaxBodyBytes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &collogspb.ExportLogsServiceRequest{}
+	if mediaType == contentTypeJSON {
+		err = unmarshalOTLPJSON(body, req)
+	} else {
+		err = proto.Unmarshal(body, req)
+	}
+	if err != nil {
+		http.Error(w, "decode request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, line := range OTLPRequestToLines(req) {
+		line.LineNumber = int(s.seq.Add(1))
+		select {
+		case ch <- Result[*LogLine]{Value: line}:
+		case <-ctx.Done():
+			http.Error(w, "receiver shutting down", http.StatusServiceUnavailable)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	resp := &collogspb.ExportLogsServiceResponse{}
+	var out []byte
+	if mediaType == contentTypeJSON {
+		out, err = protojson.Marshal(resp)
+	} else {
+		out, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	_, _ = w.Write(out)
+}
+
+// readRequestBody reads a request body up to limit bytes, transparently
+// inflating gzip content encoding.
+func readRequestBody(r *http.Request, limit int64) ([]byte, error) {
+	var body io.Reader = r.Body
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, errors.Errorf("open gzip body: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		body = gz
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, errors.Errorf("read body: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, errors.Errorf("body exceeds %d bytes", limit)
+	}
+	return data, nil
+}
+
+// unmarshalOTLPJSON decodes the OTLP JSON encoding, which differs from
+// canonical protobuf JSON in encoding trace and span IDs as hex strings.
+func unmarshalOTLPJSON(data []byte, req *collogspb.ExportLogsServiceRequest) error {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	hexIDsToBase64(doc)
+	fixed, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(fixed, req)
+}
+
+func hexIDsToBase64(node any) {
+	switch v := node.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if key == "traceId" || key == "spanId" || key == "trace_id" || key == "span_id" {
+				if s, ok := child.(string); ok {
+					if raw, err := hex.DecodeString(s); err == nil {
+						v[key] = base64.StdEncoding.EncodeToString(raw)
+					}
+				}
+				continue
+			}
+			hexIDsToBase64(child)
+		}
+	case []any:
+		for _, child := range v {
+			hexIDsToBase64(child)
+		}
+	}
+}
+
+// ListenOTLP binds an OTLPServer and starts serving it.
+func ListenOTLP(ctx context.Context, cfg OTLPConfig) (<-chan Result[*LogLine], error) {
+	s, err := NewOTLPServer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return s.Ingest(ctx)
+}
+
+// OTLPRequestToLines flattens an export request into one LogLine per record.
+func OTLPRequestToLines(req *collogspb.ExportLogsServiceRequest) []*LogLine {
+	var lines []*LogLine
+	for _, rl := range req.GetResourceLogs() {
+		for _, sl := range rl.GetScopeLogs() {
+			for _, record := range sl.GetLogRecords() {
+				lines = append(lines, OTLPRecordToLine(rl.GetResource().GetAttributes(), sl.GetScope(), record))
+			}
+		}
+	}
+	return lines
+}
+
+// OTLPRecordToLine maps a LogRecord onto the event model: the body becomes
+// Content, the record (or observed) time the Timestamp, severity the
+// canonical "level" attr, and resource then record attributes the attrs,
+// with "service.name" also exposed as "service". Trace and span IDs are kept
+// as hex "trace_id" and "span_id" attrs.
+func OTLPRecordToLine(resourceAttrs []*commonpb.KeyValue, scope *commonpb.InstrumentationScope, record *logspb.LogRecord) *LogLine {
+	line := &LogLine{
+		Content: anyValueString(record.GetBody()),
+		Attrs:   make(map[string]string),
+	}
+
+	if nanos := record.GetTimeUnixNano(); nanos != 0 {
+		ts := time.Unix(0, int64(nanos)).UTC()
+		line.Timestamp = &ts
+	} else if nanos := record.GetObservedTimeUnixNano(); nanos != 0 {
+		ts := time.Unix(0, int64(nanos)).UTC()
+		line.Timestamp = &ts
+	}
+
+	for _, kv := range resourceAttrs {
+		line.Attrs[kv.GetKey()] = anyValueString(kv.GetValue())
+	}
+	if name := scope.GetName(); name != "" {
+		line.Attrs["scope"] = name
+	}
+	for _, kv := range record.GetAttributes() {
+		line.Attrs[kv.GetKey()] = anyValueString(kv.GetValue())
+	}
+	if service := line.Attrs["service.name"]; service != "" {
+		line.Attrs["service"] = service
+	}
+
+	if level, ok := otlpSeverityLevel(record.GetSeverityNumber(), record.GetSeverityText()); ok {
+		line.Attrs["level"] = level
+	}
+	if id := record.GetTraceId(); len(id) > 0 {
+		line.Attrs["trace_id"] = hex.EncodeToString(id)
+	}
+	if id := record.GetSpanId(); len(id) > 0 {
+		line.Attrs["span_id"] = hex.EncodeToString(id)
+	}
+	return line
+}
+
+// otlpSeverityLevel maps SeverityNumber ranges (TRACE=1-4 ... FATAL=21-24)
+// to canonical levels, falling back to SeverityText.
+func otlpSeverityLevel(number logspb.SeverityNumber, text string) (string, bool) {
+	levels := [...]string{"trace", "debug", "info", "warn", "error", "fatal"}
+	if number >= logspb.SeverityNumber_SEVERITY_NUMBER_TRACE && number <= logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4 {
+		return levels[(number-1)/4], true
+	}
+
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "trace":
+		return "trace", true
+	case "debug":
+		return "debug", true
+	case "info", "information", "notice":
+		return "info", true
+	case "warn", "warning":
+		return "warn", true
+	case "error", "err":
+		return "error", true
+	case "fatal", "critical", "crit", "panic", "emergency", "alert":
+		return "fatal", true
+	default:
+		return "", false
+	}
+}
+
+// anyValueString renders an AnyValue as text. Scalars use their natural
+// form, bytes are hex-encoded, and arrays and maps become JSON.
+func anyValueString(v *commonpb.AnyValue) string {
+	switch value := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(value.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(value.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(value.DoubleValue, 'f', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return hex.EncodeToString(value.BytesValue)
+	case *commonpb.AnyValue_ArrayValue, *commonpb.AnyValue_KvlistValue:
+		b, err := json.Marshal(anyValueNative(v))
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	default:
+		return ""
+	}
+}
+
+func anyValueNative(v *commonpb.AnyValue) any {
+	switch value := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return value.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return value.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return value.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return hex.EncodeToString(value.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		items := make([]any, 0, len(value.ArrayValue.GetValues()))
+		for _, item := range value.ArrayValue.GetValues() {
+			items = append(items, anyValueNative(item))
+		}
+		return items
+	case *commonpb.AnyValue_KvlistValue:
+		fields := make(map[string]any, len(value.KvlistValue.GetValues()))
+		for _, kv := range value.KvlistValue.GetValues() {
+			fields[kv.GetKey()] = anyValueNative(kv.GetValue())
+		}
+		return fields
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,184 @@
+package logsource
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func stringKV(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func TestOTLPRecordToLine(t *testing.T) {
+	ts := time.Date(2026, 3, 10, 21, 0, 0, 0, time.UTC)
+	record := &logspb.LogRecord{
+		TimeUnixNano:   uint64(ts.UnixNano()),
+		SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_WARN2,
+		Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "cache miss ratio high"}},
+		Attributes: []*commonpb.KeyValue{
+			{Key: "ratio", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 0.75}}},
+			stringKV("deployment.environment", "override"),
+		},
+		TraceId: []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanId:  []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	}
+	resource := []*commonpb.KeyValue{
+		stringKV("service.name", "checkout"),
+		stringKV("deployment.environment", "prod"),
+	}
+
+	line := OTLPRecordToLine(resource, &commonpb.InstrumentationScope{Name: "app/logger"}, record)
+
+	if line.Content != "cache miss ratio high" {
+		t.Errorf("Content: got %q", line.Content)
+	}
+	if line.Timestamp == nil || !line.Timestamp.Equal(ts) {
+		t.Errorf("Timestamp: got %v, want %v", line.Timestamp, ts)
+	}
+	for key, value := range map[string]string{
+		"level":                  "warn",
+		"service":                "checkout",
+		"service.name":           "checkout",
+		"deployment.environment": "override",
+		"ratio":                  "0.75",
+		"scope":                  "app/logger",
+		"trace_id":               "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":                "00f067aa0ba902b7",
+	} {
+		if line.Attrs[key] != value {
+			t.Errorf("attr %s: got %q, want %q", key, line.Attrs[key], value)
+		}
+	}
+}
+
+func TestOTLPSeverityTextFallback(t *testing.T) {
+	record := &logspb.LogRecord{SeverityText: "Critical", ObservedTimeUnixNano: 1}
+	line := OTLPRecordToLine(nil, nil, record)
+	if line.Attrs["level"] != "fatal" {
+		t.Errorf("level: got %q, want fatal", line.Attrs["level"])
+	}
+	if line.Timestamp == nil {
+		t.Error("expected observed time fallback")
+	}
+}
+
+func TestOTLPServerProtobufAndJSON(t *testing.T) {
+	server, err := NewOTLPServer(OTLPConfig{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("NewOTLPServer: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := server.Ingest(ctx)
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	url := "http://" + server.Addr().String() + "/v1/logs"
+
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringKV("service.name", "api")}},
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{{
+			Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "from protobuf"}},
+		}}}},
+	}}}
+	body, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	postOTLP(t, url, "application/x-protobuf", body)
+
+	jsonBody := `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"worker"}}]},
+		"scopeLogs":[{"logRecords":[{"timeUnixNano":"1710576724000000000","severityNumber":17,
+		"body":{"stringValue":"from json"},"traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174"}]}]}]}`
+	postOTLP(t, url, "application/json", []byte(jsonBody))
+
+	var got []*LogLine
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case rr := <-ch:
+			if rr.Err != nil {
+				t.Fatalf("unexpected error: %v", rr.Err)
+			}
+			got = append(got, rr.Value)
+		case <-timeout:
+			t.Fatalf("timed out after %d lines", len(got))
+		}
+	}
+
+	if got[0].Content != "from protobuf" || got[0].Attrs["service"] != "api" || got[0].LineNumber != 1 {
+		t.Errorf("protobuf line: got %d %q %v", got[0].LineNumber, got[0].Content, got[0].Attrs)
+	}
+	if got[1].Content != "from json" || got[1].Attrs["service"] != "worker" || got[1].Attrs["level"] != "error" {
+		t.Errorf("json line: got %q %v", got[1].Content, got[1].Attrs)
+	}
+	if got[1].Attrs["trace_id"] != "5b8efff798038103d269b633813fc60c" || got[1].Attrs["span_id"] != "eee19b7ec3c1b174" {
+		t.Errorf("json trace ids: got %v", got[1].Attrs)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestHandlerGateRejectsAfterShutdown(t *testing.T) {
+	var gate handlerGate
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := gate.wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	<-started
+	done := make(chan struct{})
+	go func() {
+		gate.shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("shutdown returned with a handler running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-done
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("request after shutdown: got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func postOTLP(t *testing.T, url, contentType string, body []byte) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post %s: %v", contentType, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("post %s: status %d", contentType, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != contentType {
+		t.Errorf("response content type: got %q, want %q", got, contentType)
+	}
+}
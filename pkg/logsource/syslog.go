@@ -0,0 +1,456 @@
+package logsource
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/strrl/lapp/pkg/timestamp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// syslogFacilities names the facility codes from RFC 5424 section 6.2.1.
+var syslogFacilities = [...]string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// rfc3164TimestampLayouts are the BSD syslog timestamp forms seen in the wild.
+// The day is space-padded ("Jul  1") in the RFC but often not. The
+// fractional forms come first, since the plain ones match their prefix.
+var rfc3164TimestampLayouts = []string{
+	time.StampMicro,
+	"Jan 2 15:04:05.000000",
+	time.Stamp,
+	"Jan 2 15:04:05",
+}
+
+// SyslogConfig configures the syslog receiver.
+type SyslogConfig struct {
+	// UDPAddr is the UDP listen address (e.g. ":5514"). Empty disables UDP.
+	UDPAddr string
+	// TCPAddr is the TCP listen address. Empty disables TCP.
+	TCPAddr string
+	// MaxMessageBytes bounds a single message. Default: 65536.
+	MaxMessageBytes int
+}
+
+func (c *SyslogConfig) defaults() {
+	if c.MaxMessageBytes == 0 {
+		c.MaxMessageBytes = 65536
+	}
+}
+
+var _ ingestor = (*SyslogServer)(nil)
+
+// SyslogServer receives RFC 3164 and RFC 5424 messages over UDP and TCP.
+// TCP accepts both octet-counted and newline-framed transport (RFC 6587).
+type SyslogServer struct {
+	cfg     SyslogConfig
+	udpConn net.PacketConn
+	tcpLn   net.Listener
+	seq     atomic.Int64
+}
+
+// NewSyslogServer binds the configured listeners. Serving starts with Ingest.
+func NewSyslogServer(cfg SyslogConfig) (*SyslogServer, error) {
+	cfg.defaults()
+	if cfg.UDPAddr == "" && cfg.TCPAddr == "" {
+		return nil, errors.New("at least one of UDP or TCP address is required")
+	}
+
+	s := &SyslogServer{cfg: cfg}
+	if cfg.UDPAddr != "" {
+		conn, err := net.ListenPacket("udp", cfg.UDPAddr)
+		if err != nil {
+			return nil, errors.Errorf("listen udp: %w", err)
+		}
+		s.udpConn = conn
+	}
+	if cfg.TCPAddr != "" {
+		ln, err := net.Listen("tcp", cfg.TCPAddr)
+		if err != nil {
+			if s.udpConn != nil {
+				_ = s.udpConn.Close()
+			}
+			return nil, errors.Errorf("listen tcp: %w", err)
+		}
+		s.tcpLn = ln
+	}
+	return s, nil
+}
+
+// UDPAddr returns the bound UDP address, or nil when UDP is disabled.
+func (s *SyslogServer) UDPAddr() net.Addr {
+	if s.udpConn == nil {
+		return nil
+	}
+	return s.udpConn.LocalAddr()
+}
+
+// TCPAddr returns the bound TCP address, or nil when TCP is disabled.
+func (s *SyslogServer) TCPAddr() net.Addr {
+	if s.tcpLn == nil {
+		return nil
+	}
+	return s.tcpLn.Addr()
+}
+
+// Ingest serves until ctx is cancelled and streams one LogLine per message.
+// LineNumber is the 1-based arrival order across all transports.
+func (s *SyslogServer) Ingest(ctx context.Context) (<-chan Result[*LogLine], error) {
+	ctx, span := otel.Tracer("lapp/logsource").Start(ctx, "logsource.SyslogServer.Ingest")
+	if addr := s.UDPAddr(); addr != nil {
+		span.SetAttributes(attribute.String("syslog.udp", addr.String()))
+	}
+	if addr := s.TCPAddr(); addr != nil {
+		span.SetAttributes(attribute.String("syslog.tcp", addr.String()))
+	}
+
+	ch := make(chan Result[*LogLine], 100)
+	var wg sync.WaitGroup
+
+	if s.udpConn != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveUDP(ctx, ch)
+		}()
+	}
+	if s.tcpLn != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveTCP(ctx, ch, &wg)
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		if s.udpConn != nil {
+			_ = s.udpConn.Close()
+		}
+		if s.tcpLn != nil {
+			_ = s.tcpLn.Close()
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(ch)
+		span.End()
+	}()
+
+	return ch, nil
+}
+
+func (s *SyslogServer) emit(ctx context.Context, ch chan<- Result[*LogLine], raw string) bool {
+	line := ParseSyslog(raw, time.Now())
+	line.LineNumber = int(s.seq.Add(1))
+	select {
+	case ch <- Result[*LogLine]{Value: line}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *SyslogServer) serveUDP(ctx context.Context, ch chan<- Result[*LogLine]) {
+	buf := make([]byte, s.cfg.MaxMessageBytes)
+	for {
+		n, _, err := s.udpConn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				select {
+				case ch <- Result[*LogLine]{Err: errors.Errorf("read udp: %w", err)}:
+				case <-ctx.Done():
+				}
+			}
+			return
+		}
+		msg := strings.TrimRight(string(buf[:n]), "\r\n\x00")
+		if msg == "" {
+			continue
+		}
+		if !s.emit(ctx, ch, msg) {
+			return
+		}
+	}
+}
+
+func (s *SyslogServer) serveTCP(ctx context.Context, ch chan<- Result[*LogLine], wg *sync.WaitGroup) {
+	for {
+		conn, err := s.tcpLn.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				select {
+				case ch <- Result[*LogLine]{Err: errors.Errorf("accept tcp: %w", err)}:
+				case <-ctx.Done():
+				}
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn, ch)
+		}()
+	}
+}
+
+// serveConn reads framed messages from one TCP connection. Per RFC 6587 a
+// frame starting with a digit is octet-counted ("LEN SP MSG"); anything else
+// is terminated by a newline.
+func (s *SyslogServer) serveConn(ctx context.Context, conn net.Conn, ch chan<- Result[*LogLine]) {
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	r := bufio.NewReaderSize(conn, 64*1024)
+	for {
+		msg, err := s.readFrame(r)
+		if err != nil {
+			return
+		}
+		if msg == "" {
+			continue
+		}
+		if !s.emit(ctx, ch, msg) {
+			return
+		}
+	}
+}
+
+func (s *SyslogServer) readFrame(r *bufio.Reader) (string, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		rawLen, err := r.ReadString(' ')
+		if err != nil {
+			return "", err
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(rawLen, " "))
+		if err != nil || n > s.cfg.MaxMessageBytes {
+			return "", errors.Errorf("invalid octet count %q", rawLen)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(buf), "\r\n"), nil
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n\x00"), nil
+}
+
+// ListenSyslog binds a SyslogServer and starts serving it.
+func ListenSyslog(ctx context.Context, cfg SyslogConfig) (<-chan Result[*LogLine], error) {
+	s, err := NewSyslogServer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return s.Ingest(ctx)
+}
+
+// ParseSyslog parses an RFC 5424 or RFC 3164 message. The free-text MSG part
+// becomes Content. Facility, canonical level, host, app_name, procid, msgid
+// and structured data ("sd.<SD-ID>.<PARAM>") become attrs. received is used
+// to infer the year of RFC 3164 timestamps. Input without a PRI header is
+// returned as plain content.
+func ParseSyslog(raw string, received time.Time) *LogLine {
+	line := &LogLine{Content: raw, Attrs: make(map[string]string)}
+
+	pri, rest, ok := parseSyslogPRI(raw)
+	if !ok {
+		return line
+	}
+	if facility := pri / 8; facility < len(syslogFacilities) {
+		line.Attrs["facility"] = syslogFacilities[facility]
+	}
+	if level, ok := syslogSeverityLevel(pri % 8); ok {
+		line.Attrs["level"] = level
+	}
+
+	if version, after, ok := strings.Cut(rest, " "); ok && version == "1" {
+		parseRFC5424(line, after)
+		return line
+	}
+	parseRFC3164(line, rest, received)
+	return line
+}
+
+func parseSyslogPRI(raw string) (int, string, bool) {
+	if !strings.HasPrefix(raw, "<") {
+		return 0, "", false
+	}
+	end := strings.IndexByte(raw, '>')
+	if end < 2 || end > 4 {
+		return 0, "", false
+	}
+	pri, err := strconv.Atoi(raw[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, "", false
+	}
+	return pri, raw[end+1:], true
+}
+
+// parseRFC5424 handles "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD [MSG]".
+func parseRFC5424(line *LogLine, rest string) {
+	fields := make([]string, 5)
+	for i := range fields {
+		var field string
+		field, rest, _ = strings.Cut(rest, " ")
+		fields[i] = field
+	}
+
+	if fields[0] != "-" {
+		if ts, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+			line.Timestamp = &ts
+		}
+	}
+	for i, attr := range []string{"host", "app_name", "procid", "msgid"} {
+		if value := fields[i+1]; value != "" && value != "-" {
+			line.Attrs[attr] = value
+		}
+	}
+
+	msg := rest
+	if strings.HasPrefix(rest, "-") {
+		msg = strings.TrimPrefix(strings.TrimPrefix(rest, "-"), " ")
+	} else if strings.HasPrefix(rest, "[") {
+		msg = parseStructuredData(line, rest)
+	}
+	line.Content = strings.TrimPrefix(msg, "\ufeff")
+}
+
+// parseStructuredData consumes one or more "[SD-ID PARAM="VALUE" ...]"
+// elements and returns the remaining MSG.
+func parseStructuredData(line *LogLine, s string) string {
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		i++
+		idEnd := i
+		for idEnd < len(s) && s[idEnd] != ' ' && s[idEnd] != ']' {
+			idEnd++
+		}
+		id := s[i:idEnd]
+		i = idEnd
+
+		for i < len(s) && s[i] != ']' {
+			i = skipSpaces(s, i)
+			eq := strings.IndexByte(s[i:], '=')
+			if eq < 0 || i+eq+1 >= len(s) || s[i+eq+1] != '"' {
+				return s
+			}
+			name := s[i : i+eq]
+			value, next, ok := scanSDValue(s, i+eq+2)
+			if !ok {
+				return s
+			}
+			line.Attrs["sd."+id+"."+name] = value
+			i = next
+		}
+		if i >= len(s) {
+			return ""
+		}
+		i++ // closing ']'
+	}
+	return strings.TrimPrefix(s[i:], " ")
+}
+
+// scanSDValue reads a PARAM-VALUE up to the closing quote, unescaping
+// \", \\ and \].
+func scanSDValue(s string, start int) (string, int, bool) {
+	var b strings.Builder
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+				i++
+			}
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), i + 1, true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, false
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+// parseRFC3164 handles "TIMESTAMP HOSTNAME TAG[PID]: MSG". Relays sometimes
+// substitute an RFC 3339 timestamp, which is accepted as well.
+func parseRFC3164(line *LogLine, rest string, received time.Time) {
+	rest = parseRFC3164Timestamp(line, rest, received)
+
+	host, after, ok := strings.Cut(rest, " ")
+	if !ok || strings.HasSuffix(host, ":") || strings.Contains(host, "[") {
+		// No hostname (e.g. local logger output); the token is the tag.
+		after = rest
+	} else {
+		line.Attrs["host"] = host
+	}
+
+	tag, msg, ok := strings.Cut(after, ": ")
+	if !ok || strings.Contains(tag, " ") {
+		line.Content = after
+		return
+	}
+	if name, pid, ok := strings.Cut(tag, "["); ok {
+		line.Attrs["app_name"] = name
+		line.Attrs["procid"] = strings.TrimSuffix(pid, "]")
+	} else {
+		line.Attrs["app_name"] = tag
+	}
+	line.Content = msg
+}
+
+func parseRFC3164Timestamp(line *LogLine, rest string, received time.Time) string {
+	if token, after, ok := strings.Cut(rest, " "); ok {
+		if ts, err := time.Parse(time.RFC3339Nano, token); err == nil {
+			line.Timestamp = &ts
+			return after
+		}
+	}
+
+	for _, layout := range rfc3164TimestampLayouts {
+		// Timestamps are fixed-width per layout, modulo the day padding.
+		for _, width := range []int{len(layout), len(layout) + 1} {
+			// The timestamp must end at a space, not inside a longer one.
+			if len(rest) < width || (len(rest) > width && rest[width] != ' ') {
+				continue
+			}
+			ts, err := time.ParseInLocation(layout, rest[:width], received.Location())
+			if err != nil {
+				continue
+			}
+			ts = timestamp.InferYear(ts, received)
+			line.Timestamp = &ts
+			return strings.TrimPrefix(rest[width:], " ")
+		}
+	}
+	return rest
+}
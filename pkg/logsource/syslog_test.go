@@ -0,0 +1,177 @@
+package logsource
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseSyslogRFC5424(t *testing.T) {
+	raw := `<165>1 2024-03-16T08:12:04.123Z web-01 payments 4242 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication"][meta seq="9"] ` + "\ufeff" + `checkout failed`
+
+	line := ParseSyslog(raw, time.Now())
+
+	if line.Content != "checkout failed" {
+		t.Errorf("Content: got %q", line.Content)
+	}
+	want := time.Date(2024, 3, 16, 8, 12, 4, 123000000, time.UTC)
+	if line.Timestamp == nil || !line.Timestamp.Equal(want) {
+		t.Errorf("Timestamp: got %v, want %v", line.Timestamp, want)
+	}
+	for key, value := range map[string]string{
+		"facility":                         "local4",
+		"level":                            "info",
+		"host":                             "web-01",
+		"app_name":                         "payments",
+		"procid":                           "4242",
+		"msgid":                            "ID47",
+		"sd.exampleSDID@32473.iut":         "3",
+		"sd.exampleSDID@32473.eventSource": `App"lication`,
+		"sd.meta.seq":                      "9",
+	} {
+		if line.Attrs[key] != value {
+			t.Errorf("attr %s: got %q, want %q", key, line.Attrs[key], value)
+		}
+	}
+}
+
+func TestParseSyslogRFC5424NilValues(t *testing.T) {
+	line := ParseSyslog("<11>1 - - - - - - disk full", time.Now())
+
+	if line.Content != "disk full" {
+		t.Errorf("Content: got %q", line.Content)
+	}
+	if line.Timestamp != nil {
+		t.Errorf("Timestamp: expected nil, got %v", line.Timestamp)
+	}
+	if line.Attrs["level"] != "error" || line.Attrs["facility"] != "user" {
+		t.Errorf("attrs: got %v", line.Attrs)
+	}
+	if _, ok := line.Attrs["host"]; ok {
+		t.Errorf("expected nil host to be skipped, got %v", line.Attrs)
+	}
+}
+
+func TestParseSyslogRFC3164(t *testing.T) {
+	received := time.Date(2024, 3, 16, 9, 0, 0, 0, time.UTC)
+	line := ParseSyslog("<38>Mar 16 08:12:04 myhost sshd[1234]: Accepted publickey for root", received)
+
+	if line.Content != "Accepted publickey for root" {
+		t.Errorf("Content: got %q", line.Content)
+	}
+	want := time.Date(2024, 3, 16, 8, 12, 4, 0, time.UTC)
+	if line.Timestamp == nil || !line.Timestamp.Equal(want) {
+		t.Errorf("Timestamp: got %v, want %v", line.Timestamp, want)
+	}
+	for key, value := range map[string]string{
+		"facility": "auth",
+		"level":    "info",
+		"host":     "myhost",
+		"app_name": "sshd",
+		"procid":   "1234",
+	} {
+		if line.Attrs[key] != value {
+			t.Errorf("attr %s: got %q, want %q", key, line.Attrs[key], value)
+		}
+	}
+}
+
+func TestParseSyslogRFC3164Microseconds(t *testing.T) {
+	received := time.Date(2024, 3, 16, 9, 0, 0, 0, time.UTC)
+	for _, raw := range []string{
+		"<34>Mar 16 08:12:04.123456 myhost sshd[42]: hello",
+		"<34>Mar  6 08:12:04.123456 myhost sshd[42]: hello",
+	} {
+		line := ParseSyslog(raw, received)
+		if line.Content != "hello" {
+			t.Errorf("%q: Content: got %q", raw, line.Content)
+		}
+		if line.Timestamp == nil || line.Timestamp.Nanosecond() != 123456000 {
+			t.Errorf("%q: Timestamp: got %v, want .123456", raw, line.Timestamp)
+		}
+		for key, value := range map[string]string{"host": "myhost", "app_name": "sshd", "procid": "42"} {
+			if line.Attrs[key] != value {
+				t.Errorf("%q: attr %s: got %q, want %q", raw, key, line.Attrs[key], value)
+			}
+		}
+	}
+}
+
+func TestParseSyslogRFC3164YearRollover(t *testing.T) {
+	received := time.Date(2025, 1, 1, 0, 0, 5, 0, time.UTC)
+	line := ParseSyslog("<13>Dec 31 23:59:58 host cron: tick", received)
+
+	want := time.Date(2024, 12, 31, 23, 59, 58, 0, time.UTC)
+	if line.Timestamp == nil || !line.Timestamp.Equal(want) {
+		t.Errorf("Timestamp: got %v, want %v", line.Timestamp, want)
+	}
+}
+
+func TestParseSyslogWithoutPRI(t *testing.T) {
+	line := ParseSyslog("just some text", time.Now())
+	if line.Content != "just some text" || len(line.Attrs) != 0 {
+		t.Errorf("expected plain passthrough, got %q %v", line.Content, line.Attrs)
+	}
+}
+
+func TestSyslogServerUDPAndTCP(t *testing.T) {
+	server, err := NewSyslogServer(SyslogConfig{UDPAddr: "127.0.0.1:0", TCPAddr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("NewSyslogServer: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := server.Ingest(ctx)
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+
+	udp, err := net.Dial("udp", server.UDPAddr().String())
+	if err != nil {
+		t.Fatalf("dial udp: %v", err)
+	}
+	defer func() { _ = udp.Close() }()
+	if _, err := udp.Write([]byte("<14>1 2024-03-16T08:12:04Z host app - - - over udp\n")); err != nil {
+		t.Fatalf("write udp: %v", err)
+	}
+
+	tcp, err := net.Dial("tcp", server.TCPAddr().String())
+	if err != nil {
+		t.Fatalf("dial tcp: %v", err)
+	}
+	defer func() { _ = tcp.Close() }()
+	framed := "<14>1 2024-03-16T08:12:05Z host app - - - octet\ncounted"
+	if _, err := fmt.Fprintf(tcp, "%d %s<14>Mar 16 08:12:06 host app: newline framed\n", len(framed), framed); err != nil {
+		t.Fatalf("write tcp: %v", err)
+	}
+
+	got := make(map[string]bool)
+	timeout := time.After(5 * time.Second)
+	for len(got) < 3 {
+		select {
+		case rr, ok := <-ch:
+			if !ok {
+				t.Fatal("channel closed early")
+			}
+			if rr.Err != nil {
+				t.Fatalf("unexpected error: %v", rr.Err)
+			}
+			got[rr.Value.Content] = true
+		case <-timeout:
+			t.Fatalf("timed out, got %v", got)
+		}
+	}
+
+	for _, want := range []string{"over udp", "octet\ncounted", "newline framed"} {
+		if !got[want] {
+			t.Errorf("missing message %q, got %v", want, got)
+		}
+	}
+
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,172 @@
+package logsource
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// TimelineConfig configures MergeTimeline.
+type TimelineConfig struct {
+	// Lateness is how far a source may step back in time behind the newest
+	// timestamp it has produced and still be merged in order. Items later
+	// than that are emitted as soon as possible instead. Default: 5s.
+	Lateness time.Duration
+}
+
+func (c *TimelineConfig) defaults() {
+	if c.Lateness <= 0 {
+		c.Lateness = 5 * time.Second
+	}
+}
+
+// TimelineItem is one value of a merged timeline.
+type TimelineItem[T any] struct {
+	Value T
+	// Source is the index of the input the value came from.
+	Source int
+	// Timestamp is the ordering key: the value's own timestamp or, if it
+	// has none, that of the previous timestamped value of the same source.
+	// Zero for untimed values at the head of a source.
+	Timestamp time.Time
+	// Seq is the 0-based position in the merged timeline.
+	Seq int64
+}
+
+// MergeTimeline interleaves several sources into one stream ordered by
+// timestamp (a k-way merge). Each source is expected to be roughly
+// chronological: values up to cfg.Lateness older than the newest one seen
+// from the same source are reordered, anything later is emitted as it
+// comes. Values timeOf reports no timestamp for (e.g. continuation lines)
+// stay right after their predecessor. Ties go to the lower source index,
+// then to input order.
+//
+// The output closes once every source has closed; an error from any source
+// is forwarded and ends the merge.
+func MergeTimeline[T any](ctx context.Context, sources []<-chan Result[T], timeOf func(T) (time.Time, bool), cfg TimelineConfig) <-chan Result[TimelineItem[T]] {
+	cfg.defaults()
+	out := make(chan Result[TimelineItem[T]], 100)
+
+	go func() {
+		defer close(out)
+
+		states := make([]*timelineSource[T], len(sources))
+		for i := range sources {
+			states[i] = &timelineSource[T]{index: i}
+		}
+
+		var seq int64
+		for {
+			// Fill every open source until its head may be released.
+			for i, st := range states {
+				for !st.done && !st.ready(cfg.Lateness) {
+					select {
+					case r, ok := <-sources[i]:
+						if !ok {
+							st.done = true
+							continue
+						}
+						if r.Err != nil {
+							select {
+							case out <- Result[TimelineItem[T]]{Err: r.Err}:
+							case <-ctx.Done():
+							}
+							return
+						}
+						st.push(r.Value, timeOf)
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			var next *timelineSource[T]
+			for _, st := range states {
+				if st.buf.Len() > 0 && (next == nil || st.buf[0].before(next.buf[0])) {
+					next = st
+				}
+			}
+			if next == nil {
+				return
+			}
+
+			e := heap.Pop(&next.buf).(*timelineEntry[T])
+			item := TimelineItem[T]{Value: e.value, Source: e.source, Timestamp: e.ts, Seq: seq}
+			seq++
+			select {
+			case out <- Result[TimelineItem[T]]{Value: item}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// timelineSource buffers the values of one source that may still be
+// overtaken by later ones.
+type timelineSource[T any] struct {
+	index   int
+	buf     timelineHeap[T]
+	newest  time.Time
+	last    time.Time
+	arrived int64
+	done    bool
+}
+
+func (s *timelineSource[T]) push(v T, timeOf func(T) (time.Time, bool)) {
+	ts, ok := timeOf(v)
+	if ok {
+		s.last = ts
+		if ts.After(s.newest) {
+			s.newest = ts
+		}
+	} else {
+		ts = s.last
+	}
+	heap.Push(&s.buf, &timelineEntry[T]{value: v, source: s.index, ts: ts, arrival: s.arrived})
+	s.arrived++
+}
+
+// ready reports whether the buffered head can no longer be overtaken by a
+// value still to come from this source.
+func (s *timelineSource[T]) ready(lateness time.Duration) bool {
+	if s.buf.Len() == 0 {
+		return false
+	}
+	return !s.buf[0].ts.After(s.newest.Add(-lateness))
+}
+
+type timelineEntry[T any] struct {
+	value   T
+	source  int
+	ts      time.Time
+	arrival int64
+}
+
+func (e *timelineEntry[T]) before(o *timelineEntry[T]) bool {
+	if !e.ts.Equal(o.ts) {
+		return e.ts.Before(o.ts)
+	}
+	if e.source != o.source {
+		return e.source < o.source
+	}
+	return e.arrival < o.arrival
+}
+
+type timelineHeap[T any] []*timelineEntry[T]
+
+func (h timelineHeap[T]) Len() int           { return len(h) }
+func (h timelineHeap[T]) Less(i, j int) bool { return h[i].before(h[j]) }
+func (h timelineHeap[T]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *timelineHeap[T]) Push(x any) { *h = append(*h, x.(*timelineEntry[T])) }
+
+func (h *timelineHeap[T]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
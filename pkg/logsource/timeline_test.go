@@ -0,0 +1,118 @@
+package logsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+type timedValue struct {
+	name string
+	ts   time.Time
+}
+
+func timelineInput(values ...timedValue) <-chan Result[timedValue] {
+	ch := make(chan Result[timedValue], len(values))
+	for _, v := range values {
+		ch <- Result[timedValue]{Value: v}
+	}
+	close(ch)
+	return ch
+}
+
+func timeOfValue(v timedValue) (time.Time, bool) {
+	return v.ts, !v.ts.IsZero()
+}
+
+func collectTimeline(t *testing.T, ch <-chan Result[TimelineItem[timedValue]]) []TimelineItem[timedValue] {
+	t.Helper()
+	var items []TimelineItem[timedValue]
+	for r := range ch {
+		if r.Err != nil {
+			t.Fatalf("unexpected error: %v", r.Err)
+		}
+		items = append(items, r.Value)
+	}
+	return items
+}
+
+func TestMergeTimeline(t *testing.T) {
+	base := time.Date(2024, 3, 16, 10, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return base.Add(time.Duration(sec) * time.Second) }
+
+	api := timelineInput(
+		timedValue{"api-1", at(1)},
+		timedValue{"api-4", at(4)},
+		timedValue{"api-4-trace", time.Time{}},
+		timedValue{"api-3-late", at(3)},
+		timedValue{"api-9", at(9)},
+	)
+	worker := timelineInput(
+		timedValue{"worker-2", at(2)},
+		timedValue{"worker-4", at(4)},
+		timedValue{"worker-5", at(5)},
+	)
+
+	items := collectTimeline(t, MergeTimeline(context.Background(), []<-chan Result[timedValue]{api, worker}, timeOfValue, TimelineConfig{}))
+
+	want := []string{"api-1", "worker-2", "api-3-late", "api-4", "api-4-trace", "worker-4", "worker-5", "api-9"}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d items, got %d", len(want), len(items))
+	}
+	for i, item := range items {
+		if item.Value.name != want[i] {
+			t.Errorf("item %d: got %s, want %s", i, item.Value.name, want[i])
+		}
+		if item.Seq != int64(i) {
+			t.Errorf("item %d: Seq %d", i, item.Seq)
+		}
+	}
+	if items[4].Timestamp != at(4) || items[4].Source != 0 {
+		t.Errorf("untimed value should inherit its predecessor's timestamp, got %v from source %d", items[4].Timestamp, items[4].Source)
+	}
+}
+
+func TestMergeTimelineLateness(t *testing.T) {
+	base := time.Date(2024, 3, 16, 10, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return base.Add(time.Duration(sec) * time.Second) }
+
+	// api-2 arrives 18s behind api-20, beyond the 5s tolerance: by then
+	// worker-3 and api-10 have been emitted, so it comes out of order.
+	api := timelineInput(timedValue{"api-10", at(10)}, timedValue{"api-20", at(20)}, timedValue{"api-2", at(2)})
+	worker := timelineInput(timedValue{"worker-3", at(3)}, timedValue{"worker-15", at(15)})
+
+	items := collectTimeline(t, MergeTimeline(context.Background(), []<-chan Result[timedValue]{api, worker}, timeOfValue, TimelineConfig{Lateness: 5 * time.Second}))
+
+	var got []string
+	for _, item := range items {
+		got = append(got, item.Value.name)
+	}
+	want := []string{"worker-3", "api-10", "api-2", "worker-15", "api-20"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestMergeTimelineError(t *testing.T) {
+	broken := make(chan Result[timedValue], 1)
+	broken <- Result[timedValue]{Err: errors.New("read failed")}
+	close(broken)
+
+	ch := MergeTimeline(context.Background(), []<-chan Result[timedValue]{timelineInput(), broken}, timeOfValue, TimelineConfig{})
+	var gotErr error
+	for r := range ch {
+		if r.Err != nil {
+			gotErr = r.Err
+		}
+	}
+	if gotErr == nil {
+		t.Fatal("expected the source error to be forwarded")
+	}
+}
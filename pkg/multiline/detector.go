@@ -6,69 +6,14 @@
 
 package multiline
 
-import "regexp"
-
-// knownTimestampFormats is the list of known timestamp formats used to build
-// the token graph. Adding similar or partial duplicate timestamps does not
-// impact accuracy since related tokens are inherently deduped in the graph.
-var knownTimestampFormats = []string{
-	"2024-03-28T13:45:30.123456Z",
-	"28/Mar/2024:13:45:30",
-	"Sun, 28 Mar 2024 13:45:30",
-	"2024-03-28 13:45:30",
-	"2024-03-28 13:45:30,123",
-	"02 Jan 06 15:04 MST",
-	"2024-03-28T14:33:53.743350Z",
-	"2024-03-28T15:19:38.578639+00:00",
-	"2024-03-28 15:44:53",
-	"2024-08-20'T'13:20:10*633+0000",
-	"2024 Mar 03 05:12:41.211 PDT",
-	"Jan 21 18:20:11 +0000 2024",
-	"19/Apr/2024:06:36:15",
-	"Dec 2, 2024 2:39:58 AM",
-	"Jun 09 2024 15:28:14",
-	"Apr 20 00:00:35 2010",
-	"Sep 28 19:00:00 +0000",
-	"Mar 16 08:12:04",
-	"Jul 1 09:00:55",
-	"2024-10-14T22:11:20+0000",
-	"2024-07-01T14:59:55.711",
-	"2024-07-01T14:59:55.711Z",
-	"2024-08-19 12:17:55-0400",
-	"2024-06-26 02:31:29,573",
-	"2024/04/12*19:37:50",
-	"2024 Apr 13 22:08:13.211*PDT",
-	"2024 Mar 10 01:44:20.392",
-	"2024-03-10 14:30:12,655+0000",
-	"2024-02-27 15:35:20.311",
-	"2024-07-22'T'16:28:55.444",
-	"2024-11-22'T'10:10:15.455",
-	"2024-02-11'T'18:31:44",
-	"2024-10-30*02:47:33:899",
-	"2024-07-04*13:23:55",
-	"24-02-11 16:47:35,985 +0000",
-	"24-06-26 02:31:29,573",
-	"24-04-19 12:00:17",
-	"06/01/24 04:11:05",
-	"08/10/24*13:33:56",
-	"11/24/2024*05:13:11",
-	"05/09/2024*08:22:14*612",
-	"04/23/24 04:34:22 +0000",
-	"2024/04/25 14:57:42",
-	"11:42:35.173",
-	"11:42:35,173",
-	"23/Apr 11:42:35,173",
-	"23/Apr/2024:11:42:35",
-	"23/Apr/2024 11:42:35",
-	"23-Apr-2024 11:42:35",
-	"23-Apr-2024 11:42:35.883",
-	"23 Apr 2024 11:42:35",
-	"23 Apr 2024 10:32:35*311",
-	"8/5/2024 3:31:18 AM:234",
-	"9/28/2024 2:23:15 PM",
-	"2023-03.28T14-33:53-7430Z",
-	"2017-05-16_13:53:08",
-}
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/strrl/lapp/pkg/timestamp"
+)
 
 var staticTokenGraph = makeStaticTokenGraph()
 
@@ -76,8 +21,8 @@ const minimumTokenLength = 8
 
 func makeStaticTokenGraph() *tokenGraph {
 	tok := newTokenizer(100)
-	inputData := make([][]Token, len(knownTimestampFormats))
-	for i, format := range knownTimestampFormats {
+	inputData := make([][]Token, len(timestamp.Samples))
+	for i, format := range timestamp.Samples {
 		tokens, _ := tok.tokenize([]byte(format))
 		inputData[i] = tokens
 	}
@@ -102,8 +47,64 @@ type DetectorConfig struct {
 	// Entries exceeding this are flushed regardless of detection.
 	// Default: 65536 (64KB).
 	MaxEntryBytes int
+
+	// StackTraces enables language-aware stack trace grouping: lines that
+	// continue a Java, Python, Go, Node.js, .NET or Ruby stack trace are
+	// merged into its entry, even in logs without leading timestamps.
+	StackTraces bool
+
+	// Payloads enables brace-balanced merging of pretty-printed JSON and
+	// XML: once a line opens a payload that it does not close, the
+	// following lines are merged into its entry until the payload is
+	// balanced again. A line that opens a payload at its first byte starts
+	// an entry of its own, so the whole payload can be parsed as one.
+	Payloads bool
+
+	// Learn adapts detection to each source: the first LearnSampleLines
+	// lines of a merge run are sampled for the signature most entries start
+	// with (see Detector.Learn). Default: LearnOff.
+	Learn LearnMode
+
+	// LearnSampleLines is how many lines Learn samples. Default: 500.
+	LearnSampleLines int
+
+	// Pattern, Negate and Match are Filebeat-style multiline rules. When
+	// Pattern is set it replaces timestamp detection and learning: lines
+	// matching Pattern (or, with Negate, lines not matching it) are
+	// continuation lines, appended to the line before them with Match
+	// "after" or prepended to the line after them with Match "before".
+	// Pattern cannot be combined with FirstLineRegex, which is the same as
+	// Pattern with Negate and Match "after", except that it falls back to
+	// line-by-line output until it first matches. Default Match: "after".
+	Pattern string
+	Negate  bool
+	Match   MatchMode
+
+	// MaxLines is the maximum number of physical lines of a merged entry.
+	// Longer entries are split like those exceeding MaxEntryBytes. 0 means
+	// no limit.
+	MaxLines int
+
+	// QuotedRecords reads CSV-style records instead: every line starts a
+	// new entry unless the line before it ended inside a double-quoted
+	// field. It replaces all other detection.
+	QuotedRecords bool
+
+	// FlushTimeout makes Merge emit a buffered entry once no line has
+	// arrived for this long, instead of holding it until the next entry
+	// starts or the input closes, as following a live source needs. 0
+	// disables it; MergeSlice ignores it.
+	FlushTimeout time.Duration
 }
 
+// MatchMode says which neighbor Filebeat-style continuation lines join.
+type MatchMode string
+
+const (
+	MatchAfter  MatchMode = "after"
+	MatchBefore MatchMode = "before"
+)
+
 func (c *DetectorConfig) defaults() {
 	if c.MaxScanBytes == 0 {
 		c.MaxScanBytes = 60
@@ -114,6 +115,12 @@ func (c *DetectorConfig) defaults() {
 	if c.MaxEntryBytes == 0 {
 		c.MaxEntryBytes = 65536
 	}
+	if c.LearnSampleLines == 0 {
+		c.LearnSampleLines = 500
+	}
+	if c.Match == "" {
+		c.Match = MatchAfter
+	}
 }
 
 // Detector determines whether a log line is the start of a new log entry.
@@ -124,11 +131,30 @@ type Detector struct {
 	firstLineRegex *regexp.Regexp
 	maxScanBytes   int
 	maxEntryBytes  int
+	stackTraces    bool
+	payloads       bool
+	learn          LearnMode
+	learnSample    int
+	pattern        *regexp.Regexp
+	negate         bool
+	match          MatchMode
+	maxLines       int
+	flushTimeout   time.Duration
+	quotedRecords  bool
 }
 
 // NewDetector creates a new multiline entry boundary detector.
 func NewDetector(cfg DetectorConfig) (*Detector, error) {
 	cfg.defaults()
+	if err := cfg.Learn.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Match != MatchAfter && cfg.Match != MatchBefore {
+		return nil, errors.Errorf("unknown match mode %q", cfg.Match)
+	}
+	if cfg.Pattern != "" && cfg.FirstLineRegex != "" {
+		return nil, errors.New("pattern and first line regex are mutually exclusive")
+	}
 
 	var re *regexp.Regexp
 	if cfg.FirstLineRegex != "" {
@@ -138,6 +164,14 @@ func NewDetector(cfg DetectorConfig) (*Detector, error) {
 			return nil, err
 		}
 	}
+	var pattern *regexp.Regexp
+	if cfg.Pattern != "" {
+		var err error
+		pattern, err = regexp.Compile(cfg.Pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return &Detector{
 		tokenizer:      newTokenizer(cfg.MaxScanBytes),
@@ -146,6 +180,16 @@ func NewDetector(cfg DetectorConfig) (*Detector, error) {
 		firstLineRegex: re,
 		maxScanBytes:   cfg.MaxScanBytes,
 		maxEntryBytes:  cfg.MaxEntryBytes,
+		stackTraces:    cfg.StackTraces,
+		payloads:       cfg.Payloads,
+		learn:          cfg.Learn,
+		learnSample:    cfg.LearnSampleLines,
+		pattern:        pattern,
+		negate:         cfg.Negate,
+		match:          cfg.Match,
+		maxLines:       cfg.MaxLines,
+		flushTimeout:   cfg.FlushTimeout,
+		quotedRecords:  cfg.QuotedRecords,
 	}, nil
 }
 
@@ -156,19 +200,123 @@ func (d *Detector) IsNewEntry(line string) bool {
 		return d.firstLineRegex.MatchString(line)
 	}
 
+	_, match := d.score(line)
+	return match.probability > d.threshold
+}
+
+// score tokenizes the head of line and matches it against the timestamp
+// token graph.
+func (d *Detector) score(line string) ([]Token, matchContext) {
 	scanLen := len(line)
 	if scanLen > d.maxScanBytes {
 		scanLen = d.maxScanBytes
 	}
 	if scanLen == 0 {
-		return false
+		return nil, matchContext{}
 	}
 
 	tokens, _ := d.tokenizer.tokenize([]byte(line[:scanLen]))
-	return d.tokenGraph.matchProbability(tokens).probability > d.threshold
+	return tokens, d.tokenGraph.matchProbability(tokens)
 }
 
 // MaxEntryBytes returns the configured maximum entry size.
 func (d *Detector) MaxEntryBytes() int {
 	return d.maxEntryBytes
 }
+
+// LearnSampleLines returns how many leading lines of a source are sampled
+// for learning, or 0 if learning is off.
+func (d *Detector) LearnSampleLines() int {
+	if d.learn == LearnOff || d.firstLineRegex != nil || d.pattern != nil || d.quotedRecords {
+		return 0
+	}
+	return d.learnSample
+}
+
+// entrySplitter decides entry boundaries for one merge run. Until the first
+// line detected as a new entry every line is its own entry, so logs without
+// timestamps are passed through line by line.
+type entrySplitter struct {
+	detector     *Detector
+	trace        *stackTracer
+	payload      *payloadTracker
+	rule         *LearnedRule
+	everDetected bool
+	// prevContinues records whether the previous line was a continuation
+	// line under the Filebeat-style rules.
+	prevContinues bool
+	// inQuote records whether a quoted field is open under QuotedRecords.
+	inQuote bool
+}
+
+func (d *Detector) newSplitter() *entrySplitter {
+	s := &entrySplitter{detector: d}
+	if d.stackTraces {
+		s.trace = &stackTracer{}
+	}
+	if d.payloads {
+		s.payload = &payloadTracker{}
+	}
+	return s
+}
+
+// learn sets the rule learned from the head of the source.
+func (s *entrySplitter) learn(sample []string) *LearnedRule {
+	s.rule = s.detector.Learn(sample)
+	return s.rule
+}
+
+// startsEntry reports whether line begins a new entry.
+func (s *entrySplitter) startsEntry(line string) bool {
+	if s.detector.quotedRecords {
+		starts := !s.inQuote
+		// An escaped quote ("") toggles twice, so parity is enough.
+		if strings.Count(line, `"`)%2 == 1 {
+			s.inQuote = !s.inQuote
+		}
+		return starts
+	}
+	if s.detector.pattern != nil {
+		return s.startsEntryByPattern(line)
+	}
+	if s.payload != nil {
+		continues, starts := s.payload.next(line)
+		if continues {
+			return false
+		}
+		if starts {
+			return true
+		}
+	}
+
+	var isNew bool
+	switch {
+	case s.rule == nil:
+		isNew = s.detector.IsNewEntry(line)
+	case s.detector.learn == LearnReplace:
+		isNew = s.detector.matchesRule(s.rule, line)
+	default:
+		isNew = s.detector.IsNewEntry(line) || s.detector.matchesRule(s.rule, line)
+	}
+	if isNew {
+		s.everDetected = true
+	}
+	if s.trace != nil && s.trace.continues(line) {
+		return false
+	}
+	return isNew || !s.everDetected
+}
+
+func (s *entrySplitter) startsEntryByPattern(line string) bool {
+	continues := s.detector.pattern.MatchString(line) != s.detector.negate
+	prevContinues := s.prevContinues
+	s.prevContinues = continues
+	if s.trace != nil && s.trace.continues(line) {
+		return false
+	}
+	if s.detector.match == MatchBefore {
+		// A continuation line holds its entry open for the next line.
+		return !prevContinues
+	}
+	return !continues
+}
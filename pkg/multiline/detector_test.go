@@ -85,3 +85,15 @@ func TestDetectorInvalidRegex(t *testing.T) {
 		t.Error("expected error for invalid regex")
 	}
 }
+
+func TestDetectorInvalidRules(t *testing.T) {
+	for name, cfg := range map[string]DetectorConfig{
+		"pattern with first line regex": {Pattern: `^\s`, FirstLineRegex: `^\d`},
+		"unknown match":                 {Pattern: `^\s`, Match: "around"},
+		"invalid pattern":               {Pattern: `[invalid`},
+	} {
+		if _, err := NewDetector(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
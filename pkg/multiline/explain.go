@@ -0,0 +1,31 @@
+package multiline
+
+// Explanation shows how IsNewEntry judged a line, for debugging entry
+// boundaries.
+type Explanation struct {
+	// Tokens is the tokenized prefix of the line, e.g. "DDDD-DD-DD DD:DD:DD"
+	// for a line starting with "2024-03-28 13:45:30".
+	Tokens string
+	// Match is the run of Tokens that best fits the known timestamp
+	// formats and Probability its score; empty and 0 if nothing matched.
+	Match       string
+	Probability float64
+	// IsNewEntry is the decision: Probability above the threshold, or a
+	// FirstLineRegex match if one is set.
+	IsNewEntry bool
+}
+
+// Explain returns the intermediate results behind IsNewEntry(line).
+func (d *Detector) Explain(line string) Explanation {
+	tokens, match := d.score(line)
+	e := Explanation{
+		Tokens:      tokensToString(tokens),
+		Probability: match.probability,
+		IsNewEntry:  d.IsNewEntry(line),
+	}
+	// A match spans the tokens around the transitions start..end-1.
+	if match.end > match.start {
+		e.Match = tokensToString(tokens[match.start : match.end+1])
+	}
+	return e
+}
@@ -0,0 +1,36 @@
+package multiline
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	d, err := NewDetector(DetectorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := d.Explain("2024-03-28 13:45:30 INFO started")
+	if !e.IsNewEntry || e.Probability <= 0.5 {
+		t.Errorf("timestamped line: got %+v", e)
+	}
+	if !strings.HasPrefix(e.Match, "DDDD-DD-DD DD:DD:DD") {
+		t.Errorf("match: got %q", e.Match)
+	}
+	if !strings.HasPrefix(e.Tokens, e.Match) {
+		t.Errorf("tokens %q do not start with match %q", e.Tokens, e.Match)
+	}
+
+	e = d.Explain("\tat com.example.Foo.bar(Foo.java:42)")
+	if e.IsNewEntry || e.Tokens == "" {
+		t.Errorf("continuation line: got %+v", e)
+	}
+	if e.IsNewEntry != d.IsNewEntry("\tat com.example.Foo.bar(Foo.java:42)") {
+		t.Error("Explain disagrees with IsNewEntry")
+	}
+
+	if e := d.Explain(""); e.IsNewEntry || e.Tokens != "" || e.Match != "" {
+		t.Errorf("empty line: got %+v", e)
+	}
+}
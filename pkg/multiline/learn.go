@@ -0,0 +1,169 @@
+package multiline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// LearnMode selects how the detector adapts to each source.
+type LearnMode string
+
+const (
+	// LearnOff uses timestamp detection only.
+	LearnOff LearnMode = ""
+	// LearnExtend treats lines starting with the learned signature as new
+	// entries in addition to timestamped lines.
+	LearnExtend LearnMode = "extend"
+	// LearnReplace detects new entries by the learned signature alone,
+	// falling back to timestamp detection if nothing was learned.
+	LearnReplace LearnMode = "replace"
+)
+
+func (m LearnMode) validate() error {
+	switch m {
+	case LearnOff, LearnExtend, LearnReplace:
+		return nil
+	}
+	return errors.Errorf("unknown learn mode %q", m)
+}
+
+const (
+	// signatureTokens is how many leading tokens make up a signature.
+	signatureTokens = 6
+	// minLearnedLines is the fewest sampled lines a signature must start
+	// for it to be learned.
+	minLearnedLines = 3
+)
+
+// LearnedRule is the start-of-entry signature learned from the head of a
+// source: the shape of the first few tokens most sampled lines begin with,
+// e.g. "[C-D] " for "[req-123] GET /health" (C is a run of letters, D a run
+// of digits).
+type LearnedRule struct {
+	Signature string
+	// Matched is how many of the Sampled non-blank lines start with the
+	// signature.
+	Matched int
+	Sampled int
+	// Example is the first sampled line with the signature.
+	Example string
+
+	tokens []Token
+}
+
+// String describes the rule for review.
+func (r *LearnedRule) String() string {
+	return fmt.Sprintf("entries start with %q (%d of %d sampled lines), e.g. %q", r.Signature, r.Matched, r.Sampled, r.Example)
+}
+
+// Learn finds the dominant start-of-entry signature of lines, typically the
+// first DetectorConfig.LearnSampleLines of a source. It returns nil if no
+// signature qualifies: one must start at least 10% of the non-blank lines,
+// must not start with whitespace (continuation lines usually do) and must
+// contain more than words and spaces, so ordinary prose is never mistaken
+// for an entry header. Learn is not used when FirstLineRegex or Pattern is
+// set.
+func (d *Detector) Learn(lines []string) *LearnedRule {
+	if d.firstLineRegex != nil || d.pattern != nil {
+		return nil
+	}
+
+	type candidate struct {
+		tokens  []Token
+		count   int
+		example string
+	}
+	var (
+		candidates = make(map[string]*candidate)
+		order      []string
+		sampled    int
+	)
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		sampled++
+		sig := d.signature(line)
+		if !learnableSignature(sig) {
+			continue
+		}
+		key := tokensToString(sig)
+		c, ok := candidates[key]
+		if !ok {
+			c = &candidate{tokens: sig, example: line}
+			candidates[key] = c
+			order = append(order, key)
+		}
+		c.count++
+	}
+
+	var best *candidate
+	var bestKey string
+	for _, key := range order {
+		if c := candidates[key]; best == nil || c.count > best.count {
+			best, bestKey = c, key
+		}
+	}
+	if best == nil || best.count < minLearnedLines || best.count*10 < sampled {
+		return nil
+	}
+	return &LearnedRule{
+		Signature: bestKey,
+		Matched:   best.count,
+		Sampled:   sampled,
+		Example:   best.example,
+		tokens:    best.tokens,
+	}
+}
+
+// matchesRule reports whether line starts with the rule's signature.
+func (d *Detector) matchesRule(r *LearnedRule, line string) bool {
+	sig := d.signature(line)
+	if len(sig) != len(r.tokens) {
+		return false
+	}
+	for i := range sig {
+		if sig[i] != r.tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// signature returns the first signatureTokens tokens of line with letter
+// and digit runs collapsed to a single token regardless of their length,
+// so "[req-7]" and "[req-123]" share a signature.
+func (d *Detector) signature(line string) []Token {
+	scanLen := min(len(line), d.maxScanBytes)
+	if scanLen == 0 {
+		return nil
+	}
+	tokens, _ := d.tokenizer.tokenize([]byte(line[:scanLen]))
+	if len(tokens) < signatureTokens {
+		return nil
+	}
+	sig := tokens[:signatureTokens]
+	for i, t := range sig {
+		switch {
+		case t >= tD1 && t <= tD10:
+			sig[i] = tD1
+		case t >= tC1 && t <= tC10:
+			sig[i] = tC1
+		}
+	}
+	return sig
+}
+
+func learnableSignature(sig []Token) bool {
+	if len(sig) == 0 || sig[0] == tSpace {
+		return false
+	}
+	for _, t := range sig {
+		if t != tC1 && t != tSpace {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package multiline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/strrl/lapp/pkg/logsource"
+)
+
+var requestIDLines = []string{
+	"[req-7] GET /health 200",
+	"[req-8] POST /orders 500",
+	"request body:",
+	`  {"item": 42}`,
+	"[req-9] GET /orders/42 200",
+	"[req-123] DELETE /orders/42 204",
+}
+
+func TestLearnRequestIDPrefix(t *testing.T) {
+	d, err := NewDetector(DetectorConfig{Learn: LearnExtend})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule := d.Learn(requestIDLines)
+	if rule == nil {
+		t.Fatal("expected a learned rule")
+	}
+	if rule.Signature != "[C-D] " || rule.Matched != 4 || rule.Sampled != 6 {
+		t.Errorf("unexpected rule: %s", rule)
+	}
+	if rule.Example != requestIDLines[0] {
+		t.Errorf("Example: got %q", rule.Example)
+	}
+
+	merged := MergeSlice(context.Background(), requestIDLines, d)
+	want := [][2]int{{1, 1}, {2, 4}, {5, 5}, {6, 6}}
+	if len(merged) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(merged))
+	}
+	for i, r := range want {
+		if merged[i].StartLine != r[0] || merged[i].EndLine != r[1] {
+			t.Errorf("entry %d: expected lines %d-%d, got %d-%d", i, r[0], r[1], merged[i].StartLine, merged[i].EndLine)
+		}
+	}
+}
+
+func TestLearnKlogReplace(t *testing.T) {
+	lines := []string{
+		"I0314 12:00:00.123456       1 main.go:42] Starting controller",
+		"W0314 12:00:01.000001       1 sync.go:88] Retrying sync:",
+		"object has been modified; please apply your changes",
+		"to the latest version and try again",
+		"E0314 12:00:02.500000       1 sync.go:91] Sync failed",
+	}
+	d, err := NewDetector(DetectorConfig{Learn: LearnReplace})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged := MergeSlice(context.Background(), lines, d)
+	want := [][2]int{{1, 1}, {2, 4}, {5, 5}}
+	if len(merged) != len(want) {
+		for i, m := range merged {
+			t.Logf("entry %d: lines %d-%d: %s", i, m.StartLine, m.EndLine, truncate(m.Content))
+		}
+		t.Fatalf("expected %d entries, got %d", len(want), len(merged))
+	}
+	for i, r := range want {
+		if merged[i].StartLine != r[0] || merged[i].EndLine != r[1] {
+			t.Errorf("entry %d: expected lines %d-%d, got %d-%d", i, r[0], r[1], merged[i].StartLine, merged[i].EndLine)
+		}
+	}
+}
+
+func TestLearnIgnoresProse(t *testing.T) {
+	d, err := NewDetector(DetectorConfig{Learn: LearnExtend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		"starting the server now",
+		"loading the config file",
+		"listening on every interface",
+		"waiting for new clients",
+	}
+	if rule := d.Learn(lines); rule != nil {
+		t.Fatalf("expected no rule for prose, got %s", rule)
+	}
+	if merged := MergeSlice(context.Background(), lines, d); len(merged) != len(lines) {
+		t.Errorf("expected line-by-line fallback, got %d entries", len(merged))
+	}
+}
+
+func TestLearnInvalidMode(t *testing.T) {
+	if _, err := NewDetector(DetectorConfig{Learn: "sometimes"}); err == nil {
+		t.Fatal("expected error for unknown learn mode")
+	}
+}
+
+func TestMergeChannelLearns(t *testing.T) {
+	d, err := NewDetector(DetectorConfig{Learn: LearnExtend, LearnSampleLines: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan logsource.Result[*logsource.LogLine], len(requestIDLines))
+	for i, line := range requestIDLines {
+		in <- logsource.Result[*logsource.LogLine]{Value: &logsource.LogLine{LineNumber: i + 1, Content: line}}
+	}
+	close(in)
+
+	// The rule is learned from the first five lines only, then applied
+	// to the rest of the stream.
+	var merged []*MergedLine
+	for mr := range Merge(context.Background(), in, d) {
+		if mr.Err != nil {
+			t.Fatal(mr.Err)
+		}
+		merged = append(merged, mr.Value)
+	}
+	if len(merged) != 4 || merged[1].StartLine != 2 || merged[1].EndLine != 4 {
+		for i, m := range merged {
+			t.Logf("entry %d: lines %d-%d", i, m.StartLine, m.EndLine)
+		}
+		t.Fatalf("expected 4 entries with lines 2-4 merged, got %d", len(merged))
+	}
+}
@@ -1,14 +1,9 @@
-// TODO: Consider upgrading to Fluent Bit-style state machine for
-// language-specific stack trace parsing (Java `Caused by:` chains,
-// Python `File "..."` frames, Go goroutine dumps). The current
-// timestamp-only approach handles 99%+ of cases but can't semantically
-// understand continuation structure.
-
 package multiline
 
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/strrl/lapp/pkg/logsource"
 	"go.opentelemetry.io/otel"
@@ -21,6 +16,19 @@ type MergedLine struct {
 	StartLine int
 	EndLine   int
 	Content   string
+	// Timestamp and Attrs carry source-level metadata from the entry's
+	// first physical line (see logsource.LogLine).
+	Timestamp *time.Time
+	Attrs     map[string]string
+	// EndOffset is the source byte offset just past the entry's last
+	// physical line (see logsource.LogLine.Offset).
+	EndOffset int64
+	// Truncated reports that the entry was cut short by MaxEntryBytes or
+	// MaxLines; its remaining lines follow in the next entry.
+	Truncated bool
+	// Continuation reports that the entry holds the rest of a Truncated
+	// entry rather than starting a new one.
+	Continuation bool
 }
 
 // MergeResult wraps either a successfully merged line or an error from the input stream.
@@ -29,11 +37,85 @@ type MergeResult struct {
 	Err   error
 }
 
+// mergeState is the buffering state machine shared by Merge and
+// MergeSlice: it collects physical lines into the current entry and emits
+// the entry when a new one starts or a size limit is reached.
+type mergeState struct {
+	detector *Detector
+	splitter *entrySplitter
+	emit     func(MergedLine)
+
+	buf          []string
+	first        *logsource.LogLine
+	endLine      int
+	endOffset    int64
+	bufBytes     int
+	continuation bool
+}
+
+func newMergeState(detector *Detector, emit func(MergedLine)) *mergeState {
+	return &mergeState{detector: detector, splitter: detector.newSplitter(), emit: emit}
+}
+
+// add appends line to the current entry, first emitting the buffered entry
+// if line starts a new one or would push it past a size limit.
+func (s *mergeState) add(line *logsource.LogLine) {
+	if s.splitter.startsEntry(line.Content) {
+		s.flush(false)
+		s.continuation = false
+	}
+
+	// Check overflow before updating endLine so flush uses correct range
+	newSize := s.bufBytes + len(line.Content)
+	if len(s.buf) > 0 {
+		newSize++
+	}
+	if len(s.buf) > 0 && (newSize > s.detector.MaxEntryBytes() ||
+		(s.detector.maxLines > 0 && len(s.buf) >= s.detector.maxLines)) {
+		s.flush(true)
+	}
+
+	if len(s.buf) == 0 {
+		s.first = line
+		s.bufBytes = len(line.Content)
+	} else {
+		s.bufBytes = newSize
+	}
+	s.endLine = line.LineNumber
+	s.endOffset = line.Offset
+
+	s.buf = append(s.buf, line.Content)
+}
+
+// flush emits the buffered entry, if any. truncated marks an entry cut
+// short by a size limit, so the entry after it is a continuation.
+func (s *mergeState) flush(truncated bool) {
+	if len(s.buf) == 0 {
+		return
+	}
+	s.emit(MergedLine{
+		StartLine:    s.first.LineNumber,
+		EndLine:      s.endLine,
+		Content:      strings.Join(s.buf, "\n"),
+		Timestamp:    s.first.Timestamp,
+		Attrs:        s.first.Attrs,
+		EndOffset:    s.endOffset,
+		Truncated:    truncated,
+		Continuation: s.continuation,
+	})
+	s.continuation = truncated
+	s.buf = s.buf[:0]
+	s.bufBytes = 0
+}
+
 // Merge reads physical lines from in and merges continuation lines into
 // logical entries using the provided detector to identify entry boundaries.
 // It propagates read errors from the ingestor Result channel.
 // If no line is ever detected as a new entry (i.e. no recognizable timestamp),
-// each physical line is emitted as its own entry to avoid behavioral regression.
+// each physical line is emitted as its own entry to avoid behavioral
+// regression, except for stack trace continuations when
+// DetectorConfig.StackTraces is set. With DetectorConfig.FlushTimeout, a
+// buffered entry is emitted once the input has been idle that long.
 func Merge(ctx context.Context, in <-chan logsource.Result[*logsource.LogLine], detector *Detector) <-chan MergeResult {
 	_, span := otel.Tracer("lapp/multiline").Start(ctx, "multiline.Merge")
 
@@ -42,68 +124,84 @@ func Merge(ctx context.Context, in <-chan logsource.Result[*logsource.LogLine],
 		defer close(out)
 		defer span.End()
 
-		var buf []string
-		startLine := 0
-		endLine := 0
-		bufBytes := 0
-		everDetected := false
+		state := newMergeState(detector, func(m MergedLine) {
+			out <- MergeResult{Value: &m}
+		})
 
-		flush := func() {
-			if len(buf) == 0 {
-				return
+		// receive waits for the next line. With a flush timeout and lines
+		// held back, it gives up once the input has been idle that long.
+		var timer *time.Timer
+		if detector.flushTimeout > 0 {
+			timer = time.NewTimer(detector.flushTimeout)
+			timer.Stop()
+		}
+		receive := func(holding bool) (rr logsource.Result[*logsource.LogLine], ok, idle bool) {
+			if timer == nil || !holding {
+				rr, ok = <-in
+				return rr, ok, false
 			}
-			out <- MergeResult{
-				Value: &MergedLine{
-					StartLine: startLine,
-					EndLine:   endLine,
-					Content:   strings.Join(buf, "\n"),
-				},
+			timer.Reset(detector.flushTimeout)
+			defer timer.Stop()
+			select {
+			case rr, ok = <-in:
+				return rr, ok, false
+			case <-timer.C:
+				return rr, true, true
 			}
-			buf = buf[:0]
-			bufBytes = 0
 		}
 
-		for rr := range in {
-			if rr.Err != nil {
-				out <- MergeResult{Err: rr.Err}
-				return
+		// Hold back the head of the source until a rule is learned from
+		// it, or until the input goes idle.
+		if n := detector.LearnSampleLines(); n > 0 {
+			var sample []*logsource.LogLine
+			sampleIdle := false
+			for len(sample) < n {
+				rr, ok, idle := receive(len(sample) > 0)
+				if !ok {
+					break
+				}
+				if idle {
+					sampleIdle = true
+					break
+				}
+				if rr.Err != nil {
+					out <- MergeResult{Err: rr.Err}
+					return
+				}
+				sample = append(sample, rr.Value)
 			}
-			line := rr.Value
-			isNew := detector.IsNewEntry(line.Content)
-			if isNew {
-				everDetected = true
+			contents := make([]string, len(sample))
+			for i, line := range sample {
+				contents[i] = line.Content
 			}
-
-			// If we have never seen a timestamp, fall back to line-by-line
-			if !everDetected && len(buf) > 0 {
-				flush()
+			if rule := state.splitter.learn(contents); rule != nil {
+				span.SetAttributes(attribute.String("multiline.learned_rule", rule.Signature))
 			}
-
-			if isNew && len(buf) > 0 {
-				flush()
+			for _, line := range sample {
+				state.add(line)
+			}
+			if sampleIdle {
+				state.flush(false)
 			}
+		}
 
-			// Check overflow before updating endLine so flush uses correct range
-			newSize := bufBytes + len(line.Content)
-			if len(buf) > 0 {
-				newSize++
+		for {
+			rr, ok, idle := receive(len(state.buf) > 0)
+			if !ok {
+				break
 			}
-			if newSize > detector.MaxEntryBytes() && len(buf) > 0 {
-				flush()
+			if idle {
+				state.flush(false)
+				continue
 			}
-
-			if len(buf) == 0 {
-				startLine = line.LineNumber
-				bufBytes = len(line.Content)
-			} else {
-				bufBytes = newSize
+			if rr.Err != nil {
+				out <- MergeResult{Err: rr.Err}
+				return
 			}
-			endLine = line.LineNumber
-
-			buf = append(buf, line.Content)
+			state.add(rr.Value)
 		}
 
-		flush()
+		state.flush(false)
 	}()
 	return out
 }
@@ -122,60 +220,19 @@ func MergeSlice(ctx context.Context, lines []string, detector *Detector) []Merge
 	}
 
 	var result []MergedLine
-	var buf []string
-	startLine := 0
-	endLine := 0
-	bufBytes := 0
-	everDetected := false
-
-	flush := func() {
-		if len(buf) == 0 {
-			return
+	state := newMergeState(detector, func(m MergedLine) {
+		result = append(result, m)
+	})
+	if n := detector.LearnSampleLines(); n > 0 {
+		if rule := state.splitter.learn(lines[:min(n, len(lines))]); rule != nil {
+			span.SetAttributes(attribute.String("multiline.learned_rule", rule.Signature))
 		}
-		result = append(result, MergedLine{
-			StartLine: startLine,
-			EndLine:   endLine,
-			Content:   strings.Join(buf, "\n"),
-		})
-		buf = buf[:0]
-		bufBytes = 0
 	}
 
 	for i, line := range lines {
-		lineNum := i + 1
-		isNew := detector.IsNewEntry(line)
-		if isNew {
-			everDetected = true
-		}
-
-		if !everDetected && len(buf) > 0 {
-			flush()
-		}
-
-		if isNew && len(buf) > 0 {
-			flush()
-		}
-
-		newSize := bufBytes + len(line)
-		if len(buf) > 0 {
-			newSize++
-		}
-		if newSize > detector.MaxEntryBytes() && len(buf) > 0 {
-			flush()
-		}
-
-		if len(buf) == 0 {
-			startLine = lineNum
-			bufBytes = len(line)
-		} else {
-			bufBytes = newSize
-		}
-		endLine = lineNum
-
-		buf = append(buf, line)
+		state.add(&logsource.LogLine{LineNumber: i + 1, Content: line})
 	}
-
-	flush()
+	state.flush(false)
 
 	span.SetAttributes(attribute.Int("merged.entries", len(result)))
 	return result
@@ -3,6 +3,7 @@ package multiline
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/strrl/lapp/pkg/logsource"
 )
@@ -210,3 +211,223 @@ func TestMergeSliceOverflowLineRange(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeChannelKeepsFirstLineMetadata(t *testing.T) {
+	d, err := NewDetector(DetectorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Date(2024, 3, 28, 13, 45, 31, 0, time.UTC)
+	ch := make(chan logsource.Result[*logsource.LogLine], 2)
+	ch <- logsource.Result[*logsource.LogLine]{Value: &logsource.LogLine{
+		LineNumber: 1,
+		Content:    "2024-03-28 13:45:31 ERROR something broke",
+		Timestamp:  &ts,
+		Attrs:      map[string]string{"stream": "stderr"},
+	}}
+	ch <- logsource.Result[*logsource.LogLine]{Value: &logsource.LogLine{
+		LineNumber: 2,
+		Content:    "\tat com.example.Foo.bar(Foo.java:42)",
+		Attrs:      map[string]string{"stream": "stdout"},
+	}}
+	close(ch)
+
+	var results []MergedLine
+	for m := range Merge(context.Background(), ch, d) {
+		if m.Err != nil {
+			t.Fatalf("unexpected error: %v", m.Err)
+		}
+		results = append(results, *m.Value)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 merged entry, got %d", len(results))
+	}
+	if results[0].Timestamp == nil || !results[0].Timestamp.Equal(ts) {
+		t.Errorf("expected timestamp %v, got %v", ts, results[0].Timestamp)
+	}
+	if results[0].Attrs["stream"] != "stderr" {
+		t.Errorf("expected stream stderr, got %q", results[0].Attrs["stream"])
+	}
+}
+
+func TestMergeSliceFilebeatRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    DetectorConfig
+		lines  []string
+		ranges [][2]int
+	}{
+		{
+			// Indented lines continue the line before them.
+			name:   "continuation pattern after",
+			cfg:    DetectorConfig{Pattern: `^\s`, Match: MatchAfter},
+			lines:  []string{"request failed", "  retry 1", "  retry 2", "request ok", "done"},
+			ranges: [][2]int{{1, 3}, {4, 4}, {5, 5}},
+		},
+		{
+			// Lines ending in a backslash continue onto the next line.
+			name:   "continuation pattern before",
+			cfg:    DetectorConfig{Pattern: `\\$`, Match: MatchBefore},
+			lines:  []string{`SELECT * \`, `FROM t \`, "WHERE id = 1", "COMMIT"},
+			ranges: [][2]int{{1, 3}, {4, 4}},
+		},
+		{
+			// Lines not starting with "[" belong to the entry before them.
+			name:   "negated after",
+			cfg:    DetectorConfig{Pattern: `^\[`, Negate: true},
+			lines:  []string{"[1] start", "detail a", "detail b", "[2] next"},
+			ranges: [][2]int{{1, 3}, {4, 4}},
+		},
+		{
+			// Lines not ending an entry with ";" are prepended to the line
+			// that does.
+			name:   "negated before",
+			cfg:    DetectorConfig{Pattern: `;$`, Negate: true, Match: MatchBefore},
+			lines:  []string{"BEGIN", "UPDATE t SET x = 1;", "SELECT 1;"},
+			ranges: [][2]int{{1, 2}, {3, 3}},
+		},
+		{
+			name:   "max lines",
+			cfg:    DetectorConfig{Pattern: `^\s`, MaxLines: 2},
+			lines:  []string{"head", "  a", "  b", "  c", "next"},
+			ranges: [][2]int{{1, 2}, {3, 4}, {5, 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDetector(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			merged := MergeSlice(context.Background(), tt.lines, d)
+			if len(merged) != len(tt.ranges) {
+				for i, m := range merged {
+					t.Logf("entry %d: lines %d-%d: %s", i, m.StartLine, m.EndLine, m.Content)
+				}
+				t.Fatalf("expected %d entries, got %d", len(tt.ranges), len(merged))
+			}
+			for i, r := range tt.ranges {
+				if merged[i].StartLine != r[0] || merged[i].EndLine != r[1] {
+					t.Errorf("entry %d: expected lines %d-%d, got %d-%d", i, r[0], r[1], merged[i].StartLine, merged[i].EndLine)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeSliceQuotedRecords(t *testing.T) {
+	d, err := NewDetector(DetectorConfig{QuotedRecords: true, StackTraces: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		"ts,level,message",
+		`2024-03-16 08:12:04,ERROR,"query failed:`,
+		`  at db.Query (db.go:42)`,
+		`  said ""retry"""`,
+		"2024-03-16 08:12:05,INFO,ok",
+		"Traceback (most recent call last):",
+	}
+	merged := MergeSlice(context.Background(), lines, d)
+
+	want := [][2]int{{1, 1}, {2, 4}, {5, 5}, {6, 6}}
+	if len(merged) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(merged))
+	}
+	for i, r := range want {
+		if merged[i].StartLine != r[0] || merged[i].EndLine != r[1] {
+			t.Errorf("entry %d: expected lines %d-%d, got %d-%d", i, r[0], r[1], merged[i].StartLine, merged[i].EndLine)
+		}
+	}
+}
+
+func TestMergeChannelFlushTimeout(t *testing.T) {
+	for _, learn := range []LearnMode{LearnOff, LearnExtend} {
+		t.Run(string(learn)+"learn", func(t *testing.T) {
+			d, err := NewDetector(DetectorConfig{Learn: learn, FlushTimeout: 20 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := make(chan logsource.Result[*logsource.LogLine])
+			defer close(in)
+			out := Merge(context.Background(), in, d)
+
+			in <- logsource.Result[*logsource.LogLine]{Value: &logsource.LogLine{LineNumber: 1, Content: "2024-03-28 13:45:32 ERROR boom"}}
+			in <- logsource.Result[*logsource.LogLine]{Value: &logsource.LogLine{LineNumber: 2, Content: "\tat com.example.Foo.bar(Foo.java:42)"}}
+
+			// The input stays open: only the timeout can flush the entry.
+			select {
+			case mr := <-out:
+				if mr.Err != nil {
+					t.Fatal(mr.Err)
+				}
+				if mr.Value.StartLine != 1 || mr.Value.EndLine != 2 {
+					t.Errorf("expected lines 1-2, got %d-%d", mr.Value.StartLine, mr.Value.EndLine)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("entry was not flushed after the input went idle")
+			}
+		})
+	}
+}
+
+func TestMergeMarksTruncatedEntries(t *testing.T) {
+	d, err := NewDetector(DetectorConfig{MaxLines: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []string{
+		"2024-03-28 13:45:30 ERROR boom",
+		"\tat a.A.a(A.java:1)",
+		"\tat b.B.b(B.java:2)",
+		"\tat c.C.c(C.java:3)",
+		"\tat d.D.d(D.java:4)",
+		"2024-03-28 13:45:31 INFO next",
+	}
+	type flags struct {
+		start, end              int
+		truncated, continuation bool
+	}
+	want := []flags{
+		{1, 2, true, false},
+		{3, 4, true, true},
+		{5, 5, false, true},
+		{6, 6, false, false},
+	}
+	check := func(t *testing.T, merged []MergedLine) {
+		t.Helper()
+		if len(merged) != len(want) {
+			t.Fatalf("expected %d entries, got %d", len(want), len(merged))
+		}
+		for i, m := range merged {
+			got := flags{m.StartLine, m.EndLine, m.Truncated, m.Continuation}
+			if got != want[i] {
+				t.Errorf("entry %d: got %+v, want %+v", i, got, want[i])
+			}
+		}
+	}
+
+	t.Run("slice", func(t *testing.T) {
+		check(t, MergeSlice(context.Background(), lines, d))
+	})
+	t.Run("channel", func(t *testing.T) {
+		ch := make(chan logsource.Result[*logsource.LogLine], len(lines))
+		for i, s := range lines {
+			ch <- logsource.Result[*logsource.LogLine]{Value: &logsource.LogLine{LineNumber: i + 1, Content: s}}
+		}
+		close(ch)
+		var merged []MergedLine
+		for m := range Merge(context.Background(), ch, d) {
+			if m.Err != nil {
+				t.Fatalf("unexpected error: %v", m.Err)
+			}
+			merged = append(merged, *m.Value)
+		}
+		check(t, merged)
+	})
+}
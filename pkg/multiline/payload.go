@@ -0,0 +1,226 @@
+package multiline
+
+import "strings"
+
+type payloadKind int
+
+const (
+	payloadNone payloadKind = iota
+	payloadJSON
+	payloadXML
+)
+
+// payloadTracker follows the nesting of a pretty-printed JSON or XML payload
+// across lines: JSON braces and brackets outside string literals, XML
+// elements outside comments, CDATA sections and attribute values. It is not
+// safe for concurrent use; each merge run needs its own.
+type payloadTracker struct {
+	kind payloadKind
+	// stack holds the open JSON brackets or XML element names.
+	stack []string
+
+	// XML lexer state carried across lines.
+	prolog    bool   // after an XML declaration, before the root element
+	skipUntil string // end of a comment, CDATA section or declaration
+	inTag     bool   // inside a start tag whose '>' is on a later line
+	tag       string // name of that start tag
+	quote     byte   // open attribute quote inside that start tag
+}
+
+// next advances the tracker by one line. continues reports that line is
+// part of an open payload; starts reports that line opens a payload at its
+// first byte, so it begins an entry of its own.
+func (p *payloadTracker) next(line string) (continues, starts bool) {
+	if p.kind != payloadNone {
+		if p.belongs(line) {
+			if p.kind == payloadJSON {
+				p.scanJSON(line)
+			} else {
+				p.scanXML(line)
+			}
+			if !p.open() {
+				p.reset()
+			}
+			return true, false
+		}
+		// A line that cannot be part of the payload means it was never
+		// closed; treat the line as if no payload had been open.
+		p.reset()
+	}
+
+	leading := len(line) > 0 && strings.IndexByte("{[<", line[0]) >= 0
+	trimmed := strings.TrimRight(line, " \t")
+	last := byte(0)
+	if trimmed != "" {
+		last = trimmed[len(trimmed)-1]
+	}
+
+	p.kind = payloadJSON
+	p.scanJSON(line)
+	if p.open() && (leading || last == '{' || last == '[') {
+		return false, leading
+	}
+	p.reset()
+
+	p.kind = payloadXML
+	p.prolog = strings.HasPrefix(line, "<?xml")
+	p.scanXML(line)
+	if p.open() && (leading || last == '>') {
+		return false, leading
+	}
+	p.reset()
+	return false, false
+}
+
+// belongs reports whether line can continue the open payload: pretty
+// printers indent everything but the closing bracket or tag of the root.
+func (p *payloadTracker) belongs(line string) bool {
+	if line == "" || line[0] == ' ' || line[0] == '\t' || p.skipUntil != "" || p.inTag {
+		return true
+	}
+	if p.kind == payloadJSON {
+		return line[0] == '}' || line[0] == ']'
+	}
+	return line[0] == '<'
+}
+
+func (p *payloadTracker) open() bool {
+	return p.kind != payloadNone && (len(p.stack) > 0 || p.inTag || p.prolog)
+}
+
+func (p *payloadTracker) reset() {
+	*p = payloadTracker{stack: p.stack[:0]}
+}
+
+// scanJSON updates the bracket stack with one line. JSON strings cannot
+// span lines, so string state starts over on each line.
+func (p *payloadTracker) scanJSON(line string) {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			p.stack = append(p.stack, string(c))
+		case '}', ']':
+			want := "{"
+			if c == ']' {
+				want = "["
+			}
+			if len(p.stack) == 0 || p.stack[len(p.stack)-1] != want {
+				p.stack = p.stack[:0]
+				return
+			}
+			p.stack = p.stack[:len(p.stack)-1]
+		}
+	}
+}
+
+// scanXML updates the element stack with one line.
+func (p *payloadTracker) scanXML(line string) {
+	i := 0
+	for i < len(line) {
+		if p.skipUntil != "" {
+			j := strings.Index(line[i:], p.skipUntil)
+			if j < 0 {
+				return
+			}
+			i += j + len(p.skipUntil)
+			p.skipUntil = ""
+			continue
+		}
+		if p.inTag {
+			i = p.scanTagEnd(line, i)
+			continue
+		}
+		if line[i] != '<' {
+			i++
+			continue
+		}
+
+		rest := line[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			p.skipUntil, i = "-->", i+4
+		case strings.HasPrefix(rest, "<![CDATA["):
+			p.skipUntil, i = "]]>", i+9
+		case strings.HasPrefix(rest, "<?"):
+			p.skipUntil, i = "?>", i+2
+		case strings.HasPrefix(rest, "<!"):
+			p.skipUntil, i = ">", i+2
+		case strings.HasPrefix(rest, "</"):
+			name := xmlName(rest[2:])
+			if name == "" {
+				i++
+				continue
+			}
+			if len(p.stack) == 0 || p.stack[len(p.stack)-1] != name {
+				p.stack = p.stack[:0]
+				return
+			}
+			p.stack = p.stack[:len(p.stack)-1]
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return
+			}
+			i += end + 1
+		default:
+			name := xmlName(rest[1:])
+			if name == "" {
+				// A bare '<' in text, e.g. "a < b".
+				i++
+				continue
+			}
+			p.inTag, p.tag, p.prolog = true, name, false
+			i += 1 + len(name)
+		}
+	}
+}
+
+// scanTagEnd scans a start tag from line[i] for its closing '>', skipping
+// quoted attribute values, and returns where scanning continues.
+func (p *payloadTracker) scanTagEnd(line string, i int) int {
+	for ; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case p.quote != 0:
+			if c == p.quote {
+				p.quote = 0
+			}
+		case c == '"' || c == '\'':
+			p.quote = c
+		case c == '>':
+			if i == 0 || line[i-1] != '/' {
+				p.stack = append(p.stack, p.tag)
+			}
+			p.inTag, p.tag = false, ""
+			return i + 1
+		}
+	}
+	return i
+}
+
+// xmlName returns the element name s starts with, or "" if it does not
+// start with one.
+func xmlName(s string) string {
+	end := 0
+	for end < len(s) {
+		c := s[end]
+		isStart := c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isStart && (end == 0 || !(c == '-' || c == '.' || (c >= '0' && c <= '9'))) {
+			break
+		}
+		end++
+	}
+	return s[:end]
+}
@@ -0,0 +1,116 @@
+package multiline
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func mergePayloads(t *testing.T, lines []string) []MergedLine {
+	t.Helper()
+	d, err := NewDetector(DetectorConfig{Payloads: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return MergeSlice(context.Background(), lines, d)
+}
+
+func assertRanges(t *testing.T, merged []MergedLine, ranges [][2]int) {
+	t.Helper()
+	if len(merged) != len(ranges) {
+		for i, m := range merged {
+			t.Logf("entry %d: lines %d-%d: %s", i, m.StartLine, m.EndLine, truncate(m.Content))
+		}
+		t.Fatalf("expected %d entries, got %d", len(ranges), len(merged))
+	}
+	for i, r := range ranges {
+		if merged[i].StartLine != r[0] || merged[i].EndLine != r[1] {
+			t.Errorf("entry %d: expected lines %d-%d, got %d-%d", i, r[0], r[1], merged[i].StartLine, merged[i].EndLine)
+		}
+	}
+}
+
+func TestPayloadsPrettyJSON(t *testing.T) {
+	lines := []string{
+		"2024-03-28 13:45:30 INFO request received",
+		"{",
+		`  "ts": "2024-03-28T13:45:31Z",`,
+		`  "msg": "closing } inside a string, and a \" quote",`,
+		`  "items": [`,
+		`    {"id": 1},`,
+		`    {"id": 2}`,
+		`  ]`,
+		"}",
+		"2024-03-28 13:45:32 INFO response sent",
+	}
+
+	merged := mergePayloads(t, lines)
+	assertRanges(t, merged, [][2]int{{1, 1}, {2, 9}, {10, 10}})
+	if !json.Valid([]byte(merged[1].Content)) {
+		t.Errorf("merged payload is not valid JSON: %s", merged[1].Content)
+	}
+}
+
+func TestPayloadsTrailingOpener(t *testing.T) {
+	lines := []string{
+		"2024-03-28 13:45:30 DEBUG upstream replied: {",
+		`  "status": "2024-03-28 13:45:30 looks like a timestamp",`,
+		`  "code": 503`,
+		"}",
+		"2024-03-28 13:45:31 INFO retrying",
+	}
+	assertRanges(t, mergePayloads(t, lines), [][2]int{{1, 4}, {5, 5}})
+}
+
+func TestPayloadsXML(t *testing.T) {
+	lines := []string{
+		"2024-03-28 13:45:30 DEBUG SOAP request",
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"`,
+		`    xmlns:m="urn:orders">`,
+		"  <!-- sent at 2024-03-28 13:45:30 -->",
+		"  <soap:Body>",
+		`    <m:GetOrder id="a>b"/>`,
+		"    <m:Note><![CDATA[</soap:Body> is not a tag here]]></m:Note>",
+		"  </soap:Body>",
+		"</soap:Envelope>",
+		"2024-03-28 13:45:31 DEBUG SOAP response",
+	}
+
+	merged := mergePayloads(t, lines)
+	assertRanges(t, merged, [][2]int{{1, 1}, {2, 10}, {11, 11}})
+	dec := xml.NewDecoder(strings.NewReader(merged[1].Content))
+	for {
+		_, err := dec.Token()
+		if err != nil {
+			if err.Error() != "EOF" {
+				t.Errorf("merged payload is not well-formed XML: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestPayloadsUnclosedDoesNotSwallowLog(t *testing.T) {
+	// Output cut off mid-payload: the unindented line after it ends the
+	// payload instead of being merged into it.
+	lines := []string{
+		"{",
+		`  "truncated": [1, 2,`,
+		"server restarted",
+		"listening on :8080",
+	}
+	assertRanges(t, mergePayloads(t, lines), [][2]int{{1, 2}, {3, 3}, {4, 4}})
+}
+
+func TestPayloadsBalancedLinesUntouched(t *testing.T) {
+	lines := []string{
+		`{"level":"info","msg":"one"}`,
+		"[INFO] a < b and c > d",
+		`<p>inline</p>`,
+		"array[0] = {}",
+	}
+	assertRanges(t, mergePayloads(t, lines), [][2]int{{1, 1}, {2, 2}, {3, 3}, {4, 4}})
+}
@@ -0,0 +1,78 @@
+package multiline
+
+import "regexp"
+
+// stackTraceRule is one transition of the stack trace state machine, in
+// the style of Fluent Bit's multiline parsers: a line matching pattern while
+// the machine is in state continues the trace and moves it to next. Rules
+// of the "start" state begin a new trace.
+type stackTraceRule struct {
+	state   string
+	pattern *regexp.Regexp
+	next    string
+}
+
+const traceStartState = "start"
+
+// stackTraceRules recognizes the stack traces of several languages. Java,
+// .NET and Node.js share the "exception" state: all three print frames as
+// indented "at ..." lines. Rules of the current state are tried before the
+// start rules, so e.g. the final "ValueError: ..." line of a Python
+// traceback continues it rather than starting a new trace.
+var stackTraceRules = []stackTraceRule{
+	// Python: header, "File ..." frames with indented source and caret
+	// lines, the exception line, and chained tracebacks.
+	{traceStartState, regexp.MustCompile(`^Traceback \(most recent call last\):$`), "python"},
+	{"python", regexp.MustCompile(`^\s+\S`), "python"},
+	{"python", regexp.MustCompile(`^[A-Za-z_][\w.]*(?::.*)?$`), "python_exception"},
+	{"python_exception", regexp.MustCompile(`^$`), "python_chain"},
+	{"python_chain", regexp.MustCompile(`^(?:During handling of the above exception, another exception occurred|The above exception was the direct cause of the following exception):$`), "python_chain"},
+	{"python_chain", regexp.MustCompile(`^$`), "python_chain"},
+	{"python_chain", regexp.MustCompile(`^Traceback \(most recent call last\):$`), "python"},
+
+	// Go: panics, fatal errors and goroutine dumps, with blank lines
+	// between goroutines.
+	{traceStartState, regexp.MustCompile(`^(?:panic: |fatal error: |goroutine \d+ (?:.* )?\[.+\]:$)`), "go"},
+	{"go", regexp.MustCompile(`^(?:$|\t|goroutine \d+ (?:.* )?\[.+\]:$|\[signal |created by |panic: |fatal error: |\[recovered\]|runtime stack:$|exit status \d+$|\.\.\.additional frames elided\.\.\.$)`), "go"},
+	{"go", regexp.MustCompile(`^[\w./*()\[\]%-]+\(.*\)$`), "go"},
+
+	// Ruby: "file:line:in 'method': message (Class)" followed by "from"
+	// frames.
+	{traceStartState, regexp.MustCompile("^\\S.*:\\d+:in [`'].*': .* \\([A-Z][\\w:]*\\)$"), "ruby"},
+	{"ruby", regexp.MustCompile("^\\s+(?:from )?\\S+:\\d+:in [`']"), "ruby"},
+
+	// Java, .NET and Node.js: an exception header followed by "at" frames,
+	// Java's "Caused by:" / "... N more" and .NET's inner exception markers.
+	{traceStartState, regexp.MustCompile(`^(?:Exception in thread "[^"]*" |Unhandled [eE]xception[.:] ?|Uncaught )?(?:[A-Za-z_$][\w$]*\.)*[A-Z][\w$]*(?:Exception|Error|Throwable)(?: \[\w+\])?(?::.*)?$`), "exception"},
+	{"exception", regexp.MustCompile(`^\s+at `), "exception"},
+	{"exception", regexp.MustCompile(`^\s*(?:Caused by|Suppressed): `), "exception"},
+	{"exception", regexp.MustCompile(`^\s*\.\.\. \d+ (?:more|common frames omitted)$`), "exception"},
+	{"exception", regexp.MustCompile(`^\s*(?:---> |--- End of )`), "exception"},
+}
+
+// stackTracer tracks which stack trace, if any, the previous lines belong
+// to. It is not safe for concurrent use; each merge run needs its own.
+type stackTracer struct {
+	state string
+}
+
+// continues advances the machine by one line and reports whether the line
+// continues the current trace.
+func (t *stackTracer) continues(line string) bool {
+	if t.state != "" {
+		for _, r := range stackTraceRules {
+			if r.state == t.state && r.pattern.MatchString(line) {
+				t.state = r.next
+				return true
+			}
+		}
+	}
+	t.state = ""
+	for _, r := range stackTraceRules {
+		if r.state == traceStartState && r.pattern.MatchString(line) {
+			t.state = r.next
+			break
+		}
+	}
+	return false
+}
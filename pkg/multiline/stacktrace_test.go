@@ -0,0 +1,169 @@
+package multiline
+
+import (
+	"context"
+	"testing"
+)
+
+func mergeStackTraces(t *testing.T, lines []string) []MergedLine {
+	t.Helper()
+	d, err := NewDetector(DetectorConfig{StackTraces: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return MergeSlice(context.Background(), lines, d)
+}
+
+func TestStackTracesWithoutTimestamps(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		// ranges holds the expected [StartLine, EndLine] of each entry.
+		ranges [][2]int
+	}{
+		{
+			name: "java",
+			lines: []string{
+				"Starting worker",
+				`Exception in thread "main" java.lang.IllegalStateException: pool closed`,
+				"\tat com.example.Pool.get(Pool.java:31)",
+				"\tat com.example.Main.main(Main.java:12)",
+				"Caused by: java.io.IOException: broken pipe",
+				"\tat com.example.Conn.write(Conn.java:88)",
+				"\t... 2 more",
+				"Worker stopped",
+			},
+			ranges: [][2]int{{1, 1}, {2, 7}, {8, 8}},
+		},
+		{
+			name: "python",
+			lines: []string{
+				"processing batch 7",
+				"Traceback (most recent call last):",
+				`  File "/app/worker.py", line 12, in run`,
+				"    result = compute(batch)",
+				`  File "/app/worker.py", line 30, in compute`,
+				"    return total / count",
+				"           ~~~~~~^~~~~~~",
+				"ZeroDivisionError: division by zero",
+				"",
+				"During handling of the above exception, another exception occurred:",
+				"",
+				"Traceback (most recent call last):",
+				`  File "/app/main.py", line 5, in <module>`,
+				"    run()",
+				"RuntimeError: batch failed",
+				"retrying batch 7",
+			},
+			ranges: [][2]int{{1, 1}, {2, 15}, {16, 16}},
+		},
+		{
+			name: "go",
+			lines: []string{
+				"listening on :8080",
+				"panic: runtime error: invalid memory address or nil pointer dereference",
+				"[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a2b1c]",
+				"",
+				"goroutine 7 [running]:",
+				"main.(*Server).handle(0xc000010000, {0x4b3a20, 0xc00001c0a0})",
+				"\t/app/server.go:42 +0x1c",
+				"created by main.(*Server).Serve in goroutine 1",
+				"\t/app/server.go:30 +0x85",
+				"",
+				"goroutine 1 [IO wait]:",
+				"main.main()",
+				"\t/app/main.go:10 +0x1d",
+				"exit status 2",
+				"restarting",
+			},
+			ranges: [][2]int{{1, 1}, {2, 14}, {15, 15}},
+		},
+		{
+			name: "node",
+			lines: []string{
+				"server started",
+				"TypeError: Cannot read properties of undefined (reading 'id')",
+				"    at getUser (/app/src/users.js:14:22)",
+				"    at async handler (/app/src/routes.js:8:5)",
+				"request handled",
+			},
+			ranges: [][2]int{{1, 1}, {2, 4}, {5, 5}},
+		},
+		{
+			name: "dotnet",
+			lines: []string{
+				"Unhandled exception. System.InvalidOperationException: Sequence contains no elements",
+				" ---> System.ArgumentNullException: Value cannot be null.",
+				"   at System.Linq.ThrowHelper.ThrowNoElementsException()",
+				"   --- End of inner exception stack trace ---",
+				"   at Program.Main(String[] args) in /src/Program.cs:line 9",
+				"shutting down",
+			},
+			ranges: [][2]int{{1, 5}, {6, 6}},
+		},
+		{
+			name: "ruby",
+			lines: []string{
+				"app.rb:3:in `divide': divided by 0 (ZeroDivisionError)",
+				"\tfrom app.rb:7:in `run'",
+				"\tfrom app.rb:10:in `<main>'",
+				"done",
+			},
+			ranges: [][2]int{{1, 3}, {4, 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := mergeStackTraces(t, tt.lines)
+			if len(merged) != len(tt.ranges) {
+				for i, m := range merged {
+					t.Logf("entry %d: lines %d-%d: %s", i, m.StartLine, m.EndLine, truncate(m.Content))
+				}
+				t.Fatalf("expected %d entries, got %d", len(tt.ranges), len(merged))
+			}
+			for i, r := range tt.ranges {
+				if merged[i].StartLine != r[0] || merged[i].EndLine != r[1] {
+					t.Errorf("entry %d: expected lines %d-%d, got %d-%d", i, r[0], r[1], merged[i].StartLine, merged[i].EndLine)
+				}
+			}
+		})
+	}
+}
+
+func TestStackTracesKeepTimestampedEntries(t *testing.T) {
+	for _, name := range []string{"java_stacktrace.log", "python_traceback.log", "go_panic.log", "mixed_formats.log"} {
+		lines := readTestData(t, name)
+		d, err := NewDetector(DetectorConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := MergeSlice(context.Background(), lines, d)
+		got := mergeStackTraces(t, lines)
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %d entries as without stack trace rules, got %d", name, len(want), len(got))
+		}
+		for i := range want {
+			if got[i].StartLine != want[i].StartLine || got[i].EndLine != want[i].EndLine {
+				t.Errorf("%s entry %d: expected lines %d-%d, got %d-%d", name, i, want[i].StartLine, want[i].EndLine, got[i].StartLine, got[i].EndLine)
+			}
+		}
+	}
+}
+
+func TestStackTracesRequireFrames(t *testing.T) {
+	// An error message followed by prose that merely starts with "at" is
+	// not a stack trace.
+	lines := []string{
+		"Error: config file not found",
+		"at least one backend is required",
+		"exiting",
+	}
+	merged := mergeStackTraces(t, lines)
+	if len(merged) != len(lines) {
+		for i, m := range merged {
+			t.Logf("entry %d: %q", i, m.Content)
+		}
+		t.Fatalf("expected %d single-line entries, got %d", len(lines), len(merged))
+	}
+}
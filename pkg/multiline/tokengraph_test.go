@@ -1,12 +1,16 @@
 package multiline
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/strrl/lapp/pkg/timestamp"
+)
 
 func TestTokenGraphMatchProbability(t *testing.T) {
 	tok := newTokenizer(100)
 
 	patterns := [][]Token{}
-	for _, format := range knownTimestampFormats {
+	for _, format := range timestamp.Samples {
 		tokens, _ := tok.tokenize([]byte(format))
 		patterns = append(patterns, tokens)
 	}
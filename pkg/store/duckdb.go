@@ -32,7 +32,17 @@ func NewDuckDBStore(dsn string) (*DuckDBStore, error) {
 	return &DuckDBStore{db: db}, nil
 }
 
-// Init creates the log_entries and patterns tables if they do not exist.
+// logEntryColumns is the column list read by scanEntries.
+const logEntryColumns = `id, line_number, end_line_number, timestamp, raw, CAST(labels AS VARCHAR),
+	COALESCE(source, ''), COALESCE(CAST(attrs AS VARCHAR), ''), COALESCE(timeline_seq, -1),
+	COALESCE(truncated, false), COALESCE(continuation, false), COALESCE(message, ''),
+	COALESCE(CAST(attr_values AS VARCHAR), ''), COALESCE(message_key, '')`
+
+const insertLogSQL = `INSERT INTO log_entries (line_number, end_line_number, timestamp, raw, labels, source, attrs, truncated, continuation, message, attr_values, message_key)
+	VALUES (?, ?, ?, ?, ?::JSON, ?, ?::JSON, ?, ?, ?, ?::JSON, ?)`
+
+// Init creates the log_entries, patterns and ingest_checkpoints tables if
+// they do not exist.
 func (s *DuckDBStore) Init(ctx context.Context) error {
 	_, span := otel.Tracer("lapp/store").Start(ctx, "store.Init")
 	defer span.End()
@@ -47,12 +57,26 @@ func (s *DuckDBStore) Init(ctx context.Context) error {
 			end_line_number INTEGER,
 			timestamp TIMESTAMP,
 			raw VARCHAR,
-			labels JSON
+			labels JSON,
+			source VARCHAR,
+			attrs JSON,
+			timeline_seq BIGINT,
+			truncated BOOLEAN,
+			continuation BOOLEAN,
+			message VARCHAR,
+			attr_values JSON,
+			message_key VARCHAR
 		)
 	`)
 	if err != nil {
 		return errors.Errorf("create log_entries table: %w", err)
 	}
+	// Stores created before source tracking lack these columns.
+	for _, column := range []string{"source VARCHAR", "attrs JSON", "timeline_seq BIGINT", "truncated BOOLEAN", "continuation BOOLEAN", "message VARCHAR", "attr_values JSON", "message_key VARCHAR"} {
+		if _, err := s.db.ExecContext(ctx, `ALTER TABLE log_entries ADD COLUMN IF NOT EXISTS `+column); err != nil {
+			return errors.Errorf("add log_entries column %s: %w", column, err)
+		}
+	}
 
 	_, err = s.db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS patterns (
@@ -67,6 +91,26 @@ func (s *DuckDBStore) Init(ctx context.Context) error {
 		return errors.Errorf("create patterns table: %w", err)
 	}
 
+	_, err = s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS ingest_checkpoints (
+			source VARCHAR PRIMARY KEY,
+			device UBIGINT,
+			inode UBIGINT,
+			size BIGINT,
+			fingerprint VARCHAR,
+			byte_offset BIGINT,
+			line_number INTEGER,
+			updated_at TIMESTAMP,
+			parser_state JSON
+		)
+	`)
+	if err != nil {
+		return errors.Errorf("create ingest_checkpoints table: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `ALTER TABLE ingest_checkpoints ADD COLUMN IF NOT EXISTS parser_state JSON`); err != nil {
+		return errors.Errorf("add ingest_checkpoints column parser_state: %w", err)
+	}
+
 	return nil
 }
 
@@ -86,25 +130,36 @@ func (s *DuckDBStore) InsertLog(ctx context.Context, entry LogEntry) error {
 	_, span := otel.Tracer("lapp/store").Start(ctx, "store.InsertLog")
 	defer span.End()
 
-	labelsJSON, err := marshalLabels(entry.Labels)
+	args, err := logEntryArgs(entry)
 	if err != nil {
 		return err
 	}
-	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO log_entries (line_number, end_line_number, timestamp, raw, labels)
-		 VALUES (?, ?, ?, ?, ?::JSON)`,
-		entry.LineNumber,
-		entry.EndLineNumber,
-		entry.Timestamp,
-		entry.Raw,
-		labelsJSON,
-	)
-	if err != nil {
+	if _, err := s.db.ExecContext(ctx, insertLogSQL, args...); err != nil {
 		return errors.Errorf("insert log: %w", err)
 	}
 	return nil
 }
 
+func logEntryArgs(e LogEntry) ([]any, error) {
+	labelsJSON, err := marshalLabels(e.Labels)
+	if err != nil {
+		return nil, err
+	}
+	attrsJSON, err := marshalLabels(e.Attrs)
+	if err != nil {
+		return nil, err
+	}
+	valuesJSON := "{}"
+	if len(e.Values) > 0 {
+		b, err := json.Marshal(e.Values)
+		if err != nil {
+			return nil, errors.Errorf("marshal attr values: %w", err)
+		}
+		valuesJSON = string(b)
+	}
+	return []any{e.LineNumber, e.EndLineNumber, e.Timestamp, e.Raw, labelsJSON, e.Source, attrsJSON, e.Truncated, e.Continuation, e.Message, valuesJSON, e.MessageKey}, nil
+}
+
 // InsertLogBatch stores multiple log entries in a single transaction.
 func (s *DuckDBStore) InsertLogBatch(ctx context.Context, entries []LogEntry) error {
 	_, span := otel.Tracer("lapp/store").Start(ctx, "store.InsertLogBatch")
@@ -118,25 +173,153 @@ func (s *DuckDBStore) InsertLogBatch(ctx context.Context, entries []LogEntry) er
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	stmt, err := tx.PrepareContext(ctx,
-		`INSERT INTO log_entries (line_number, end_line_number, timestamp, raw, labels)
-		 VALUES (?, ?, ?, ?, ?::JSON)`,
-	)
+	if err := insertEntries(ctx, tx, entries); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+func insertEntries(ctx context.Context, tx *sql.Tx, entries []LogEntry) error {
+	stmt, err := tx.PrepareContext(ctx, insertLogSQL)
 	if err != nil {
 		return errors.Errorf("prepare: %w", err)
 	}
 	defer func() { _ = stmt.Close() }()
 
 	for _, e := range entries {
-		labelsJSON, err := marshalLabels(e.Labels)
+		args, err := logEntryArgs(e)
 		if err != nil {
 			return err
 		}
-		_, err = stmt.ExecContext(ctx, e.LineNumber, e.EndLineNumber, e.Timestamp, e.Raw, labelsJSON)
-		if err != nil {
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
 			return errors.Errorf("exec: %w", err)
 		}
 	}
+	return nil
+}
+
+// Checkpoint returns the ingestion checkpoint of source, or nil if none.
+func (s *DuckDBStore) Checkpoint(ctx context.Context, source string) (*Checkpoint, error) {
+	_, span := otel.Tracer("lapp/store").Start(ctx, "store.Checkpoint")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("source", source))
+
+	cp := Checkpoint{Source: source}
+	var parserState string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT device, inode, size, fingerprint, byte_offset, line_number, updated_at, COALESCE(CAST(parser_state AS VARCHAR), '')
+		 FROM ingest_checkpoints WHERE source = ?`,
+		source,
+	).Scan(&cp.Device, &cp.Inode, &cp.Size, &cp.Fingerprint, &cp.Offset, &cp.LineNumber, &cp.UpdatedAt, &parserState)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("query checkpoint: %w", err)
+	}
+	if parserState != "" {
+		if err := json.Unmarshal([]byte(parserState), &cp.ParserState); err != nil {
+			return nil, errors.Errorf("unmarshal parser state: %w", err)
+		}
+	}
+	return &cp, nil
+}
+
+// CommitLogBatch inserts entries and upserts cp in one transaction.
+func (s *DuckDBStore) CommitLogBatch(ctx context.Context, entries []LogEntry, cp Checkpoint) error {
+	_, span := otel.Tracer("lapp/store").Start(ctx, "store.CommitLogBatch")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("source", cp.Source),
+		attribute.Int("batch.size", len(entries)),
+		attribute.Int64("checkpoint.offset", cp.Offset),
+	)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := insertEntries(ctx, tx, entries); err != nil {
+		return err
+	}
+
+	if cp.UpdatedAt.IsZero() {
+		cp.UpdatedAt = time.Now().UTC()
+	}
+	parserState, err := json.Marshal(cp.ParserState)
+	if err != nil {
+		return errors.Errorf("marshal parser state: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO ingest_checkpoints (source, device, inode, size, fingerprint, byte_offset, line_number, updated_at, parser_state)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?::JSON)
+		 ON CONFLICT(source) DO UPDATE SET
+		     device      = excluded.device,
+		     inode       = excluded.inode,
+		     size        = excluded.size,
+		     fingerprint = excluded.fingerprint,
+		     byte_offset = excluded.byte_offset,
+		     line_number = excluded.line_number,
+		     updated_at  = excluded.updated_at,
+		     parser_state = excluded.parser_state`,
+		cp.Source, cp.Device, cp.Inode, cp.Size, cp.Fingerprint, cp.Offset, cp.LineNumber, cp.UpdatedAt, string(parserState),
+	)
+	if err != nil {
+		return errors.Errorf("upsert checkpoint: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// DeleteEntriesAfter removes the entries of source whose line number is
+// greater than lineNumber.
+func (s *DuckDBStore) DeleteEntriesAfter(ctx context.Context, source string, lineNumber int) (int, error) {
+	_, span := otel.Tracer("lapp/store").Start(ctx, "store.DeleteEntriesAfter")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("source", source), attribute.Int("line_number", lineNumber))
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM log_entries WHERE source = ? AND line_number > ?`, source, lineNumber)
+	if err != nil {
+		return 0, errors.Errorf("delete entries: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Errorf("delete entries: %w", err)
+	}
+	return int(n), nil
+}
+
+// DeleteSource removes all entries and the checkpoint of source.
+func (s *DuckDBStore) DeleteSource(ctx context.Context, source string) error {
+	_, span := otel.Tracer("lapp/store").Start(ctx, "store.DeleteSource")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("source", source))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM log_entries WHERE source = ?`, source); err != nil {
+		return errors.Errorf("delete entries: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ingest_checkpoints WHERE source = ?`, source); err != nil {
+		return errors.Errorf("delete checkpoint: %w", err)
+	}
 
 	if err := tx.Commit(); err != nil {
 		return errors.Errorf("commit: %w", err)
@@ -152,7 +335,7 @@ func (s *DuckDBStore) QueryByPattern(ctx context.Context, pattern string) ([]Log
 	span.SetAttributes(attribute.String("pattern", pattern))
 
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, line_number, end_line_number, timestamp, raw, CAST(labels AS VARCHAR)
+		`SELECT `+logEntryColumns+`
 		 FROM log_entries WHERE json_extract_string(labels, '$.pattern') = ?`,
 		pattern,
 	)
@@ -168,6 +351,33 @@ func (s *DuckDBStore) QueryLogs(ctx context.Context, opts QueryOpts) ([]LogEntry
 	_, span := otel.Tracer("lapp/store").Start(ctx, "store.QueryLogs")
 	defer span.End()
 
+	conditions, args := logConditions(opts)
+	query := "SELECT " + logEntryColumns + " FROM log_entries"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if opts.Timeline {
+		query += " ORDER BY timeline_seq NULLS LAST, source, line_number"
+	} else {
+		query += " ORDER BY line_number"
+	}
+	if opts.Limit > 0 {
+		// DuckDB's database/sql driver does not reliably bind LIMIT via placeholder,
+		// so we interpolate the int directly. This is safe as opts.Limit is an int.
+		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Errorf("query logs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	return scanEntries(rows)
+}
+
+// logConditions returns the WHERE conditions and their arguments for the
+// filters of opts.
+func logConditions(opts QueryOpts) ([]string, []any) {
 	var conditions []string
 	var args []any
 
@@ -175,6 +385,10 @@ func (s *DuckDBStore) QueryLogs(ctx context.Context, opts QueryOpts) ([]LogEntry
 		conditions = append(conditions, "json_extract_string(labels, '$.pattern') = ?")
 		args = append(args, opts.Pattern)
 	}
+	if opts.Source != "" {
+		conditions = append(conditions, "source = ?")
+		args = append(args, opts.Source)
+	}
 	if !opts.From.IsZero() {
 		conditions = append(conditions, "timestamp >= ?")
 		args = append(args, opts.From)
@@ -183,24 +397,162 @@ func (s *DuckDBStore) QueryLogs(ctx context.Context, opts QueryOpts) ([]LogEntry
 		conditions = append(conditions, "timestamp <= ?")
 		args = append(args, opts.To)
 	}
-
-	query := "SELECT id, line_number, end_line_number, timestamp, raw, CAST(labels AS VARCHAR) FROM log_entries"
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	if opts.Request != (RequestRef{}) {
+		conditions = append(conditions, requestKeySQL+" = ?", requestSessionSQL+" = ?")
+		args = append(args, opts.Request.Key, opts.Request.ID)
 	}
-	query += " ORDER BY line_number"
+	return conditions, args
+}
+
+// requestSessionSQL is the request session ID of an entry: its trace_id,
+// or request_id if it has no trace. See event.AttrTraceID.
+const requestSessionSQL = `COALESCE(NULLIF(json_extract_string(attrs, '$.trace_id'), ''), NULLIF(json_extract_string(attrs, '$.request_id'), ''))`
+
+// requestKeySQL is the attr requestSessionSQL comes from.
+const requestKeySQL = `CASE WHEN NULLIF(json_extract_string(attrs, '$.trace_id'), '') IS NOT NULL THEN 'trace_id'
+	WHEN NULLIF(json_extract_string(attrs, '$.request_id'), '') IS NOT NULL THEN 'request_id' END`
+
+// RequestSessions groups entries by trace_id, or request_id for entries
+// without a trace.
+func (s *DuckDBStore) RequestSessions(ctx context.Context, opts QueryOpts) ([]RequestSession, error) {
+	_, span := otel.Tracer("lapp/store").Start(ctx, "store.RequestSessions")
+	defer span.End()
+
+	opts.Request = RequestRef{}
+	conditions, args := logConditions(opts)
+	conditions = append(conditions, requestSessionSQL+" IS NOT NULL")
+	// Sessions with errors first, then those crossing the most sources,
+	// as they are where a failure propagated.
+	query := `SELECT ` + requestKeySQL + ` AS key, ` + requestSessionSQL + ` AS session,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE json_extract_string(attrs, '$.level') IN ('error', 'fatal')) AS errors,
+			list_sort(list_distinct(list(COALESCE(source, '')))) AS sources,
+			list_sort(list_distinct(list(json_extract_string(attrs, '$.service')))),
+			MIN(timestamp) AS first, MAX(timestamp)
+		FROM log_entries
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		GROUP BY key, session
+		HAVING COUNT(*) > 1
+		ORDER BY errors > 0 DESC, len(sources) DESC, COUNT(*) DESC, first, key, session`
 	if opts.Limit > 0 {
-		// DuckDB's database/sql driver does not reliably bind LIMIT via placeholder,
-		// so we interpolate the int directly. This is safe as opts.Limit is an int.
 		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
 	}
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, errors.Errorf("query logs: %w", err)
+		return nil, errors.Errorf("query request sessions: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
-	return scanEntries(rows)
+
+	var sessions []RequestSession
+	for rows.Next() {
+		var (
+			rs                RequestSession
+			sources, services []any
+			first, last       sql.NullTime
+		)
+		if err := rows.Scan(&rs.Key, &rs.ID, &rs.Count, &rs.Errors, &sources, &services, &first, &last); err != nil {
+			return nil, errors.Errorf("scan request session: %w", err)
+		}
+		rs.Sources = nonEmptyStrings(sources)
+		rs.Services = nonEmptyStrings(services)
+		rs.First, rs.Last = first.Time, last.Time
+		sessions = append(sessions, rs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Errorf("rows err: %w", err)
+	}
+	return sessions, nil
+}
+
+func nonEmptyStrings(items []any) []string {
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// AttrStats aggregates the numeric values of the attr key. The typed value
+// is used when the entry has one; otherwise the string attr counts if it
+// parses as a number.
+func (s *DuckDBStore) AttrStats(ctx context.Context, key string, opts QueryOpts) (*AttrStats, error) {
+	_, span := otel.Tracer("lapp/store").Start(ctx, "store.AttrStats")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("attr.key", key))
+
+	// Quoted, so dotted keys such as http.response.status_code are one
+	// member rather than a nested path.
+	path := `$."` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key) + `"`
+	conditions, args := logConditions(opts)
+	query := `SELECT COUNT(v), COALESCE(MIN(v), 0), COALESCE(MAX(v), 0), COALESCE(SUM(v), 0), COALESCE(AVG(v), 0)
+		FROM (SELECT CASE
+			WHEN json_type(attr_values, ?) IN ('BIGINT', 'UBIGINT', 'DOUBLE') THEN CAST(json_extract_string(attr_values, ?) AS DOUBLE)
+			WHEN json_type(attr_values, ?) IS NULL THEN TRY_CAST(json_extract_string(attrs, ?) AS DOUBLE)
+		END AS v FROM log_entries`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += ")"
+
+	stats := &AttrStats{Key: key}
+	row := s.db.QueryRowContext(ctx, query, append([]any{path, path, path, path}, args...)...)
+	if err := row.Scan(&stats.Count, &stats.Min, &stats.Max, &stats.Sum, &stats.Avg); err != nil {
+		return nil, errors.Errorf("query attr stats: %w", err)
+	}
+	return stats, nil
+}
+
+// SetTimelineOrder numbers the given entries 0..n-1 in timeline_seq and
+// clears it on every other entry.
+func (s *DuckDBStore) SetTimelineOrder(ctx context.Context, ids []int64) error {
+	_, span := otel.Tracer("lapp/store").Start(ctx, "store.SetTimelineOrder")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("timeline.size", len(ids)))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE IF NOT EXISTS timeline_order (id BIGINT, seq BIGINT)`); err != nil {
+		return errors.Errorf("create timeline_order: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM timeline_order`); err != nil {
+		return errors.Errorf("clear timeline_order: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO timeline_order (id, seq) VALUES (?, ?)`)
+	if err != nil {
+		return errors.Errorf("prepare: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	for seq, id := range ids {
+		if _, err := stmt.ExecContext(ctx, id, int64(seq)); err != nil {
+			return errors.Errorf("exec: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE log_entries SET timeline_seq = NULL`); err != nil {
+		return errors.Errorf("clear timeline_seq: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE log_entries SET timeline_seq = timeline_order.seq
+		 FROM timeline_order WHERE log_entries.id = timeline_order.id`,
+	)
+	if err != nil {
+		return errors.Errorf("set timeline_seq: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Errorf("commit: %w", err)
+	}
+	return nil
 }
 
 // PatternSummaries returns all patterns with their occurrence counts,
@@ -352,8 +704,8 @@ func scanEntries(rows *sql.Rows) ([]LogEntry, error) {
 	for rows.Next() {
 		var e LogEntry
 		var ts time.Time
-		var labelsJSON string
-		if err := rows.Scan(&e.ID, &e.LineNumber, &e.EndLineNumber, &ts, &e.Raw, &labelsJSON); err != nil {
+		var labelsJSON, attrsJSON, valuesJSON string
+		if err := rows.Scan(&e.ID, &e.LineNumber, &e.EndLineNumber, &ts, &e.Raw, &labelsJSON, &e.Source, &attrsJSON, &e.TimelineSeq, &e.Truncated, &e.Continuation, &e.Message, &valuesJSON, &e.MessageKey); err != nil {
 			return nil, errors.Errorf("scan entry: %w", err)
 		}
 		e.Timestamp = ts
@@ -362,6 +714,18 @@ func scanEntries(rows *sql.Rows) ([]LogEntry, error) {
 				return nil, errors.Errorf("unmarshal labels: %w", err)
 			}
 		}
+		if attrsJSON != "" && attrsJSON != "{}" {
+			if err := json.Unmarshal([]byte(attrsJSON), &e.Attrs); err != nil {
+				return nil, errors.Errorf("unmarshal attrs: %w", err)
+			}
+		}
+		if valuesJSON != "" && valuesJSON != "{}" {
+			values, err := unmarshalValues(valuesJSON)
+			if err != nil {
+				return nil, err
+			}
+			e.Values = values
+		}
 		entries = append(entries, e)
 	}
 	if err := rows.Err(); err != nil {
@@ -369,3 +733,33 @@ func scanEntries(rows *sql.Rows) ([]LogEntry, error) {
 	}
 	return entries, nil
 }
+
+// unmarshalValues decodes typed attr values, keeping integers as int64.
+func unmarshalValues(data string) (map[string]any, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	var values map[string]any
+	if err := dec.Decode(&values); err != nil {
+		return nil, errors.Errorf("unmarshal attr values: %w", err)
+	}
+	for key, value := range values {
+		values[key] = numbersOf(value)
+	}
+	return values, nil
+}
+
+func numbersOf(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i, item := range v {
+			v[i] = numbersOf(item)
+		}
+	}
+	return value
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -313,3 +314,260 @@ func TestPatternCounts(t *testing.T) {
 		t.Errorf("pattern 2 count: got %d, want 1", counts["pat-2"])
 	}
 }
+
+func TestCommitLogBatchAndCheckpoint(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	cp, err := s.Checkpoint(ctx, "app.log")
+	if err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	if cp != nil {
+		t.Fatalf("expected no checkpoint, got %+v", cp)
+	}
+
+	entries := []LogEntry{
+		{LineNumber: 1, EndLineNumber: 1, Raw: "first", Source: "app.log", Attrs: map[string]string{"level": "info"}},
+		{LineNumber: 2, EndLineNumber: 3, Raw: "second\n  continued", Source: "app.log"},
+	}
+	want := Checkpoint{Source: "app.log", Device: 7, Inode: 42, Size: 100, Fingerprint: "abc", Offset: 30, LineNumber: 3, ParserState: map[string]string{"w3c": "date time"}}
+	if err := s.CommitLogBatch(ctx, entries, want); err != nil {
+		t.Fatalf("CommitLogBatch: %v", err)
+	}
+	if err := s.CommitLogBatch(ctx, []LogEntry{{LineNumber: 1, Raw: "other", Source: "other.log"}}, Checkpoint{Source: "other.log", Offset: 6, LineNumber: 1}); err != nil {
+		t.Fatalf("CommitLogBatch other: %v", err)
+	}
+
+	got, err := s.Checkpoint(ctx, "app.log")
+	if err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	if got == nil || got.Device != 7 || got.Inode != 42 || got.Size != 100 || got.Fingerprint != "abc" || got.Offset != 30 || got.LineNumber != 3 || got.ParserState["w3c"] != "date time" || got.UpdatedAt.IsZero() {
+		t.Fatalf("checkpoint: got %+v", got)
+	}
+
+	logs, err := s.QueryLogs(ctx, QueryOpts{Source: "app.log"})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	if len(logs) != 2 || logs[0].Attrs["level"] != "info" || logs[1].Source != "app.log" {
+		t.Fatalf("entries: got %+v", logs)
+	}
+
+	// Advancing the checkpoint replaces it.
+	want.Offset, want.LineNumber = 40, 4
+	if err := s.CommitLogBatch(ctx, []LogEntry{{LineNumber: 4, Raw: "third", Source: "app.log"}}, want); err != nil {
+		t.Fatalf("CommitLogBatch: %v", err)
+	}
+	if got, _ := s.Checkpoint(ctx, "app.log"); got == nil || got.Offset != 40 || got.LineNumber != 4 {
+		t.Fatalf("advanced checkpoint: got %+v", got)
+	}
+
+	if err := s.DeleteSource(ctx, "app.log"); err != nil {
+		t.Fatalf("DeleteSource: %v", err)
+	}
+	if got, _ := s.Checkpoint(ctx, "app.log"); got != nil {
+		t.Fatalf("expected checkpoint to be deleted, got %+v", got)
+	}
+	all, err := s.QueryLogs(ctx, QueryOpts{})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	if len(all) != 1 || all[0].Source != "other.log" {
+		t.Fatalf("expected only other.log to remain, got %+v", all)
+	}
+}
+
+func TestSetTimelineOrder(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	entries := []LogEntry{
+		{LineNumber: 1, Raw: "api first", Source: "api.log"},
+		{LineNumber: 2, Raw: "api second", Source: "api.log"},
+		{LineNumber: 1, Raw: "worker first", Source: "worker.log"},
+	}
+	if err := s.InsertLogBatch(ctx, entries); err != nil {
+		t.Fatalf("InsertLogBatch: %v", err)
+	}
+	stored, err := s.QueryLogs(ctx, QueryOpts{})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	byRaw := make(map[string]int64)
+	for _, e := range stored {
+		if e.TimelineSeq != -1 {
+			t.Errorf("expected no timeline yet, got %d for %q", e.TimelineSeq, e.Raw)
+		}
+		byRaw[e.Raw] = e.ID
+	}
+
+	order := []int64{byRaw["api first"], byRaw["worker first"], byRaw["api second"]}
+	if err := s.SetTimelineOrder(ctx, order); err != nil {
+		t.Fatalf("SetTimelineOrder: %v", err)
+	}
+	timeline, err := s.QueryLogs(ctx, QueryOpts{Timeline: true})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	for i, want := range []string{"api first", "worker first", "api second"} {
+		if timeline[i].Raw != want || timeline[i].TimelineSeq != int64(i) {
+			t.Errorf("timeline[%d]: got %q seq %d, want %q", i, timeline[i].Raw, timeline[i].TimelineSeq, want)
+		}
+	}
+
+	// Rebuilding with fewer entries clears the stale positions.
+	if err := s.SetTimelineOrder(ctx, order[:1]); err != nil {
+		t.Fatalf("SetTimelineOrder: %v", err)
+	}
+	timeline, err = s.QueryLogs(ctx, QueryOpts{Timeline: true})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	if timeline[0].TimelineSeq != 0 || timeline[1].TimelineSeq != -1 || timeline[2].TimelineSeq != -1 {
+		t.Errorf("expected stale positions cleared, got %d %d %d", timeline[0].TimelineSeq, timeline[1].TimelineSeq, timeline[2].TimelineSeq)
+	}
+}
+
+func TestInsertTruncatedEntries(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	entries := []LogEntry{
+		{LineNumber: 1, EndLineNumber: 2, Raw: "head", Truncated: true},
+		{LineNumber: 3, EndLineNumber: 3, Raw: "tail", Continuation: true},
+		{LineNumber: 4, EndLineNumber: 4, Raw: "whole", Message: "message", MessageKey: "msg"},
+	}
+	if err := s.InsertLogBatch(ctx, entries); err != nil {
+		t.Fatalf("InsertLogBatch: %v", err)
+	}
+
+	got, err := s.QueryLogs(ctx, QueryOpts{})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("expected %d entries, got %d", len(entries), len(got))
+	}
+	for i, e := range got {
+		if e.Truncated != entries[i].Truncated || e.Continuation != entries[i].Continuation {
+			t.Errorf("entry %d: got truncated=%v continuation=%v, want %v %v",
+				i, e.Truncated, e.Continuation, entries[i].Truncated, entries[i].Continuation)
+		}
+		if e.Message != entries[i].Message || e.MessageKey != entries[i].MessageKey {
+			t.Errorf("entry %d: got message %q from %q, want %q from %q",
+				i, e.Message, e.MessageKey, entries[i].Message, entries[i].MessageKey)
+		}
+	}
+}
+
+func TestAttrValuesAndStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	entries := []LogEntry{
+		{LineNumber: 1, Raw: "a", Source: "api.log",
+			Attrs:  map[string]string{"http.status": "200", "ms": "1.5", "tags": `["x",1]`},
+			Values: map[string]any{"http.status": int64(200), "ms": 1.5, "tags": []any{"x", int64(1)}}},
+		{LineNumber: 2, Raw: "b", Source: "api.log",
+			Attrs:  map[string]string{"http.status": "502", "ms": "slow"},
+			Values: map[string]any{"http.status": int64(502)}},
+		// Untyped, e.g. from logfmt: the string counts if numeric.
+		{LineNumber: 3, Raw: "c", Source: "api.log", Attrs: map[string]string{"http.status": "404", "ms": "2.5"}},
+		{LineNumber: 4, Raw: "d", Source: "worker.log", Attrs: map[string]string{"http.status": "100"}},
+	}
+	if err := s.InsertLogBatch(ctx, entries); err != nil {
+		t.Fatalf("InsertLogBatch: %v", err)
+	}
+
+	got, err := s.QueryLogs(ctx, QueryOpts{Limit: 1})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	if !reflect.DeepEqual(got[0].Values, entries[0].Values) {
+		t.Errorf("Values: got %#v, want %#v", got[0].Values, entries[0].Values)
+	}
+
+	stats, err := s.AttrStats(ctx, "http.status", QueryOpts{Source: "api.log"})
+	if err != nil {
+		t.Fatalf("AttrStats: %v", err)
+	}
+	want := AttrStats{Key: "http.status", Count: 3, Min: 200, Max: 502, Sum: 1106, Avg: 1106.0 / 3}
+	if *stats != want {
+		t.Errorf("AttrStats: got %+v, want %+v", *stats, want)
+	}
+
+	stats, err = s.AttrStats(ctx, "ms", QueryOpts{})
+	if err != nil {
+		t.Fatalf("AttrStats: %v", err)
+	}
+	if stats.Count != 2 || stats.Sum != 4 {
+		t.Errorf("expected the non-numeric value to be skipped, got %+v", *stats)
+	}
+
+	stats, err = s.AttrStats(ctx, "tags", QueryOpts{})
+	if err != nil {
+		t.Fatalf("AttrStats: %v", err)
+	}
+	if stats.Count != 0 {
+		t.Errorf("expected lists not to count, got %+v", *stats)
+	}
+}
+
+func TestRequestSessions(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	base := time.Date(2024, 3, 16, 8, 0, 0, 0, time.UTC)
+	entries := []LogEntry{
+		{LineNumber: 1, Raw: "gw in", Source: "gateway.log", Timestamp: base,
+			Attrs: map[string]string{"trace_id": "t1", "request_id": "r1", "service": "gateway"}},
+		{LineNumber: 1, Raw: "pay fail", Source: "payments.log", Timestamp: base.Add(2 * time.Second),
+			Attrs: map[string]string{"trace_id": "t1", "service": "payments", "level": "error"}},
+		{LineNumber: 2, Raw: "gw out", Source: "gateway.log", Timestamp: base.Add(3 * time.Second),
+			Attrs: map[string]string{"trace_id": "t1", "service": "gateway"}},
+		{LineNumber: 3, Raw: "untraced", Source: "gateway.log", Timestamp: base.Add(time.Second),
+			Attrs: map[string]string{"request_id": "r2"}},
+		{LineNumber: 4, Raw: "untraced again", Source: "gateway.log", Timestamp: base.Add(4 * time.Second),
+			Attrs: map[string]string{"request_id": "r2"}},
+		{LineNumber: 5, Raw: "no ids", Source: "gateway.log", Timestamp: base.Add(time.Second)},
+		{LineNumber: 6, Raw: "alone", Source: "gateway.log", Timestamp: base.Add(time.Second),
+			Attrs: map[string]string{"request_id": "r3"}},
+		// A request ID equal to a trace ID is another session.
+		{LineNumber: 1, Raw: "same id 1", Source: "worker.log", Timestamp: base,
+			Attrs: map[string]string{"request_id": "t1"}},
+		{LineNumber: 2, Raw: "same id 2", Source: "worker.log", Timestamp: base.Add(time.Second),
+			Attrs: map[string]string{"request_id": "t1"}},
+	}
+	if err := s.InsertLogBatch(ctx, entries); err != nil {
+		t.Fatalf("InsertLogBatch: %v", err)
+	}
+
+	sessions, err := s.RequestSessions(ctx, QueryOpts{})
+	if err != nil {
+		t.Fatalf("RequestSessions: %v", err)
+	}
+	want := []RequestSession{
+		{RequestRef: RequestRef{Key: "trace_id", ID: "t1"}, Count: 3, Errors: 1, Sources: []string{"gateway.log", "payments.log"},
+			Services: []string{"gateway", "payments"}, First: base, Last: base.Add(3 * time.Second)},
+		{RequestRef: RequestRef{Key: "request_id", ID: "t1"}, Count: 2, Sources: []string{"worker.log"},
+			First: base, Last: base.Add(time.Second)},
+		{RequestRef: RequestRef{Key: "request_id", ID: "r2"}, Count: 2, Sources: []string{"gateway.log"},
+			First: base.Add(time.Second), Last: base.Add(4 * time.Second)},
+	}
+	if !reflect.DeepEqual(sessions, want) {
+		t.Errorf("RequestSessions:\n got %+v\nwant %+v", sessions, want)
+	}
+
+	logs, err := s.QueryLogs(ctx, QueryOpts{Request: RequestRef{Key: "trace_id", ID: "t1"}, Timeline: true})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("expected the 3 entries of t1, got %d", len(logs))
+	}
+	if logs, _ := s.QueryLogs(ctx, QueryOpts{Request: RequestRef{Key: "request_id", ID: "r1"}}); len(logs) != 0 {
+		t.Errorf("expected traced entries to belong to their trace, got %d for r1", len(logs))
+	}
+}
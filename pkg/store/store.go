@@ -14,6 +14,47 @@ type LogEntry struct {
 	Timestamp     time.Time
 	Raw           string
 	Labels        map[string]string
+	// Source names where the entry was ingested from, e.g. a workspace
+	// log file name.
+	Source string
+	// Attrs holds the normalized event attributes of the entry.
+	Attrs map[string]string
+	// TimelineSeq is the entry's position in the timeline merged across
+	// all sources, or -1 if no timeline has been built yet.
+	TimelineSeq int64
+	// Truncated and Continuation mark an entry split by the multiline
+	// size limits: Truncated is cut short, Continuation holds the rest of
+	// the previous entry.
+	Truncated    bool
+	Continuation bool
+	// Message is the part of Raw patterns are mined from, e.g. the
+	// message column of a CSV record. Empty means all of Raw.
+	Message string
+	// MessageKey is the key of the Attrs field Message was taken from,
+	// empty if Message is not in Attrs.
+	MessageKey string
+	// Values holds the typed values of the Attrs the source typed:
+	// int64, float64, bool or []any. Attrs has them in string form.
+	Values map[string]any
+}
+
+// Checkpoint records how far a source has been ingested. Device, Inode and
+// Fingerprint identify the file so a replaced or truncated source is
+// detected instead of resumed.
+type Checkpoint struct {
+	Source      string
+	Device      uint64
+	Inode       uint64
+	Size        int64
+	Fingerprint string
+	// Offset is the byte offset just past the last ingested line.
+	Offset int64
+	// LineNumber is the last ingested physical line.
+	LineNumber int
+	// ParserState is the state of the source's parsers at Offset, such as
+	// a header read earlier in the file (see event.Registry.SourceState).
+	ParserState map[string]string
+	UpdatedAt   time.Time
 }
 
 // Pattern represents a discovered log pattern with optional semantic labels.
@@ -38,9 +79,49 @@ type PatternSummary struct {
 // QueryOpts specifies filters for querying log entries.
 type QueryOpts struct {
 	Pattern string
+	Source  string
 	From    time.Time
 	To      time.Time
 	Limit   int
+	// Timeline orders results by TimelineSeq instead of line number.
+	Timeline bool
+	// Request keeps the entries of one request session; see
+	// RequestSession.
+	Request RequestRef
+}
+
+// RequestRef names a request session.
+type RequestRef struct {
+	// Key is the attr ID comes from: "trace_id", or "request_id" for
+	// entries without a trace.
+	Key string
+	// ID is the trace or request ID.
+	ID string
+}
+
+// RequestSession groups the entries of one request, across sources, by
+// their trace_id attr, or request_id for entries without a trace. A trace
+// and a request with the same ID are different sessions.
+type RequestSession struct {
+	RequestRef
+	Count   int
+	Errors  int
+	Sources []string
+	// Services are the service attrs of the entries.
+	Services []string
+	First    time.Time
+	Last     time.Time
+}
+
+// AttrStats aggregates the numeric values of one attr.
+type AttrStats struct {
+	Key string
+	// Count is the number of entries whose value for Key is numeric.
+	Count int
+	Min   float64
+	Max   float64
+	Sum   float64
+	Avg   float64
 }
 
 // Store persists log entries and patterns.
@@ -55,6 +136,15 @@ type Store interface {
 	QueryByPattern(ctx context.Context, pattern string) ([]LogEntry, error)
 	// QueryLogs returns entries matching the given options.
 	QueryLogs(ctx context.Context, opts QueryOpts) ([]LogEntry, error)
+	// AttrStats aggregates the numeric values of the attr key over the
+	// entries matching opts; Limit and Timeline are ignored.
+	AttrStats(ctx context.Context, key string, opts QueryOpts) (*AttrStats, error)
+	// RequestSessions returns the request sessions of more than one of the
+	// entries matching opts: those with errors first, then those crossing
+	// the most sources, then those with the most entries. Request and
+	// Timeline are ignored, and Limit caps the sessions. Use
+	// QueryOpts.Request with Timeline to read one session in order.
+	RequestSessions(ctx context.Context, opts QueryOpts) ([]RequestSession, error)
 	// PatternSummaries returns all patterns with their counts.
 	PatternSummaries(ctx context.Context) ([]PatternSummary, error)
 	// InsertPatterns upserts patterns into the patterns table.
@@ -63,6 +153,21 @@ type Store interface {
 	Patterns(ctx context.Context) ([]Pattern, error)
 	// PatternCounts returns the number of log entries per pattern_id.
 	PatternCounts(ctx context.Context) (map[string]int, error)
+	// Checkpoint returns the ingestion checkpoint of source, or nil if the
+	// source has never been ingested.
+	Checkpoint(ctx context.Context, source string) (*Checkpoint, error)
+	// CommitLogBatch stores entries and advances the source checkpoint in
+	// a single transaction, so a resumed ingest never inserts twice.
+	CommitLogBatch(ctx context.Context, entries []LogEntry, cp Checkpoint) error
+	// DeleteEntriesAfter removes the entries of source starting after
+	// lineNumber, e.g. a last entry stored past its checkpoint, and returns
+	// how many there were.
+	DeleteEntriesAfter(ctx context.Context, source string, lineNumber int) (int, error)
+	// DeleteSource removes all entries and the checkpoint of source.
+	DeleteSource(ctx context.Context, source string) error
+	// SetTimelineOrder assigns TimelineSeq 0..n-1 to the entries with the
+	// given IDs, in order, and clears it on all others.
+	SetTimelineOrder(ctx context.Context, ids []int64) error
 	// InternalDB returns the underlying *sql.DB for direct SQL queries.
 	// Only use this when no interface method covers the needed operation.
 	InternalDB() *sql.DB
@@ -0,0 +1,64 @@
+package timestamp
+
+// Samples are example timestamps in the formats logs use, from which the
+// multiline detector learns the token shapes that start an entry. Parse
+// understands most of them; the others only help to tell where an entry
+// starts.
+var Samples = []string{
+	"2024-03-28T13:45:30.123456Z",
+	"28/Mar/2024:13:45:30",
+	"Sun, 28 Mar 2024 13:45:30",
+	"2024-03-28 13:45:30",
+	"2024-03-28 13:45:30,123",
+	"02 Jan 06 15:04 MST",
+	"2024-03-28T14:33:53.743350Z",
+	"2024-03-28T15:19:38.578639+00:00",
+	"2024-03-28 15:44:53",
+	"2024-08-20'T'13:20:10*633+0000",
+	"2024 Mar 03 05:12:41.211 PDT",
+	"Jan 21 18:20:11 +0000 2024",
+	"19/Apr/2024:06:36:15",
+	"Dec 2, 2024 2:39:58 AM",
+	"Jun 09 2024 15:28:14",
+	"Apr 20 00:00:35 2010",
+	"Sep 28 19:00:00 +0000",
+	"Mar 16 08:12:04",
+	"Jul 1 09:00:55",
+	"2024-10-14T22:11:20+0000",
+	"2024-07-01T14:59:55.711",
+	"2024-07-01T14:59:55.711Z",
+	"2024-08-19 12:17:55-0400",
+	"2024-06-26 02:31:29,573",
+	"2024/04/12*19:37:50",
+	"2024 Apr 13 22:08:13.211*PDT",
+	"2024 Mar 10 01:44:20.392",
+	"2024-03-10 14:30:12,655+0000",
+	"2024-02-27 15:35:20.311",
+	"2024-07-22'T'16:28:55.444",
+	"2024-11-22'T'10:10:15.455",
+	"2024-02-11'T'18:31:44",
+	"2024-10-30*02:47:33:899",
+	"2024-07-04*13:23:55",
+	"24-02-11 16:47:35,985 +0000",
+	"24-06-26 02:31:29,573",
+	"24-04-19 12:00:17",
+	"06/01/24 04:11:05",
+	"08/10/24*13:33:56",
+	"11/24/2024*05:13:11",
+	"05/09/2024*08:22:14*612",
+	"04/23/24 04:34:22 +0000",
+	"2024/04/25 14:57:42",
+	"11:42:35.173",
+	"11:42:35,173",
+	"23/Apr 11:42:35,173",
+	"23/Apr/2024:11:42:35",
+	"23/Apr/2024 11:42:35",
+	"23-Apr-2024 11:42:35",
+	"23-Apr-2024 11:42:35.883",
+	"23 Apr 2024 11:42:35",
+	"23 Apr 2024 10:32:35*311",
+	"8/5/2024 3:31:18 AM:234",
+	"9/28/2024 2:23:15 PM",
+	"2023-03.28T14-33:53-7430Z",
+	"2017-05-16_13:53:08",
+}
@@ -0,0 +1,184 @@
+// Package timestamp parses the timestamp formats found in logs. It is
+// shared by the event parsers, which turn timestamps into times, and the
+// multiline detector, which recognizes lines starting with one.
+package timestamp
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Floating is the location of times parsed from timestamps without a zone
+// or offset. Their wall clock is as written; In places them in a zone. Its
+// offset is 0, so before that they read as UTC.
+var Floating = time.FixedZone("", 0)
+
+// In returns ts in loc if it is floating, and ts unchanged otherwise. A nil
+// loc means UTC.
+func In(ts time.Time, loc *time.Location) time.Time {
+	if ts.Location() != Floating {
+		return ts
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.Date(ts.Year(), ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(), loc)
+}
+
+// layouts are tried in order by Parse. Fractional seconds after the seconds
+// field, with a period or a comma, are accepted by every layout.
+var layouts = []string{
+	// ISO 8601 and RFC 3339.
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02_15:04:05.999999999",
+	"2006/01/02 15:04:05.999999999",
+	"06-01-02 15:04:05.999999999 -0700",
+	"06-01-02 15:04:05.999999999",
+
+	// Apache/Nginx and other day-first dates.
+	"02/Jan/2006:15:04:05 -0700",
+	"02/Jan/2006:15:04:05",
+	"02/Jan/2006 15:04:05",
+	"02-Jan-2006 15:04:05",
+	"02 Jan 2006 15:04:05",
+
+	// RFC 1123 and RFC 822.
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 02 Jan 2006 15:04:05",
+	time.RFC822Z,
+	time.RFC822,
+
+	// Month-first dates, including syslog, whose timestamps have no year.
+	"2006 Jan _2 15:04:05 MST",
+	"2006 Jan _2 15:04:05",
+	"Jan _2 2006 15:04:05 MST",
+	"Jan _2 2006 15:04:05",
+	"Jan _2 15:04:05 -0700 2006",
+	"Jan _2 15:04:05 2006",
+	"Jan _2 15:04:05 MST",
+	"Jan _2 15:04:05",
+	"Jan _2, 2006 3:04:05 PM",
+}
+
+// Parse parses value as a Unix epoch (see ParseEpoch) or in one of the
+// formats logs commonly use. Year-less timestamps get the year InferYear
+// gives relative to the current time, and timestamps without a zone are
+// Floating.
+func Parse(value string) (time.Time, bool) {
+	return ParseAt(value, time.Now())
+}
+
+// ParseAt is Parse with year-less timestamps dated relative to now.
+func ParseAt(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if c := value[0]; c >= '0' && c <= '9' {
+		if ts, ok := ParseEpoch(value); ok {
+			return ts, true
+		}
+	}
+	for _, layout := range layouts {
+		if ts, ok := ParseLayout(layout, value, now); ok {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseLayout parses value with a time.Parse layout. A layout without a
+// zone gives a Floating time, and one without a year is dated by
+// InferYear relative to now.
+func ParseLayout(layout, value string, now time.Time) (time.Time, bool) {
+	ts, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if !hasZone(layout) {
+		ts = time.Date(ts.Year(), ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(), Floating)
+	}
+	if ts.Year() == 0 && !strings.Contains(layout, "06") {
+		ts = InferYear(ts, now)
+	}
+	return ts, true
+}
+
+func hasZone(layout string) bool {
+	return strings.Contains(layout, "MST") || strings.Contains(layout, "Z07") || strings.Contains(layout, "-07")
+}
+
+// ParseEpoch parses a Unix epoch in seconds, milliseconds, microseconds or
+// nanoseconds, told apart by the number of integer digits: 9-10, 12-13,
+// 15-16 or 18-19. Seconds and milliseconds may have a fraction. Fewer
+// digits are rejected, since small numbers are more likely durations or
+// counters than times.
+func ParseEpoch(value string) (time.Time, bool) {
+	integer, fraction, hasFraction := strings.Cut(value, ".")
+	if !isDigits(integer) || (hasFraction && !isDigits(fraction)) {
+		return time.Time{}, false
+	}
+
+	var unit time.Duration
+	switch len(integer) {
+	case 9, 10:
+		unit = time.Second
+	case 12, 13:
+		unit = time.Millisecond
+	case 15, 16:
+		unit = time.Microsecond
+	case 18, 19:
+		unit = time.Nanosecond
+	default:
+		return time.Time{}, false
+	}
+	if hasFraction && unit < time.Millisecond {
+		return time.Time{}, false
+	}
+
+	n, err := strconv.ParseInt(integer, 10, 64)
+	if err != nil || n > math.MaxInt64/int64(unit) {
+		return time.Time{}, false
+	}
+	nanos := n * int64(unit)
+	if hasFraction {
+		f, err := strconv.ParseFloat("0."+fraction, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		nanos += int64(math.Round(f * float64(unit)))
+	}
+	return time.Unix(0, nanos).UTC(), true
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// InferYear dates a year-less timestamp such as syslog's "Mar 16 08:12:04"
+// to the year of now, stepping back a year when that would put it more
+// than a day in the future (e.g. a December message read in January).
+func InferYear(ts, now time.Time) time.Time {
+	ts = ts.AddDate(now.Year()-ts.Year(), 0, 0)
+	if ts.After(now.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	return ts
+}
@@ -0,0 +1,96 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		want     string
+		floating bool
+	}{
+		{"2024-03-16T08:12:04.123Z", "2024-03-16T08:12:04.123Z", false},
+		{"2024-03-16T08:12:04+02:00", "2024-03-16T06:12:04Z", false},
+		{"2024-10-14T22:11:20+0000", "2024-10-14T22:11:20Z", false},
+		{"2024-03-16 08:12:04", "2024-03-16T08:12:04Z", true},
+		{"2024-03-16 08:12:04,573", "2024-03-16T08:12:04.573Z", true},
+		{"2024-03-10 14:30:12,655+0000", "2024-03-10T14:30:12.655Z", false},
+		{"2024-08-19 12:17:55-0400", "2024-08-19T16:17:55Z", false},
+		{"2024/04/25 14:57:42", "2024-04-25T14:57:42Z", true},
+		{"24-06-26 02:31:29,573", "2024-06-26T02:31:29.573Z", true},
+		{"28/Mar/2024:13:45:30 +0000", "2024-03-28T13:45:30Z", false},
+		{"28/Mar/2024:13:45:30 -0700", "2024-03-28T20:45:30Z", false},
+		{"23-Apr-2024 11:42:35.883", "2024-04-23T11:42:35.883Z", true},
+		{"Sun, 28 Mar 2024 13:45:30 +0100", "2024-03-28T12:45:30Z", false},
+		{"Mar 16 08:12:04", "2024-03-16T08:12:04Z", true},
+		{"Jul  1 09:00:55", "2023-07-01T09:00:55Z", true},
+		{"Mar 16 2024 08:12:04.123 UTC", "2024-03-16T08:12:04.123Z", false},
+		{"Dec 2, 2024 2:39:58 AM", "2024-12-02T02:39:58Z", true},
+		{"1710576724", "2024-03-16T08:12:04Z", false},
+		{"1710576724.5", "2024-03-16T08:12:04.5Z", false},
+		{"1710576724123", "2024-03-16T08:12:04.123Z", false},
+		{"1710576724123.5", "2024-03-16T08:12:04.1235Z", false},
+		{"1710576724123456", "2024-03-16T08:12:04.123456Z", false},
+		{"1710576724123456789", "2024-03-16T08:12:04.123456789Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			ts, ok := ParseAt(tt.value, now)
+			if !ok {
+				t.Fatal("expected a timestamp")
+			}
+			if got := ts.UTC().Format(time.RFC3339Nano); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if floating := ts.Location() == Floating; floating != tt.floating {
+				t.Errorf("floating: got %v, want %v", floating, tt.floating)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, value := range []string{"", "0.5", "1234", "12345678901", "17105767241234.5", "1710576724123456.5", "not a time", "2024-13-45 25:00:00"} {
+		if ts, ok := Parse(value); ok {
+			t.Errorf("%q: expected no timestamp, got %v", value, ts)
+		}
+	}
+}
+
+func TestIn(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	floating, _ := Parse("2024-03-16 08:12:04")
+	if got := In(floating, berlin); !got.Equal(time.Date(2024, 3, 16, 7, 12, 4, 0, time.UTC)) {
+		t.Errorf("floating: got %v", got)
+	}
+	if got := In(floating, nil); got.Location() != time.UTC || got.Hour() != 8 {
+		t.Errorf("nil location: got %v", got)
+	}
+	zoned, _ := Parse("2024-03-16T08:12:04Z")
+	if got := In(zoned, berlin); !got.Equal(zoned) {
+		t.Errorf("zoned: got %v, want %v unchanged", got, zoned)
+	}
+}
+
+func TestInferYear(t *testing.T) {
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ts   time.Time
+		want int
+	}{
+		{time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC), 2024},
+		{time.Date(0, 1, 2, 23, 0, 0, 0, time.UTC), 2024},
+		{time.Date(0, 12, 31, 23, 0, 0, 0, time.UTC), 2023},
+	}
+	for _, tt := range tests {
+		if got := InferYear(tt.ts, now).Year(); got != tt.want {
+			t.Errorf("%v: got year %d, want %d", tt.ts, got, tt.want)
+		}
+	}
+}
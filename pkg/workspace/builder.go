@@ -10,6 +10,8 @@ import (
 	"sort"
 	"strings"
 	"text/template"
+	"time"
+	"unicode/utf8"
 
 	"github.com/go-errors/errors"
 	"github.com/strrl/lapp/pkg/pattern"
@@ -28,6 +30,20 @@ var tmpl = template.Must(
 	}).ParseFS(templateFS, "templates/*.tmpl"),
 )
 
+// timelineMaxRows caps notes/timeline.md; timelineMaxText caps each row.
+// splitMaxRows caps the split entries listed in notes/summary.md.
+const (
+	timelineMaxRows = 1000
+	timelineMaxText = 200
+	splitMaxRows    = 20
+
+	requestMaxSessions = 20
+	requestMaxRows     = 50
+)
+
+// attrMaxValues caps the values listed per attribute in pattern.md.
+const attrMaxValues = 3
+
 var errorPattern = regexp.MustCompile(`(?i)(error|warn|fatal|panic|exception|failed|timeout)`)
 var validDirChar = regexp.MustCompile(`[^a-z0-9-]`)
 
@@ -40,6 +56,11 @@ type Builder struct {
 	patterns  []PatternInfo
 	unmatched []TaggedLine
 	logFiles  []string
+	timeline  []TaggedLine
+	requests  []Request
+	// requestTotal counts all request sessions, including those not in
+	// requests.
+	requestTotal int
 }
 
 // NewBuilder creates a Builder with pre-processed data.
@@ -52,6 +73,19 @@ func NewBuilder(dir string, taggedLines []TaggedLine, templates []pattern.DrainC
 	}
 }
 
+// SetTimeline sets the lines of all files merged in timestamp order, from
+// which notes/timeline.md is written.
+func (b *Builder) SetTimeline(lines []TaggedLine) {
+	b.timeline = lines
+}
+
+// SetRequests sets the request sessions notes/requests.md lists (see
+// LoadRequests) and how many there are in all.
+func (b *Builder) SetRequests(requests []Request, total int) {
+	b.requests = requests
+	b.requestTotal = total
+}
+
 // BuildAll orchestrates writing all workspace files.
 func (b *Builder) BuildAll() error {
 	b.computePatterns()
@@ -92,7 +126,7 @@ func (b *Builder) computePatterns() {
 	}
 	matches := make([]lineWithTemplate, 0, len(b.tagged))
 	for _, tl := range b.tagged {
-		t, ok := pattern.MatchTemplate(tl.Content, b.templates)
+		t, ok := pattern.MatchTemplate(tl.PatternText(), b.templates)
 		id := ""
 		if ok {
 			id = t.ID.String()
@@ -120,7 +154,8 @@ func (b *Builder) computePatterns() {
 		}
 	}
 
-	// Populate counts, samples, line refs
+	// Populate counts, samples, line refs and attribute values
+	attrCounts := make(map[string]map[string]map[string]int)
 	for _, m := range matches {
 		if m.templateID == "" {
 			b.unmatched = append(b.unmatched, m.tagged)
@@ -141,6 +176,22 @@ func (b *Builder) computePatterns() {
 		if len(info.Samples) < 20 {
 			info.Samples = append(info.Samples, m.tagged.Content)
 		}
+		for key, value := range m.tagged.Attrs {
+			if key == m.tagged.MessageKey {
+				// The field the pattern was mined from.
+				continue
+			}
+			if attrCounts[m.templateID] == nil {
+				attrCounts[m.templateID] = make(map[string]map[string]int)
+			}
+			if attrCounts[m.templateID][key] == nil {
+				attrCounts[m.templateID][key] = make(map[string]int)
+			}
+			attrCounts[m.templateID][key][value]++
+		}
+	}
+	for tid, info := range infoMap {
+		info.Attrs = summarizeAttrs(attrCounts[tid])
 	}
 
 	// Collect patterns sorted by count desc
@@ -152,6 +203,29 @@ func (b *Builder) computePatterns() {
 	})
 }
 
+// summarizeAttrs lists each attribute's most frequent values, by key.
+func summarizeAttrs(counts map[string]map[string]int) []AttrSummary {
+	summaries := make([]AttrSummary, 0, len(counts))
+	for key, values := range counts {
+		s := AttrSummary{Key: key, Distinct: len(values)}
+		for value, n := range values {
+			s.Values = append(s.Values, AttrValue{Value: headline(value), Count: n})
+		}
+		sort.Slice(s.Values, func(i, j int) bool {
+			if s.Values[i].Count != s.Values[j].Count {
+				return s.Values[i].Count > s.Values[j].Count
+			}
+			return s.Values[i].Value < s.Values[j].Value
+		})
+		if len(s.Values) > attrMaxValues {
+			s.Values = s.Values[:attrMaxValues]
+		}
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Key < summaries[j].Key })
+	return summaries
+}
+
 func (b *Builder) writePatternDirs() error {
 	for _, p := range b.patterns {
 		dir := filepath.Join(b.dir, "patterns", p.DirName)
@@ -201,6 +275,7 @@ func (b *Builder) writeNotes() error {
 	}
 
 	// summary.md
+	splitCount, splitRows := b.splitEntries()
 	summaryData := struct {
 		FileCount      int
 		LogFiles       []string
@@ -208,6 +283,8 @@ func (b *Builder) writeNotes() error {
 		PatternCount   int
 		UnmatchedCount int
 		Patterns       []PatternInfo
+		SplitCount     int
+		SplitEntries   []timelineRow
 	}{
 		FileCount:      len(b.logFiles),
 		LogFiles:       b.logFiles,
@@ -215,6 +292,8 @@ func (b *Builder) writeNotes() error {
 		PatternCount:   len(b.patterns),
 		UnmatchedCount: len(b.unmatched),
 		Patterns:       b.patterns,
+		SplitCount:     splitCount,
+		SplitEntries:   splitRows,
 	}
 	var buf bytes.Buffer
 	if err := tmpl.ExecuteTemplate(&buf, "summary.md.tmpl", summaryData); err != nil {
@@ -255,7 +334,180 @@ func (b *Builder) writeNotes() error {
 	if err := tmpl.ExecuteTemplate(&buf, "errors.md.tmpl", errorsData); err != nil {
 		return errors.Errorf("render errors.md: %w", err)
 	}
-	return os.WriteFile(filepath.Join(notesDir, "errors.md"), buf.Bytes(), 0o644)
+	if err := os.WriteFile(filepath.Join(notesDir, "errors.md"), buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	// timeline.md
+	buf.Reset()
+	if err := tmpl.ExecuteTemplate(&buf, "timeline.md.tmpl", b.timelineData()); err != nil {
+		return errors.Errorf("render timeline.md: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(notesDir, "timeline.md"), buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	// requests.md
+	buf.Reset()
+	if err := tmpl.ExecuteTemplate(&buf, "requests.md.tmpl", b.requestsData()); err != nil {
+		return errors.Errorf("render requests.md: %w", err)
+	}
+	return os.WriteFile(filepath.Join(notesDir, "requests.md"), buf.Bytes(), 0o644)
+}
+
+// timelineRow is one rendered line of notes/timeline.md.
+type timelineRow struct {
+	Time     string
+	FileName string
+	LineNum  int
+	Text     string
+}
+
+// timelineSource summarizes one file's span on the timeline.
+type timelineSource struct {
+	FileName string
+	Lines    int
+	First    string
+	Last     string
+}
+
+// timelineData shows the head and tail of the merged timeline, where an
+// incident's trigger and its aftermath usually are, eliding the middle of
+// long timelines.
+func (b *Builder) timelineData() any {
+	const half = timelineMaxRows / 2
+
+	sources := make(map[string]*timelineSource)
+	var order []string
+	for _, tl := range b.timeline {
+		src, ok := sources[tl.FileName]
+		if !ok {
+			src = &timelineSource{FileName: tl.FileName}
+			sources[tl.FileName] = src
+			order = append(order, tl.FileName)
+		}
+		src.Lines++
+		if !tl.Timestamp.IsZero() {
+			if src.First == "" {
+				src.First = formatTimelineTime(tl.Timestamp)
+			}
+			src.Last = formatTimelineTime(tl.Timestamp)
+		}
+	}
+	sort.Strings(order)
+	summaries := make([]timelineSource, 0, len(order))
+	for _, name := range order {
+		summaries = append(summaries, *sources[name])
+	}
+
+	head, tail := b.timeline, []TaggedLine(nil)
+	if len(b.timeline) > timelineMaxRows {
+		head, tail = b.timeline[:half], b.timeline[len(b.timeline)-half:]
+	}
+	return struct {
+		Sources []timelineSource
+		Head    []timelineRow
+		Tail    []timelineRow
+		Omitted int
+	}{
+		Sources: summaries,
+		Head:    timelineRows(head),
+		Tail:    timelineRows(tail),
+		Omitted: len(b.timeline) - len(head) - len(tail),
+	}
+}
+
+func timelineRows(lines []TaggedLine) []timelineRow {
+	rows := make([]timelineRow, 0, len(lines))
+	for _, tl := range lines {
+		t := "-"
+		if !tl.Timestamp.IsZero() {
+			t = formatTimelineTime(tl.Timestamp)
+		}
+		rows = append(rows, timelineRow{Time: t, FileName: tl.FileName, LineNum: tl.LineNum, Text: headline(tl.Content)})
+	}
+	return rows
+}
+
+// requestSession is one request of notes/requests.md.
+type requestSession struct {
+	ID       string
+	Key      string
+	Lines    int
+	Errors   int
+	Sources  []string
+	Services []string
+	First    string
+	Last     string
+	Rows     []timelineRow
+	Omitted  int
+}
+
+// requestsData lists the request sessions set by SetRequests, in the
+// order the store returned them.
+func (b *Builder) requestsData() any {
+	sessions := make([]requestSession, 0, len(b.requests))
+	for _, r := range b.requests {
+		rs := requestSession{
+			ID:       r.Session.ID,
+			Key:      r.Session.Key,
+			Lines:    r.Session.Count,
+			Errors:   r.Session.Errors,
+			Sources:  r.Session.Sources,
+			Services: r.Session.Services,
+			Rows:     timelineRows(r.Lines),
+			Omitted:  r.Session.Count - len(r.Lines),
+		}
+		if !r.Session.First.IsZero() {
+			rs.First = formatTimelineTime(r.Session.First)
+			rs.Last = formatTimelineTime(r.Session.Last)
+		}
+		sessions = append(sessions, rs)
+	}
+	return struct {
+		Total    int
+		Sessions []requestSession
+		Omitted  int
+	}{
+		Total:    b.requestTotal,
+		Sessions: sessions,
+		Omitted:  b.requestTotal - len(sessions),
+	}
+}
+
+// splitEntries counts the entries cut short by the multiline size limits
+// and returns the first splitMaxRows of them.
+func (b *Builder) splitEntries() (int, []timelineRow) {
+	count := 0
+	var rows []timelineRow
+	for _, tl := range b.tagged {
+		if !tl.Truncated {
+			continue
+		}
+		count++
+		if len(rows) < splitMaxRows {
+			rows = append(rows, timelineRow{FileName: tl.FileName, LineNum: tl.LineNum, Text: headline(tl.Content)})
+		}
+	}
+	return count, rows
+}
+
+// headline returns the first line of a multiline entry, capped at
+// timelineMaxText bytes.
+func headline(content string) string {
+	text, _, _ := strings.Cut(content, "\n")
+	if len(text) > timelineMaxText {
+		cut := timelineMaxText
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "…"
+	}
+	return text
+}
+
+func formatTimelineTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
 }
 
 func (b *Builder) writeAgentsMD() error {
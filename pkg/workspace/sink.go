@@ -0,0 +1,131 @@
+package workspace
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/strrl/lapp/pkg/logsource"
+)
+
+// Sink appends received log lines to a file under <dir>/logs/ so that they
+// take part in the next workspace rebuild. It is safe for concurrent use.
+type Sink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenSink opens (or creates) <dir>/logs/<name> for appending.
+func OpenSink(dir, name string) (*Sink, error) {
+	if name == "" || filepath.Base(name) != name {
+		return nil, errors.Errorf("invalid sink name %q", name)
+	}
+	path := filepath.Join(dir, "logs", name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, errors.Errorf("open sink %s: %w", name, err)
+	}
+	return &Sink{file: file}, nil
+}
+
+// Write appends one line. Lines that carry a source timestamp or attrs, or
+// whose content spans several physical lines, are written as a JSON object
+// ({"ts":...,"message":...,<attrs>}) so the event parser recovers them on
+// rebuild; anything else is written verbatim.
+func (s *Sink) Write(line *logsource.LogLine) error {
+	text, err := formatSinkLine(line)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.WriteString(text + "\n"); err != nil {
+		return errors.Errorf("write sink: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying file.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.file.Close(); err != nil {
+		return errors.Errorf("close sink: %w", err)
+	}
+	return nil
+}
+
+func formatSinkLine(line *logsource.LogLine) (string, error) {
+	if line.Timestamp == nil && len(line.Attrs) == 0 && !strings.ContainsAny(line.Content, "\r\n") {
+		return line.Content, nil
+	}
+
+	record := make(map[string]string, len(line.Attrs)+2)
+	for key, value := range line.Attrs {
+		record[key] = value
+	}
+	if line.Timestamp != nil {
+		record["ts"] = line.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	record["message"] = line.Content
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return "", errors.Errorf("marshal sink line: %w", err)
+	}
+	return string(b), nil
+}
+
+// SinkName returns the logs/ file name for a source name such as a Fluent
+// tag, with characters other than [a-z0-9-] replaced.
+func SinkName(source string) string {
+	return sanitizeDirName(source) + ".log"
+}
+
+// Sinks appends lines to one Sink per file name under <dir>/logs/, opening
+// each on first use. It is safe for concurrent use.
+type Sinks struct {
+	dir   string
+	mu    sync.Mutex
+	sinks map[string]*Sink
+}
+
+// OpenSinks returns Sinks for the workspace at dir.
+func OpenSinks(dir string) *Sinks {
+	return &Sinks{dir: dir, sinks: make(map[string]*Sink)}
+}
+
+// Write appends line to <dir>/logs/<name>.
+func (s *Sinks) Write(name string, line *logsource.LogLine) error {
+	s.mu.Lock()
+	sink, ok := s.sinks[name]
+	if !ok {
+		var err error
+		sink, err = OpenSink(s.dir, name)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.sinks[name] = sink
+	}
+	s.mu.Unlock()
+	return sink.Write(line)
+}
+
+// Close closes every opened sink, returning the first error.
+func (s *Sinks) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var first error
+	for _, sink := range s.sinks {
+		if err := sink.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
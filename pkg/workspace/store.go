@@ -0,0 +1,69 @@
+package workspace
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/go-errors/errors"
+	"github.com/strrl/lapp/pkg/store"
+)
+
+// StoreFile is the DuckDB database, relative to the workspace directory,
+// that holds ingested log entries and per-file ingestion checkpoints.
+const StoreFile = "lapp.duckdb"
+
+// OpenStore opens (or creates) the workspace store and ensures its schema.
+func OpenStore(ctx context.Context, dir string) (*store.DuckDBStore, error) {
+	s, err := store.NewDuckDBStore(filepath.Join(dir, StoreFile))
+	if err != nil {
+		return nil, errors.Errorf("open workspace store: %w", err)
+	}
+	if err := s.Init(ctx); err != nil {
+		_ = s.Close()
+		return nil, errors.Errorf("init workspace store: %w", err)
+	}
+	return s, nil
+}
+
+// TaggedEntry returns the workspace line of a stored entry.
+func TaggedEntry(e store.LogEntry) TaggedLine {
+	return TaggedLine{
+		Content:      e.Raw,
+		FileName:     e.Source,
+		LineNum:      e.LineNumber,
+		Timestamp:    e.Timestamp,
+		Truncated:    e.Truncated,
+		Continuation: e.Continuation,
+		Message:      e.Message,
+		MessageKey:   e.MessageKey,
+		Attrs:        e.Attrs,
+	}
+}
+
+// Request is a request session with its first lines in timeline order.
+type Request struct {
+	Session store.RequestSession
+	Lines   []TaggedLine
+}
+
+// LoadRequests reads the request sessions notes/requests.md lists from s,
+// whose timeline order must be set: the first requestMaxSessions, each
+// with its first requestMaxRows lines. total is the number of sessions.
+func LoadRequests(ctx context.Context, s store.Store) (requests []Request, total int, err error) {
+	sessions, err := s.RequestSessions(ctx, store.QueryOpts{})
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, session := range sessions[:min(len(sessions), requestMaxSessions)] {
+		entries, err := s.QueryLogs(ctx, store.QueryOpts{Request: session.RequestRef, Timeline: true, Limit: requestMaxRows})
+		if err != nil {
+			return nil, 0, errors.Errorf("load request %s: %w", session.ID, err)
+		}
+		r := Request{Session: session}
+		for _, e := range entries {
+			r.Lines = append(r.Lines, TaggedEntry(e))
+		}
+		requests = append(requests, r)
+	}
+	return requests, len(sessions), nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // TaggedLine represents a log line with its source file and line number.
@@ -12,6 +13,27 @@ type TaggedLine struct {
 	Content  string
 	FileName string
 	LineNum  int
+	// Timestamp is the line's event time, zero if it has none.
+	Timestamp time.Time
+	// Truncated and Continuation mark an entry split by the multiline size
+	// limits (see store.LogEntry).
+	Truncated    bool
+	Continuation bool
+	// Message is the part of Content patterns are mined from; empty means
+	// all of Content (see store.LogEntry).
+	Message string
+	// MessageKey is the attr Message was taken from, if any.
+	MessageKey string
+	// Attrs holds the line's structured attributes.
+	Attrs map[string]string
+}
+
+// PatternText returns the text the line is clustered and matched by.
+func (tl TaggedLine) PatternText() string {
+	if tl.Message != "" {
+		return tl.Message
+	}
+	return tl.Content
 }
 
 // LineRef identifies a line's location in a source file.
@@ -31,6 +53,24 @@ type PatternInfo struct {
 	LastSeen    LineRef
 	LineRefs    []LineRef
 	Samples     []string
+	// Attrs summarizes the structured attributes of the matched lines,
+	// which patterns are not mined from, by key.
+	Attrs []AttrSummary
+}
+
+// AttrSummary describes the values one attribute takes across the lines
+// of a pattern.
+type AttrSummary struct {
+	Key string
+	// Values are the most frequent values, most frequent first.
+	Values   []AttrValue
+	Distinct int
+}
+
+// AttrValue is one attribute value and how many lines have it.
+type AttrValue struct {
+	Value string
+	Count int
 }
 
 // ListLogFiles returns the basenames of all files in <dir>/logs/.